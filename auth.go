@@ -0,0 +1,543 @@
+package routeguide
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// MetadataAuthorizationKey is the metadata header that carries the bearer
+	// token of the client.
+	MetadataAuthorizationKey = "authorization"
+
+	// DefaultJWKSReloadInterval is the interval at which the JWKS file is
+	// polled for changes.
+	DefaultJWKSReloadInterval = 10 * time.Second
+
+	bearerPrefix = "Bearer "
+
+	// tokenLeeway is the clock skew tolerated when the expiry and the
+	// not-before times of a token are checked.
+	tokenLeeway = 30 * time.Second
+
+	// maxNumericDate is the latest NumericDate claim that is accepted, at the
+	// end of year 9999, so that the claims can't overflow a time.
+	maxNumericDate = 253402300799
+)
+
+var (
+	// ErrTokenMissing is returned when a request carries no bearer token.
+	ErrTokenMissing = errors.New("bearer token is missing")
+
+	// ErrTokenMalformed is returned when a token isn't a JWT in the compact
+	// serialization.
+	ErrTokenMalformed = errors.New("token is malformed")
+
+	// ErrTokenSignature is returned when no key of the JWKS verifies the
+	// signature of a token.
+	ErrTokenSignature = errors.New("token signature is invalid")
+
+	// ErrTokenExpired is returned when a token expired, or isn't valid yet.
+	ErrTokenExpired = errors.New("token is expired or not valid yet")
+)
+
+// Claims are the claims of a validated token.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+
+	// Raw holds all the claims of the token, including the registered ones.
+	Raw map[string]interface{}
+}
+
+type claimsKey struct{}
+
+// ContextWithClaims returns a copy of the context that carries the claims.
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the token that the request was
+// authenticated with, if it was.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// jsonWebKey is a key of a JWKS, as defined by RFC 7517.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// oct
+	K string `json:"k"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verificationKey is a key that verifies the signatures of one algorithm.
+type verificationKey struct {
+	id     string
+	alg    string
+	verify func(signingInput, signature []byte) bool
+}
+
+// TokenValidator validates the bearer JWTs signed with the HS256, RS256 or
+// ES256 keys of a JWKS file. The file is reloaded whenever it changes on
+// disk, so that the keys can be rotated without a restart.
+type TokenValidator struct {
+	jwksFile string
+	issuer   string
+	audience string
+	watcher  *fileWatcher
+
+	mutex sync.RWMutex
+	keys  []*verificationKey
+}
+
+// NewTokenValidator loads the keys of the JWKS file. The tokens must be
+// issued by the issuer for the audience. The file is polled for changes at
+// the given interval. A zero interval disables polling.
+func NewTokenValidator(jwksFile, issuer, audience string, interval time.Duration) (*TokenValidator, error) {
+	v := &TokenValidator{
+		jwksFile: jwksFile,
+		issuer:   issuer,
+		audience: audience,
+	}
+	v.watcher = newFileWatcher("auth", []string{jwksFile}, interval, v.Reload)
+
+	if err := v.Reload(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Run reloads the JWKS file whenever a signal is received on the trigger
+// channel, or whenever the file changes. It returns when the stop channel is
+// closed.
+func (v *TokenValidator) Run(trigger <-chan os.Signal, stop <-chan struct{}) {
+	v.watcher.Run(trigger, stop)
+}
+
+// Reload reads the keys of the JWKS file. If the file can't be read or holds
+// an invalid key, the previous keys are kept.
+func (v *TokenValidator) Reload() error {
+	data, err := ioutil.ReadFile(v.jwksFile)
+	if err != nil {
+		return err
+	}
+
+	var jwks struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return fmt.Errorf("%s: %s", v.jwksFile, err)
+	}
+
+	keys := []*verificationKey{}
+	for i, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := parseJSONWebKey(jwk)
+		if err != nil {
+			return fmt.Errorf("%s: key %d: %s", v.jwksFile, i, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("%s: no signing keys found", v.jwksFile)
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.keys = keys
+	log.Printf("[auth] loaded %d keys from %s", len(keys), v.jwksFile)
+	return nil
+}
+
+// Validate verifies the signature of the token, and checks its expiry, its
+// issuer and its audience.
+func (v *TokenValidator) Validate(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if !v.verify(header.Alg, header.Kid, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrTokenSignature
+	}
+
+	raw := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	claims, err := parseClaims(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.check(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// verify returns true if a key of the algorithm verifies the signature. If
+// the token names its key, only that key is tried.
+func (v *TokenValidator) verify(alg, kid string, signingInput, signature []byte) bool {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	for _, key := range v.keys {
+		if key.alg != alg || (kid != "" && key.id != kid) {
+			continue
+		}
+
+		if key.verify(signingInput, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// check checks the expiry, the not-before time, the issuer and the audience
+// of the claims.
+func (v *TokenValidator) check(claims *Claims, now time.Time) error {
+	if claims.ExpiresAt.IsZero() {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(claims.ExpiresAt.Add(tokenLeeway)) {
+		return ErrTokenExpired
+	}
+
+	if nbf, ok := claims.Raw["nbf"]; ok {
+		notBefore, err := numericDate(nbf)
+		if err != nil {
+			return err
+		}
+		if now.Add(tokenLeeway).Before(notBefore) {
+			return ErrTokenExpired
+		}
+	}
+
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return fmt.Errorf("token issuer %q is not trusted", claims.Issuer)
+	}
+
+	if v.audience != "" {
+		for _, audience := range claims.Audience {
+			if audience == v.audience {
+				return nil
+			}
+		}
+		return fmt.Errorf("token audience %v doesn't include %q", claims.Audience, v.audience)
+	}
+	return nil
+}
+
+// UnaryInterceptor authenticates the unary requests, except for the exempt
+// methods, and passes the claims of their tokens to the handlers in their
+// contexts.
+func (v *TokenValidator) UnaryInterceptor(exempt ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if contains(exempt, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ctx, err := v.authenticate(ctx)
+		if err != nil {
+			log.Printf("[auth] (denied) %s: %s", info.FullMethod, err)
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor authenticates the streams, except for the exempt methods,
+// and passes the claims of their tokens to the handlers in the contexts of the
+// streams.
+func (v *TokenValidator) StreamInterceptor(exempt ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if contains(exempt, info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx, err := v.authenticate(ss.Context())
+		if err != nil {
+			log.Printf("[auth] (denied) %s: %s", info.FullMethod, err)
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate validates the bearer token in the metadata of the request, and
+// returns a copy of the context that carries its claims.
+func (v *TokenValidator) authenticate(ctx context.Context) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(MetadataAuthorizationKey); len(values) > 0 {
+			token = values[0]
+		}
+	}
+
+	if !strings.HasPrefix(token, bearerPrefix) {
+		return nil, ErrTokenMissing
+	}
+
+	claims, err := v.Validate(strings.TrimPrefix(token, bearerPrefix))
+	if err != nil {
+		return nil, err
+	}
+	return ContextWithClaims(ctx, claims), nil
+}
+
+// contextStream is a server stream whose context is replaced.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// TokenCredentials attaches a bearer token to every RPC. The token is either
+// given, or read from a file before every RPC, so that it can be refreshed on
+// disk.
+type TokenCredentials struct {
+	token         string
+	file          string
+	allowInsecure bool
+}
+
+// NewTokenCredentials returns the credentials of the token, or of the token
+// file if the token is empty. Unless allowInsecure is true, the token is only
+// sent over TLS.
+func NewTokenCredentials(token, file string, allowInsecure bool) *TokenCredentials {
+	return &TokenCredentials{token: token, file: file, allowInsecure: allowInsecure}
+}
+
+// GetRequestMetadata returns the authorization header of the token.
+func (c *TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token := c.token
+	if token == "" {
+		data, err := ioutil.ReadFile(c.file)
+		if err != nil {
+			return nil, err
+		}
+		token = string(bytes.TrimSpace(data))
+	}
+
+	return map[string]string{MetadataAuthorizationKey: bearerPrefix + token}, nil
+}
+
+// RequireTransportSecurity returns true if the token is only sent over TLS.
+func (c *TokenCredentials) RequireTransportSecurity() bool {
+	return !c.allowInsecure
+}
+
+// parseJSONWebKey returns the verification key of the JWK.
+func parseJSONWebKey(jwk *jsonWebKey) (*verificationKey, error) {
+	key := &verificationKey{id: jwk.Kid}
+
+	switch jwk.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil || len(secret) == 0 {
+			return nil, fmt.Errorf("invalid oct key")
+		}
+
+		key.alg = "HS256"
+		key.verify = func(signingInput, signature []byte) bool {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(signingInput)
+			return hmac.Equal(mac.Sum(nil), signature)
+		}
+
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus")
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+
+		public := &rsa.PublicKey{N: n, E: int(e.Int64())}
+		key.alg = "RS256"
+		key.verify = func(signingInput, signature []byte) bool {
+			digest := sha256.Sum256(signingInput)
+			return rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature) == nil
+		}
+
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate")
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate")
+		}
+
+		curve := elliptic.P256()
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("EC point isn't on the %s curve", jwk.Crv)
+		}
+
+		public := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		key.alg = "ES256"
+		key.verify = func(signingInput, signature []byte) bool {
+			// the signature is the concatenation of r and s
+			if len(signature) != 64 {
+				return false
+			}
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+
+			digest := sha256.Sum256(signingInput)
+			return ecdsa.Verify(public, digest[:], r, s)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+
+	if jwk.Alg != "" && jwk.Alg != key.alg {
+		return nil, fmt.Errorf("unsupported algorithm %q for key type %q", jwk.Alg, jwk.Kty)
+	}
+	return key, nil
+}
+
+// parseClaims extracts the registered claims.
+func parseClaims(raw map[string]interface{}) (*Claims, error) {
+	claims := &Claims{Raw: raw}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Issuer, _ = raw["iss"].(string)
+
+	switch aud := raw["aud"].(type) {
+	case string:
+		claims.Audience = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				claims.Audience = append(claims.Audience, s)
+			}
+		}
+	}
+
+	if exp, ok := raw["exp"]; ok {
+		expiresAt, err := numericDate(exp)
+		if err != nil {
+			return nil, err
+		}
+		claims.ExpiresAt = expiresAt
+	}
+	return claims, nil
+}
+
+// numericDate returns the time of a NumericDate claim, in seconds since the
+// epoch. Non-finite and out of range values are rejected.
+func numericDate(value interface{}) (time.Time, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, ErrTokenMalformed
+	}
+
+	seconds, err := number.Float64()
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) || math.Abs(seconds) > maxNumericDate {
+		return time.Time{}, ErrTokenMalformed
+	}
+
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*1e9)), nil
+}
+
+// decodeSegment decodes a base64url-encoded JSON segment of a token.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
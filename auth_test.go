@@ -0,0 +1,304 @@
+package routeguide
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://issuer.routeguide"
+	testAudience = "routeguide"
+)
+
+// testSigner signs tokens with the key of a JWK.
+type testSigner struct {
+	alg  string
+	kid  string
+	jwk  map[string]string
+	sign func(signingInput []byte) []byte
+}
+
+func newTestSigners(t *testing.T) map[string]*testSigner {
+	t.Helper()
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := base64.RawURLEncoding.EncodeToString
+	return map[string]*testSigner{
+		"HS256": {
+			alg: "HS256",
+			kid: "hs",
+			jwk: map[string]string{"kty": "oct", "kid": "hs", "k": encode(secret)},
+			sign: func(signingInput []byte) []byte {
+				mac := hmac.New(sha256.New, secret)
+				mac.Write(signingInput)
+				return mac.Sum(nil)
+			},
+		},
+		"RS256": {
+			alg: "RS256",
+			kid: "rs",
+			jwk: map[string]string{
+				"kty": "RSA",
+				"kid": "rs",
+				"n":   encode(rsaKey.N.Bytes()),
+				"e":   encode(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			sign: func(signingInput []byte) []byte {
+				digest := sha256.Sum256(signingInput)
+				signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+				if err != nil {
+					t.Fatal(err)
+				}
+				return signature
+			},
+		},
+		"ES256": {
+			alg: "ES256",
+			kid: "es",
+			jwk: map[string]string{
+				"kty": "EC",
+				"kid": "es",
+				"crv": "P-256",
+				"x":   encode(ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   encode(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+			sign: func(signingInput []byte) []byte {
+				digest := sha256.Sum256(signingInput)
+				r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+				if err != nil {
+					t.Fatal(err)
+				}
+				return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+			},
+		},
+	}
+}
+
+// token returns a token with the header and the claims, signed by the signer.
+func (s *testSigner) token(t *testing.T, header, claims map[string]interface{}) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signingInput := encode(header) + "." + encode(claims)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(s.sign([]byte(signingInput)))
+}
+
+func newTestValidator(t *testing.T, signers map[string]*testSigner) *TokenValidator {
+	t.Helper()
+
+	keys := []map[string]string{}
+	for _, signer := range signers {
+		keys = append(keys, signer.jwk)
+	}
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := ioutil.WriteFile(jwksFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	validator, err := NewTokenValidator(jwksFile, testIssuer, testAudience, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return validator
+}
+
+func TestTokenValidatorSignature(t *testing.T) {
+	var (
+		signers   = newTestSigners(t)
+		validator = newTestValidator(t, signers)
+		claims    = map[string]interface{}{
+			"sub": "client",
+			"iss": testIssuer,
+			"aud": testAudience,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+	)
+
+	tests := []struct {
+		name   string
+		signer string
+		header map[string]interface{}
+		err    error
+	}{
+		{name: "HS256", signer: "HS256", header: map[string]interface{}{"alg": "HS256", "kid": "hs"}},
+		{name: "RS256", signer: "RS256", header: map[string]interface{}{"alg": "RS256", "kid": "rs"}},
+		{name: "ES256", signer: "ES256", header: map[string]interface{}{"alg": "ES256", "kid": "es"}},
+		{name: "without kid", signer: "RS256", header: map[string]interface{}{"alg": "RS256"}},
+		{name: "alg mismatch", signer: "RS256", header: map[string]interface{}{"alg": "HS256", "kid": "rs"}, err: ErrTokenSignature},
+		{name: "alg none", signer: "HS256", header: map[string]interface{}{"alg": "none"}, err: ErrTokenSignature},
+		{name: "unknown kid", signer: "ES256", header: map[string]interface{}{"alg": "ES256", "kid": "unknown"}, err: ErrTokenSignature},
+		{name: "kid of another key", signer: "HS256", header: map[string]interface{}{"alg": "HS256", "kid": "rs"}, err: ErrTokenSignature},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token := signers[test.signer].token(t, test.header, claims)
+			if _, err := validator.Validate(token); err != test.err {
+				t.Errorf("expected error %v, got %v", test.err, err)
+			}
+		})
+	}
+
+	t.Run("tampered claims", func(t *testing.T) {
+		token := signers["HS256"].token(t, map[string]interface{}{"alg": "HS256"}, claims)
+		other := signers["HS256"].token(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "admin"})
+
+		// the claims of the other token with the signature of the first
+		var (
+			parts      = strings.Split(token, ".")
+			otherParts = strings.Split(other, ".")
+		)
+		if _, err := validator.Validate(parts[0] + "." + otherParts[1] + "." + parts[2]); err != ErrTokenSignature {
+			t.Errorf("expected error %v, got %v", ErrTokenSignature, err)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, token := range []string{"", "a.b", "a.b.c.d", "!.!.!"} {
+			if _, err := validator.Validate(token); err != ErrTokenMalformed {
+				t.Errorf("%q: expected error %v, got %v", token, ErrTokenMalformed, err)
+			}
+		}
+	})
+}
+
+func TestTokenValidatorCheck(t *testing.T) {
+	var (
+		validator = &TokenValidator{issuer: testIssuer, audience: testAudience}
+		now       = time.Unix(1600000000, 0)
+	)
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		valid  bool
+	}{
+		{
+			name:   "valid",
+			claims: map[string]interface{}{"iss": testIssuer, "aud": testAudience, "exp": json.Number("1600003600")},
+			valid:  true,
+		},
+		{
+			name:   "no expiry",
+			claims: map[string]interface{}{"iss": testIssuer, "aud": testAudience},
+		},
+		{
+			name:   "expired",
+			claims: map[string]interface{}{"iss": testIssuer, "aud": testAudience, "exp": json.Number("1599999960")},
+		},
+		{
+			name:   "expired within leeway",
+			claims: map[string]interface{}{"iss": testIssuer, "aud": testAudience, "exp": json.Number("1599999980")},
+			valid:  true,
+		},
+		{
+			name:   "not valid yet",
+			claims: map[string]interface{}{"iss": testIssuer, "aud": testAudience, "exp": json.Number("1600003600"), "nbf": json.Number("1600000060")},
+		},
+		{
+			name:   "not valid yet within leeway",
+			claims: map[string]interface{}{"iss": testIssuer, "aud": testAudience, "exp": json.Number("1600003600"), "nbf": json.Number("1600000020.5")},
+			valid:  true,
+		},
+		{
+			name:   "malformed nbf",
+			claims: map[string]interface{}{"iss": testIssuer, "aud": testAudience, "exp": json.Number("1600003600"), "nbf": "soon"},
+		},
+		{
+			name:   "untrusted issuer",
+			claims: map[string]interface{}{"iss": "https://other", "aud": testAudience, "exp": json.Number("1600003600")},
+		},
+		{
+			name:   "missing issuer",
+			claims: map[string]interface{}{"aud": testAudience, "exp": json.Number("1600003600")},
+		},
+		{
+			name:   "audience in a list",
+			claims: map[string]interface{}{"iss": testIssuer, "aud": []interface{}{"other", testAudience}, "exp": json.Number("1600003600")},
+			valid:  true,
+		},
+		{
+			name:   "other audience",
+			claims: map[string]interface{}{"iss": testIssuer, "aud": []interface{}{"other"}, "exp": json.Number("1600003600")},
+		},
+		{
+			name:   "missing audience",
+			claims: map[string]interface{}{"iss": testIssuer, "exp": json.Number("1600003600")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := parseClaims(test.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := validator.check(claims, now); (err == nil) != test.valid {
+				t.Errorf("expected valid=%t, got error %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestNumericDate(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected time.Time
+		err      error
+	}{
+		{value: json.Number("1600000000"), expected: time.Unix(1600000000, 0)},
+		{value: json.Number("1600000000.25"), expected: time.Unix(1600000000, 250000000)},
+		{value: json.Number("-1"), expected: time.Unix(-1, 0)},
+		{value: json.Number("1e300"), err: ErrTokenMalformed},
+		{value: json.Number("1e400"), err: ErrTokenMalformed},
+		{value: json.Number("NaN"), err: ErrTokenMalformed},
+		{value: json.Number("-1e19"), err: ErrTokenMalformed},
+		{value: "1600000000", err: ErrTokenMalformed},
+	}
+
+	for _, test := range tests {
+		actual, err := numericDate(test.value)
+		if err != test.err {
+			t.Errorf("%v: expected error %v, got %v", test.value, test.err, err)
+			continue
+		}
+		if !actual.Equal(test.expected) {
+			t.Errorf("%v: expected %s, got %s", test.value, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,437 @@
+package routeguide
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// ErrSubscriberOverflow is returned when a RouteChat stream falls too far
+// behind the notes it's subscribed to.
+var ErrSubscriberOverflow = errors.New("subscriber fell too far behind the notes")
+
+const (
+	// maxQueuedNotes is the number of notes queued for a subscriber, before
+	// it's dropped.
+	maxQueuedNotes = 1024
+
+	// maxAcceptedNotes is the number of recently accepted notes whose IDs are
+	// remembered, to deduplicate the notes that are resent.
+	maxAcceptedNotes = 1 << 16
+)
+
+// noteBroker fans out the route notes to the RouteChat streams that are
+// subscribed to their locations, and keeps the recent history of the notes
+// sent from every location. It also tracks the participants at every
+// location, and announces their arrivals and departures.
+type noteBroker struct {
+	history     *noteHistory
+	presence    *presence
+	log         *NoteLog
+	subscribers map[*noteSubscriber]struct{}
+	sequence    int64
+	accepted    map[string]*pb.RouteNote
+	acceptOrder *list.List
+	mutex       sync.Mutex
+}
+
+// newNoteBroker returns a broker whose history is bounded by the retention
+// policy. If the note log isn't nil, the history is restored from the log,
+// and the published notes are appended to it.
+func newNoteBroker(retention RetentionPolicy, noteLog *NoteLog) (*noteBroker, error) {
+	b := &noteBroker{
+		history:     newNoteHistory(retention),
+		presence:    newPresence(),
+		log:         noteLog,
+		subscribers: make(map[*noteSubscriber]struct{}),
+		accepted:    make(map[string]*pb.RouteNote),
+		acceptOrder: list.New(),
+	}
+
+	if noteLog == nil {
+		return b, nil
+	}
+
+	var (
+		now      = time.Now()
+		replayed int
+	)
+	err := noteLog.Replay(func(note *pb.RouteNote, sentAt time.Time) {
+		if note.Sequence > b.sequence {
+			b.sequence = note.Sequence
+		}
+		if retention.TTL > 0 && now.Sub(sentAt) > retention.TTL {
+			return
+		}
+
+		b.accept(note)
+		b.history.append(noteKey(note.Location), note, sentAt)
+		replayed++
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[notelog] replayed %d notes", replayed)
+
+	return b, nil
+}
+
+// noteSubscriber is the subscription of a RouteChat stream to locations and
+// areas. Notes are queued for the stream's writer, so that publishers are
+// never blocked by slow streams.
+type noteSubscriber struct {
+	// keys, areas, the participant's location and closed are guarded by
+	// the broker's mutex
+	keys     map[string]bool
+	areas    []*pb.Rectangle
+	sender   string
+	at       string
+	location *pb.Point
+	closed   bool
+
+	queue   []*pb.RouteNote
+	wake    chan struct{}
+	dropped chan struct{}
+	mutex   sync.Mutex
+}
+
+// subscriber returns a new subscriber that isn't subscribed to anything yet.
+func (b *noteBroker) subscriber() *noteSubscriber {
+	s := &noteSubscriber{
+		keys:    make(map[string]bool),
+		wake:    make(chan struct{}, 1),
+		dropped: make(chan struct{}),
+	}
+
+	b.mutex.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mutex.Unlock()
+
+	return s
+}
+
+// remove unsubscribes the subscriber from everything, and announces the
+// departure of its participant. The subscriber can't move or subscribe
+// afterwards, since its stream may still be receiving notes.
+func (b *noteBroker) remove(s *noteSubscriber) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	s.closed = true
+	delete(b.subscribers, s)
+	b.depart(s, ptypes.TimestampNow())
+}
+
+// move moves the participant of the subscriber to the location, announcing
+// its departure from its previous location and its arrival at the new one.
+func (b *noteBroker) move(s *noteSubscriber, sender string, location *pb.Point) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	key := noteKey(location)
+	if s.closed || (s.at == key && s.sender == sender) {
+		return
+	}
+
+	now := ptypes.TimestampNow()
+	b.depart(s, now)
+
+	s.sender, s.at, s.location = sender, key, location
+	if b.presence.join(key, sender, location, now) {
+		b.fanOut(key, &pb.RouteNote{
+			Kind:     pb.RouteNote_JOIN,
+			Location: location,
+			SenderId: sender,
+			SentAt:   now,
+		})
+	}
+}
+
+// depart removes the participant of the subscriber from its location,
+// announcing its departure if it has no other streams there. The caller must
+// hold the mutex.
+func (b *noteBroker) depart(s *noteSubscriber, now *timestamp.Timestamp) {
+	if s.at == "" {
+		return
+	}
+
+	if b.presence.leave(s.at, s.sender) {
+		b.fanOut(s.at, &pb.RouteNote{
+			Kind:     pb.RouteNote_LEAVE,
+			Location: s.location,
+			SenderId: s.sender,
+			SentAt:   now,
+		})
+	}
+	s.at, s.location = "", nil
+}
+
+// participants returns the participants at the point, in the order they
+// arrived.
+func (b *noteBroker) participants(point *pb.Point) []*pb.Participant {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.presence.at(noteKey(point))
+}
+
+// subscribe subscribes to the notes sent from the point, queueing the history
+// of the point if the subscriber wasn't subscribed to it yet.
+func (b *noteBroker) subscribe(s *noteSubscriber, point *pb.Point) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	key := noteKey(point)
+	if s.closed || s.keys[key] {
+		return
+	}
+
+	s.keys[key] = true
+	s.replay(b.history.get(key))
+}
+
+// subscribeArea subscribes to the notes sent from the points within the area,
+// queueing their history if the subscriber wasn't subscribed to the area yet.
+func (b *noteBroker) subscribeArea(s *noteSubscriber, area *pb.Rectangle) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if s.closed {
+		return
+	}
+	for _, subscribed := range s.areas {
+		if proto.Equal(subscribed, area) {
+			return
+		}
+	}
+
+	s.areas = append(s.areas, area)
+	s.replay(b.history.within(area))
+}
+
+// unsubscribe unsubscribes from the notes sent from the point.
+func (b *noteBroker) unsubscribe(s *noteSubscriber, point *pb.Point) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(s.keys, noteKey(point))
+}
+
+// unsubscribeArea unsubscribes from the notes sent from the area.
+func (b *noteBroker) unsubscribeArea(s *noteSubscriber, area *pb.Rectangle) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for i, subscribed := range s.areas {
+		if proto.Equal(subscribed, area) {
+			s.areas = append(s.areas[:i], s.areas[i+1:]...)
+			return
+		}
+	}
+}
+
+// publish assigns the note its ID, timestamp and sequence number, appends it
+// to the log and to the history of its location, and queues it for all the
+// subscribers of the location. Subscribers that fall too far behind are
+// dropped. It returns the accepted note. If the sender already sent a note
+// with the same ID, the note isn't published again, and the note accepted
+// before is returned. If the note can't be appended to the log, it isn't
+// delivered.
+func (b *noteBroker) publish(note *pb.RouteNote) (*pb.RouteNote, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if note.Id == "" {
+		id, err := randomID()
+		if err != nil {
+			return nil, err
+		}
+		note.Id = id
+	} else if accepted, exists := b.accepted[acceptedKey(note)]; exists {
+		return accepted, nil
+	}
+
+	sentAt := time.Now()
+	timestamp, err := ptypes.TimestampProto(sentAt)
+	if err != nil {
+		return nil, err
+	}
+	note.SentAt = timestamp
+	note.Sequence = b.sequence + 1
+
+	compact := false
+	if b.log != nil {
+		if compact, err = b.log.Append(note, sentAt); err != nil {
+			return nil, err
+		}
+	}
+	b.sequence++
+	b.accept(note)
+
+	key := noteKey(note.Location)
+	b.history.append(key, note, sentAt)
+
+	if compact {
+		b.compact()
+	}
+
+	b.fanOut(key, note)
+	return note, nil
+}
+
+// fanOut queues the note for all the subscribers of its location, dropping
+// the subscribers that fall too far behind. The caller must hold the mutex.
+func (b *noteBroker) fanOut(key string, note *pb.RouteNote) {
+	for s := range b.subscribers {
+		if !s.subscribed(key, note.Location) {
+			continue
+		}
+
+		if !s.enqueue(note) {
+			delete(b.subscribers, s)
+		}
+	}
+}
+
+// accept remembers the ID of the accepted note, forgetting the oldest IDs
+// beyond maxAcceptedNotes. The caller must hold the mutex.
+func (b *noteBroker) accept(note *pb.RouteNote) {
+	key := acceptedKey(note)
+	if _, exists := b.accepted[key]; exists {
+		return
+	}
+
+	b.accepted[key] = note
+	b.acceptOrder.PushBack(key)
+	if b.acceptOrder.Len() > maxAcceptedNotes {
+		oldest := b.acceptOrder.Remove(b.acceptOrder.Front()).(string)
+		delete(b.accepted, oldest)
+	}
+}
+
+// compact seals the log, and compacts the sealed segments into a snapshot of
+// the history in the background. The caller must hold the mutex, so that the
+// snapshot covers exactly the sealed segments.
+func (b *noteBroker) compact() {
+	through, err := b.log.Seal()
+	if err != nil {
+		log.Printf("[notelog] (error) %s", err)
+		return
+	}
+
+	notes := b.history.snapshot()
+	go func() {
+		if err := b.log.Compact(through, notes); err != nil {
+			log.Printf("[notelog] (error) %s", err)
+		}
+	}()
+}
+
+// subscribed returns true if the subscriber is subscribed to the location, or
+// to an area that contains it. The caller must hold the broker's mutex.
+func (s *noteSubscriber) subscribed(key string, location *pb.Point) bool {
+	if s.keys[key] {
+		return true
+	}
+
+	for _, area := range s.areas {
+		if inRange(location, area) {
+			return true
+		}
+	}
+	return false
+}
+
+// replay queues the history of a location. The history isn't bounded by the
+// queue limit, since it's sent only once per subscription.
+func (s *noteSubscriber) replay(notes []*pb.RouteNote) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.queue = append(s.queue, notes...)
+	s.notify()
+}
+
+// enqueue queues the note, returning false if the subscriber fell too far
+// behind and was dropped.
+func (s *noteSubscriber) enqueue(note *pb.RouteNote) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.queue) >= maxQueuedNotes {
+		close(s.dropped)
+		return false
+	}
+
+	s.queue = append(s.queue, note)
+	s.notify()
+	return true
+}
+
+// acknowledge queues the ACK of a note sent by the subscriber. ACKs aren't
+// bounded by the queue limit, since the subscriber sends at most one note per
+// ACK.
+func (s *noteSubscriber) acknowledge(note *pb.RouteNote) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.queue = append(s.queue, &pb.RouteNote{
+		Kind:     pb.RouteNote_ACK,
+		Location: note.Location,
+		Id:       note.Id,
+		SenderId: note.SenderId,
+		SentAt:   note.SentAt,
+		Sequence: note.Sequence,
+	})
+	s.notify()
+}
+
+// drain returns the queued notes, emptying the queue.
+func (s *noteSubscriber) drain() []*pb.RouteNote {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	queue := s.queue
+	s.queue = nil
+	return queue
+}
+
+// notify wakes the writer up without blocking. The caller must hold the
+// subscriber's mutex.
+func (s *noteSubscriber) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// randomID returns a random ID of 16 hex digits, e.g. for a note sent without
+// an ID.
+func randomID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// acceptedKey returns the key that identifies the note among the notes of all
+// the senders.
+func acceptedKey(note *pb.RouteNote) string {
+	return note.SenderId + "/" + note.Id
+}
+
+// noteKey returns the key of the notes sent from the point.
+func noteKey(point *pb.Point) string {
+	return fmt.Sprintf("(%d,%d)", point.GetLatitude(), point.GetLongitude())
+}
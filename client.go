@@ -6,12 +6,17 @@ import (
 	"io"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	pb "github.com/ihcsim/routeguide/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -19,29 +24,59 @@ const (
 	APIListFeatures = "listfeatures"
 	APIRecordRoute  = "recordroute"
 	APIRouteChat    = "routechat"
+	APIFindNearest  = "findnearest"
+
+	APIListFeaturesPage = "listfeaturespage"
+	APIWatchFeatures    = "watchfeatures"
+	APIListRoutes       = "listroutes"
+	APIListParticipants = "listparticipants"
+	APIRecordTimedRoute = "recordtimedroute"
+
+	APICreateGeofence      = "creategeofence"
+	APIWatchGeofenceEvents = "watchgeofenceevents"
 
 	unknownServerName = "unknown"
+	defaultNearestK   = 5
+	clientPageSize    = 10
+	listRoutesPeriod  = time.Hour
+
+	// the geofences created by the client are large enough to be entered by
+	// the random points of the other APIs
+	geofenceRadius = 2000 * 1000
+	geofenceDwell  = 10 * time.Second
+
+	timedRouteInterval = 30 * time.Second
+	timedRouteGap      = 5 * time.Minute
 )
 
 // Client knows how to communicate with the GRPC server.
 type Client struct {
 	GRPC pb.RouteGuideClient
+
+	// ID identifies the client to the server. If empty, the server identifies
+	// the client by its address.
+	ID string
+
+	// resourceVersion is the version of the last feature event received by
+	// WatchFeatures, so that the next watch resumes from it.
+	resourceVersion int64
 }
 
 // GetFeature interacts with the GetFeature API on the GRPC server.
 func (c *Client) GetFeature(ctx context.Context) error {
 	var (
 		header metadata.MD
+		p      peer.Peer
 		point  = randPoint()
 	)
 	log.Printf("[GetFeature] (req) %+v\n", point)
 
-	feature, err := c.GRPC.GetFeature(ctx, point, grpc.Header(&header))
+	feature, err := c.GRPC.GetFeature(c.outgoing(ctx), point, grpc.Header(&header), grpc.Peer(&p))
 	if err != nil {
 		return err
 	}
 
-	output(APIGetFeature, header, feature)
+	output(APIGetFeature, header, &p, feature)
 	return nil
 }
 
@@ -53,7 +88,7 @@ func (c *Client) ListFeatures(ctx context.Context) error {
 	}
 	log.Printf("[ListFeatures] (req) %+v, %+v\n", rectangle.Lo, rectangle.Hi)
 
-	stream, err := c.GRPC.ListFeatures(ctx, rectangle)
+	stream, err := c.GRPC.ListFeatures(c.outgoing(ctx), rectangle)
 	if err != nil {
 		return err
 	}
@@ -73,7 +108,7 @@ func (c *Client) ListFeatures(ctx context.Context) error {
 			return err
 		}
 
-		output(APIListFeatures, header, feature)
+		output(APIListFeatures, header, streamPeer(stream), feature)
 	}
 
 	return nil
@@ -81,7 +116,7 @@ func (c *Client) ListFeatures(ctx context.Context) error {
 
 // RecourdRoute interacts with the RecordRoute API on the GRPC server.
 func (c *Client) RecordRoute(ctx context.Context) error {
-	stream, err := c.GRPC.RecordRoute(ctx)
+	stream, err := c.GRPC.RecordRoute(c.outgoing(ctx))
 	if err != nil {
 		return err
 	}
@@ -105,52 +140,457 @@ func (c *Client) RecordRoute(ctx context.Context) error {
 		return err
 	}
 
-	output(APIRecordRoute, header, summary)
+	output(APIRecordRoute, header, streamPeer(stream), summary)
 	return nil
 }
 
-// RouteChat interacts with the RouteChat API on the GRPC server.
+// RecordTimedRoute interacts with the RecordTimedRoute API on the GRPC server.
+// It uploads random points that were recorded in the past, at regular
+// intervals with a gap in the middle.
+func (c *Client) RecordTimedRoute(ctx context.Context) error {
+	track := make([]TrackPoint, 20)
+	recordedAt := time.Now().Add(-time.Hour)
+	for i := range track {
+		interval := timedRouteInterval
+		if i == len(track)/2 {
+			interval = timedRouteGap
+		}
+		recordedAt = recordedAt.Add(interval)
+
+		track[i] = TrackPoint{Point: randPoint(), Time: recordedAt}
+	}
+
+	return c.RecordTrack(ctx, track, 0)
+}
+
+// RecordTrack streams the points of the track and their recorded times to the
+// RecordTimedRoute API on the GRPC server. If speed is positive, the points
+// are sent with the intervals between their recorded times, divided by speed.
+// Otherwise, they are sent without delay.
+func (c *Client) RecordTrack(ctx context.Context, track []TrackPoint, speed float64) error {
+	stream, err := c.GRPC.RecordTimedRoute(c.outgoing(ctx))
+	if err != nil {
+		return err
+	}
+
+	for i, trackPoint := range track {
+		if speed > 0 && i > 0 && !trackPoint.Time.IsZero() && !track[i-1].Time.IsZero() {
+			interval := time.Duration(float64(trackPoint.Time.Sub(track[i-1].Time)) / speed)
+			if interval > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(interval):
+				}
+			}
+		}
+
+		timed := &pb.TimedPoint{Point: trackPoint.Point}
+		if !trackPoint.Time.IsZero() {
+			if timed.Time, err = ptypes.TimestampProto(trackPoint.Time); err != nil {
+				return err
+			}
+		}
+
+		log.Printf("[RecordTimedRoute] (req) %+v\n", timed)
+		if err := stream.Send(timed); err != nil {
+			if err == io.EOF {
+				// the server closed the stream, its status is returned by
+				// CloseAndRecv
+				break
+			}
+			return err
+		}
+	}
+
+	summary, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+
+	header, err := stream.Header()
+	if err != nil {
+		return err
+	}
+
+	output(APIRecordTimedRoute, header, streamPeer(stream), summary)
+	return nil
+}
+
+// RouteChat interacts with the RouteChat API on the GRPC server. It subscribes
+// to a random area, and sends notes from random locations while receiving the
+// notes of the other participants. The notes that are delivered more than
+// once are skipped, and the notes that weren't acknowledged are logged.
 func (c *Client) RouteChat(ctx context.Context) error {
-	stream, err := c.GRPC.RouteChat(ctx)
+	stream, err := c.GRPC.RouteChat(c.outgoing(ctx))
 	if err != nil {
 		return err
 	}
 
+	var (
+		// pending holds the IDs of the sent notes that weren't acknowledged
+		pending = map[string]bool{}
+		mutex   sync.Mutex
+	)
+
+	// the notes are received concurrently, since other participants' notes
+	// can arrive at any time
+	received := make(chan error, 1)
+	go func() {
+		delivered := map[int64]bool{}
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				received <- err
+				return
+			}
+
+			if resp.Kind == pb.RouteNote_ACK {
+				log.Printf("[RouteChat] (ack) id=%s sequence=%d\n", resp.Id, resp.Sequence)
+				mutex.Lock()
+				delete(pending, resp.Id)
+				mutex.Unlock()
+				continue
+			}
+
+			// overlapping subscriptions deliver the same history more than
+			// once
+			if resp.Kind == pb.RouteNote_NOTE && delivered[resp.Sequence] {
+				log.Printf("[RouteChat] (dup) id=%s sequence=%d\n", resp.Id, resp.Sequence)
+				continue
+			}
+			delivered[resp.Sequence] = true
+
+			header, err := stream.Header()
+			if err != nil {
+				received <- err
+				return
+			}
+
+			output(APIRouteChat, header, streamPeer(stream), resp)
+		}
+	}()
+
+	subscribe := &pb.RouteNote{
+		Kind: pb.RouteNote_SUBSCRIBE,
+		Area: &pb.Rectangle{
+			Lo: randPoint(),
+			Hi: randPoint(),
+		},
+	}
+	log.Printf("[RouteChat] (req) %+v\n", subscribe)
+	if err := stream.Send(subscribe); err != nil {
+		return err
+	}
+
 	for i := 0; i < 20; i++ {
+		id, err := randomID()
+		if err != nil {
+			return err
+		}
+
 		var (
 			point = randPoint()
-			msg   = fmt.Sprintf("[%s] ack=0 msg='message #%d'", time.Now(), i)
+			msg   = fmt.Sprintf("[%s] msg='message #%d'", time.Now(), i)
 			note  = &pb.RouteNote{
+				Id:       id,
 				Location: point,
 				Message:  msg,
 			}
 		)
 		log.Printf("[RouteChat] (req) %+v\n", note)
 
+		mutex.Lock()
+		pending[id] = true
+		mutex.Unlock()
+
 		if err := stream.Send(note); err != nil {
+			if err == io.EOF {
+				// the server closed the stream, its status is returned by
+				// the receiver
+				break
+			}
 			return err
 		}
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	if err := <-received; err != nil {
+		return err
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("%d notes weren't acknowledged", len(pending))
+	}
+	return nil
+}
+
+// FindNearest interacts with the FindNearest API on the GRPC server.
+func (c *Client) FindNearest(ctx context.Context) error {
+	var (
+		header metadata.MD
+		p      peer.Peer
+		req    = &pb.NearestRequest{
+			Point: randPoint(),
+			K:     defaultNearestK,
+		}
+	)
+	log.Printf("[FindNearest] (req) %+v\n", req)
 
-		resp, err := stream.Recv()
+	resp, err := c.GRPC.FindNearest(c.outgoing(ctx), req, grpc.Header(&header), grpc.Peer(&p))
+	if err != nil {
+		return err
+	}
+
+	output(APIFindNearest, header, &p, resp)
+	return nil
+}
+
+// ListFeaturesPage interacts with the ListFeaturesPage API on the GRPC server.
+// It lists the named features within a random rectangle, page by page, in the
+// order of their names.
+func (c *Client) ListFeaturesPage(ctx context.Context) error {
+	req := &pb.ListFeaturesPageRequest{
+		Rectangle: &pb.Rectangle{
+			Lo: randPoint(),
+			Hi: randPoint(),
+		},
+		PageSize:       clientPageSize,
+		OrderBy:        pb.ListFeaturesPageRequest_NAME,
+		ExcludeUnnamed: true,
+	}
+
+	for {
+		var (
+			header metadata.MD
+			p      peer.Peer
+		)
+		log.Printf("[ListFeaturesPage] (req) %+v\n", req)
+
+		resp, err := c.GRPC.ListFeaturesPage(c.outgoing(ctx), req, grpc.Header(&header), grpc.Peer(&p))
 		if err != nil {
 			return err
 		}
+		output(APIListFeaturesPage, header, &p, resp)
+
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+// WatchFeatures interacts with the WatchFeatures API on the GRPC server. It
+// watches all the features until the context is done. Every watch resumes
+// from the last event seen by the previous watch.
+func (c *Client) WatchFeatures(ctx context.Context) error {
+	req := &pb.WatchFeaturesRequest{
+		Rectangle: &pb.Rectangle{
+			Lo: &pb.Point{Latitude: -900000000, Longitude: -1800000000},
+			Hi: &pb.Point{Latitude: 900000000, Longitude: 1800000000},
+		},
+		ResourceVersion: c.resourceVersion,
+	}
+	log.Printf("[WatchFeatures] (req) %+v\n", req)
+
+	stream, err := c.GRPC.WatchFeatures(c.outgoing(ctx), req)
+	if err != nil {
+		return err
+	}
+
+	synced := req.ResourceVersion != 0
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			switch status.Code(err) {
+			case codes.DeadlineExceeded, codes.Canceled:
+				// the watch ends with the context
+				return nil
+			case codes.OutOfRange:
+				log.Printf("[WatchFeatures] %s, restarting from the current features", err)
+				c.resourceVersion = 0
+				return nil
+			}
+
+			return err
+		}
+
+		header, err := stream.Header()
+		if err != nil {
+			return err
+		}
+		output(APIWatchFeatures, header, streamPeer(stream), event)
+
+		if event.Type == pb.FeatureEvent_SYNCED {
+			synced = true
+		}
+		if synced {
+			c.resourceVersion = event.ResourceVersion
+		}
+	}
+}
+
+// CreateGeofence interacts with the CreateGeofence API on the GRPC server. It
+// creates a geofence around a random point.
+func (c *Client) CreateGeofence(ctx context.Context) error {
+	var (
+		header metadata.MD
+		p      peer.Peer
+		req    = &pb.Geofence{
+			Area: &pb.Geofence_Circle{Circle: &pb.Circle{
+				Center:       randPoint(),
+				RadiusMeters: geofenceRadius,
+			}},
+			Dwell: ptypes.DurationProto(geofenceDwell),
+		}
+	)
+	log.Printf("[CreateGeofence] (req) %+v\n", req)
+
+	resp, err := c.GRPC.CreateGeofence(c.outgoing(ctx), req, grpc.Header(&header), grpc.Peer(&p))
+	if err != nil {
+		return err
+	}
+
+	output(APICreateGeofence, header, &p, resp)
+	return nil
+}
+
+// WatchGeofenceEvents interacts with the WatchGeofenceEvents API on the GRPC
+// server. It watches the events of all the geofences, until the context is
+// done.
+func (c *Client) WatchGeofenceEvents(ctx context.Context) error {
+	req := &pb.WatchGeofenceEventsRequest{}
+	log.Printf("[WatchGeofenceEvents] (req) %+v\n", req)
+
+	stream, err := c.GRPC.WatchGeofenceEvents(c.outgoing(ctx), req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			switch status.Code(err) {
+			case codes.DeadlineExceeded, codes.Canceled:
+				// the watch ends with the context
+				return nil
+			}
+
+			return err
+		}
+
+		header, err := stream.Header()
+		if err != nil {
+			return err
+		}
+		output(APIWatchGeofenceEvents, header, streamPeer(stream), event)
+	}
+}
+
+// ListRoutes interacts with the ListRoutes API on the GRPC server. It lists
+// the routes recorded by the client in the last hour.
+func (c *Client) ListRoutes(ctx context.Context) error {
+	after, err := ptypes.TimestampProto(time.Now().Add(-listRoutesPeriod))
+	if err != nil {
+		return err
+	}
+
+	req := &pb.ListRoutesRequest{
+		ClientId:     c.ID,
+		StartedAfter: after,
+	}
+	log.Printf("[ListRoutes] (req) %+v\n", req)
+
+	stream, err := c.GRPC.ListRoutes(c.outgoing(ctx), req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		route, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return err
+		}
 
 		header, err := stream.Header()
 		if err != nil {
 			return err
 		}
 
-		output(APIRouteChat, header, resp)
+		output(APIListRoutes, header, streamPeer(stream), route)
+	}
+
+	return nil
+}
+
+// ListParticipants interacts with the ListParticipants API on the GRPC server.
+// It lists the RouteChat participants at a random point.
+func (c *Client) ListParticipants(ctx context.Context) error {
+	var (
+		header metadata.MD
+		p      peer.Peer
+		point  = randPoint()
+	)
+	log.Printf("[ListParticipants] (req) %+v\n", point)
+
+	resp, err := c.GRPC.ListParticipants(c.outgoing(ctx), point, grpc.Header(&header), grpc.Peer(&p))
+	if err != nil {
+		return err
 	}
 
+	output(APIListParticipants, header, &p, resp)
 	return nil
 }
 
-func output(api string, metadata metadata.MD, content proto.Message) {
+// ExportRoute writes the recorded route with the given ID to w, as a GPX
+// document.
+func (c *Client) ExportRoute(ctx context.Context, id string, w io.Writer) error {
+	log.Printf("[ExportRoute] (req) id:%q\n", id)
+
+	export, err := c.GRPC.ExportRoute(c.outgoing(ctx), &pb.RouteRequest{Id: id})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(export.Data)
+	return err
+}
+
+// outgoing returns the context with the client's identity attached to the
+// outgoing metadata.
+func (c *Client) outgoing(ctx context.Context) context.Context {
+	if c.ID == "" {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, MetadataClientIDKey, c.ID)
+}
+
+func output(api string, metadata metadata.MD, p *peer.Peer, content proto.Message) {
 	server := unknownServerName
 	if serverName, ok := metadata["server"]; ok && len(serverName) > 0 {
 		server = serverName[0]
 	}
+
+	// the identity of the server is only known over TLS
+	if identity := PeerIdentity(p); identity != "" {
+		server = fmt.Sprintf("%s, peer=%s", server, identity)
+	}
 	log.Printf("[%s] {resp) (server=%s) %+v\n", strings.Title(api), server, content)
 }
+
+// streamPeer returns the peer of the stream.
+func streamPeer(stream grpc.ClientStream) *peer.Peer {
+	p, _ := peer.FromContext(stream.Context())
+	return p
+}
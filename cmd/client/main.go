@@ -17,12 +17,15 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/balancer/roundrobin"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
 )
 
 const (
 	modeFirehose = "firehose"
 	modeRepeatN  = "repeatn"
+	modeExport   = "export"
+	modeTrack    = "track"
 
 	defaultServer       = ":8080"
 	defaultTimeout      = time.Second * 20
@@ -38,20 +41,54 @@ func main() {
 	var (
 		server    = flag.String("server", defaultServer, "Name or IP of the target server, including port number.")
 		timeout   = flag.Duration("timeout", defaultTimeout, "Default connection timeout")
-		mode      = flag.String("mode", defaultMode, "Default mode to start the client in. Supported values: repeatn firehose")
+		mode      = flag.String("mode", defaultMode, "Default mode to start the client in. Supported values: repeatn firehose export track")
 		api       = flag.String("api", defaultAPI, "In the repeatn mode, this indicates the remote API to target")
 		n         = flag.Int("n", defaultN, "In the repeatn mode, this is the number of API calls to be repeated")
 		enableLB  = flag.Bool("enable-load-balancing", false, "Set to true to enable client-side load balancing")
 		serverIPs = flag.String("server-ipv4", defaultServerAddr, "If load balancing is enabled, this is a list of comma-separated server addresses used by the GRPC name resolver")
 		resolver  = flag.String("resolver", defaultResolverType, "The resolver to use. Supported values: dns manual")
+		clientID  = flag.String("client-id", "", "The identity of the client, sent with every request and attached to the recorded routes. Defaults to the client's address")
+		routeID   = flag.String("route-id", "", "In the export mode, this is the ID of the recorded route to write to stdout as GPX")
+		trackFile = flag.String("track-file", "", "In the track mode, this is the GPX or KML track file to record")
+		speed     = flag.Float64("speed", 0, "In the track mode, this is the multiplier of the recorded timing of the track points. 1 replays the track in real time. 0 sends the points without delay")
 
-		opts = []grpc.DialOption{grpc.WithInsecure()}
+		useTLS        = flag.Bool("tls", false, "Set to true to connect over TLS, verifying the server certificate against the system roots. Implied by the other TLS flags")
+		tlsCAFile     = flag.String("tls-ca-file", "", "Path to the PEM-encoded CA bundle that the server certificate is verified with")
+		tlsCertFile   = flag.String("tls-cert-file", "", "Path to the PEM-encoded certificate that the client presents to servers that require mutual TLS")
+		tlsKeyFile    = flag.String("tls-key-file", "", "Path to the PEM-encoded private key of the client certificate")
+		tlsServerName = flag.String("tls-server-name", "", "The name that the server certificate is verified against. Defaults to the host of the server address")
+		token         = flag.String("token", "", "The bearer token that the requests are authenticated with")
+		tokenFile     = flag.String("token-file", "", "Path to a file holding the bearer token that the requests are authenticated with. It's read before every request, so that the token can be refreshed")
+
+		opts = []grpc.DialOption{}
 	)
 	flag.Parse()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	secure := *useTLS || *tlsCAFile != "" || *tlsCertFile != ""
+	if secure {
+		certs, err := routeguide.NewCertReloader(*tlsCertFile, *tlsKeyFile, *tlsCAFile, routeguide.DefaultCertReloadInterval)
+		if err != nil {
+			log.Fatalf("[main] fail to load TLS certificates: %s", err)
+		}
+		go certs.Run(nil, ctx.Done())
+
+		log.Printf("[main] TLS: enabled (ca: %s, cert: %s)", *tlsCAFile, *tlsCertFile)
+		creds := credentials.NewTLS(certs.ClientConfig(*tlsServerName))
+		opts = append(opts, grpc.WithTransportCredentials(creds))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	if *token != "" || *tokenFile != "" {
+		if !secure {
+			log.Println("[main] (warning) the bearer token is sent in plaintext")
+		}
+		opts = append(opts, grpc.WithPerRPCCredentials(routeguide.NewTokenCredentials(*token, *tokenFile, !secure)))
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, os.Kill)
 	go func() {
@@ -80,7 +117,7 @@ func main() {
 	defer conn.Close()
 
 	grpcClient := pb.NewRouteGuideClient(conn)
-	client := routeguide.Client{GRPC: grpcClient}
+	client := routeguide.Client{GRPC: grpcClient, ID: *clientID}
 
 	log.Printf("[main] running in %s mode", *mode)
 	switch strings.ToLower(*mode) {
@@ -92,8 +129,19 @@ func main() {
 		if err := repeatN(ctx, client, *timeout, *api, *n); err != nil && err != context.Canceled {
 			log.Fatalf("[main] %s", err)
 		}
+	case modeExport:
+		ctx, cancel := context.WithTimeout(ctx, *timeout)
+		defer cancel()
+
+		if err := client.ExportRoute(ctx, *routeID, os.Stdout); err != nil {
+			log.Fatalf("[main] %s", err)
+		}
+	case modeTrack:
+		if err := recordTrack(ctx, client, *trackFile, *speed); err != nil && err != context.Canceled {
+			log.Fatalf("[main] %s", err)
+		}
 	default:
-		log.Fatalf("[main] unknown mode %s", mode)
+		log.Fatalf("[main] unknown mode %s", *mode)
 	}
 	log.Println("[main] finished")
 }
@@ -108,38 +156,23 @@ func firehose(ctx context.Context, client routeguide.Client, timeout time.Durati
 			defer cancel()
 
 			// each API has a 25% of being invoked
+			var call func(context.Context) error
 			n := rand.Intn(10)
 			if n < 3 {
-				if err := client.GetFeature(ctx); err != nil {
-					if !isInjectedFault(err) {
-						return err
-					}
-					log.Println(err)
-				}
+				call = client.GetFeature
 			} else if n < 5 && n >= 3 {
-				if err := client.ListFeatures(ctx); err != nil {
-					if !isInjectedFault(err) {
-						return err
-					}
-					log.Println(err)
-				}
+				call = client.ListFeatures
 			} else if n < 7 && n >= 5 {
-				if err := client.RecordRoute(ctx); err != nil {
-					if !isInjectedFault(err) {
-						return err
-					}
-					log.Println(err)
-				}
+				call = client.RecordRoute
 			} else {
-				if err := client.RouteChat(ctx); err != nil {
-					if !isInjectedFault(err) {
-						return err
-					}
-					log.Println(err)
-				}
+				call = client.RouteChat
 			}
 
-			time.Sleep(defaultWait)
+			wait, _, err := backoff(call(ctx))
+			if err != nil {
+				return err
+			}
+			time.Sleep(wait)
 		}
 	}
 }
@@ -154,8 +187,24 @@ func repeatN(ctx context.Context, client routeguide.Client, timeout time.Duratio
 		call = client.ListFeatures
 	case routeguide.APIRecordRoute:
 		call = client.RecordRoute
+	case routeguide.APIRecordTimedRoute:
+		call = client.RecordTimedRoute
 	case routeguide.APIRouteChat:
 		call = client.RouteChat
+	case routeguide.APIFindNearest:
+		call = client.FindNearest
+	case routeguide.APIListFeaturesPage:
+		call = client.ListFeaturesPage
+	case routeguide.APIWatchFeatures:
+		call = client.WatchFeatures
+	case routeguide.APIListRoutes:
+		call = client.ListRoutes
+	case routeguide.APIListParticipants:
+		call = client.ListParticipants
+	case routeguide.APICreateGeofence:
+		call = client.CreateGeofence
+	case routeguide.APIWatchGeofenceEvents:
+		call = client.WatchGeofenceEvents
 	default:
 		return fmt.Errorf("Unsupported API %s", api)
 	}
@@ -165,19 +214,58 @@ func repeatN(ctx context.Context, client routeguide.Client, timeout time.Duratio
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
-		if err := call(ctx); err != nil {
-			if !isInjectedFault(err) {
-				return err
-			}
-			log.Println(err)
+		wait, retry, err := backoff(call(ctx))
+		if err != nil {
+			return err
 		}
-
-		time.Sleep(defaultWait)
+		if retry {
+			i--
+		}
+		time.Sleep(wait)
 	}
 
 	return nil
 }
 
+func recordTrack(ctx context.Context, client routeguide.Client, path string, speed float64) error {
+	if speed < 0 {
+		return fmt.Errorf("speed must not be negative")
+	}
+
+	track, err := routeguide.LoadTrack(path)
+	if err != nil {
+		return err
+	}
+	if speed > 0 {
+		log.Printf("[main] recording %d points from %s at %vx speed", len(track), path, speed)
+	} else {
+		log.Printf("[main] recording %d points from %s without delay", len(track), path)
+	}
+
+	return client.RecordTrack(ctx, track, speed)
+}
+
+// backoff returns how long to wait before the next call, given the error of
+// the last call, and whether the last call should be retried. The calls that
+// were rate limited are retried after the delay that the server asked for.
+// Errors other than injected faults and rate limits are returned.
+func backoff(err error) (time.Duration, bool, error) {
+	if err == nil {
+		return defaultWait, false, nil
+	}
+
+	if delay, ok := routeguide.RetryDelay(err); ok {
+		log.Printf("[main] rate limited, retrying in %s", delay)
+		return delay, true, nil
+	}
+
+	if isInjectedFault(err) {
+		log.Println(err)
+		return defaultWait, false, nil
+	}
+	return 0, false, err
+}
+
 func isInjectedFault(err error) bool {
 	s, ok := status.FromError(err)
 	if !ok {
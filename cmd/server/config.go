@@ -0,0 +1,648 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ihcsim/routeguide"
+	"google.golang.org/grpc"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// envPrefix is the prefix of the environment variables that override the
+// config file, e.g. RG_LISTEN_PORT overrides listen.port.
+const envPrefix = "RG_"
+
+const (
+	clientAuthNone    = "none"
+	clientAuthRequest = "request"
+	clientAuthRequire = "require"
+
+	logOutputStderr = "stderr"
+	logOutputStdout = "stdout"
+)
+
+// config is the configuration of the server. It's read from a YAML or JSON
+// file, whose values are overridden by the environment variables, whose
+// values are in turn overridden by the flags.
+type config struct {
+	Listen     listenConfig     `yaml:"listen" json:"listen"`
+	Store      storeConfig      `yaml:"store" json:"store"`
+	Datasets   datasetsConfig   `yaml:"datasets" json:"datasets"`
+	Routes     routesConfig     `yaml:"routes" json:"routes"`
+	Notes      notesConfig      `yaml:"notes" json:"notes"`
+	Faults     faultsConfig     `yaml:"faults" json:"faults"`
+	Limits     limitsConfig     `yaml:"limits" json:"limits"`
+	Logging    loggingConfig    `yaml:"logging" json:"logging"`
+	TLS        tlsConfig        `yaml:"tls" json:"tls"`
+	Auth       authConfig       `yaml:"auth" json:"auth"`
+	Authz      authzConfig      `yaml:"authz" json:"authz"`
+	RateLimits rateLimitsConfig `yaml:"rateLimits" json:"rateLimits"`
+}
+
+type listenConfig struct {
+	// Port is the port of the GRPC server.
+	Port int `yaml:"port" json:"port"`
+
+	// DebugPort is the port of the /debug/vars endpoint. 0 disables it.
+	DebugPort int `yaml:"debugPort" json:"debugPort"`
+}
+
+type storeConfig struct {
+	Type     string `yaml:"type" json:"type"`
+	BoltPath string `yaml:"boltPath" json:"boltPath"`
+}
+
+type datasetsConfig struct {
+	// Files are the dataset files of the features. If it's empty, the
+	// embedded dataset is used.
+	Files          []string `yaml:"files" json:"files"`
+	ReloadInterval duration `yaml:"reloadInterval" json:"reloadInterval"`
+}
+
+type routesConfig struct {
+	Distance      string   `yaml:"distance" json:"distance"`
+	SnapTolerance float64  `yaml:"snapTolerance" json:"snapTolerance"`
+	IdleSpeed     float64  `yaml:"idleSpeed" json:"idleSpeed"`
+	GapThreshold  duration `yaml:"gapThreshold" json:"gapThreshold"`
+}
+
+type notesConfig struct {
+	TTL                 duration      `yaml:"ttl" json:"ttl"`
+	MaxNotesPerLocation int           `yaml:"maxNotesPerLocation" json:"maxNotesPerLocation"`
+	MaxBytes            int64         `yaml:"maxBytes" json:"maxBytes"`
+	Log                 noteLogConfig `yaml:"log" json:"log"`
+}
+
+type noteLogConfig struct {
+	// Dir is the directory of the note log. If it's empty, the notes are
+	// only kept in memory.
+	Dir          string   `yaml:"dir" json:"dir"`
+	Sync         string   `yaml:"sync" json:"sync"`
+	SyncInterval duration `yaml:"syncInterval" json:"syncInterval"`
+}
+
+type faultsConfig struct {
+	// Percent is the percentage of the requests that fail with a random
+	// fault, between 0 and 100.
+	Percent float64 `yaml:"percent" json:"percent"`
+}
+
+type limitsConfig struct {
+	// The limits are left to GRPC if they are 0.
+	MaxRecvMsgBytes      int    `yaml:"maxRecvMsgBytes" json:"maxRecvMsgBytes"`
+	MaxSendMsgBytes      int    `yaml:"maxSendMsgBytes" json:"maxSendMsgBytes"`
+	MaxConcurrentStreams uint32 `yaml:"maxConcurrentStreams" json:"maxConcurrentStreams"`
+}
+
+type loggingConfig struct {
+	// Output is stderr, stdout, or the path of a file that the logs are
+	// appended to.
+	Output string `yaml:"output" json:"output"`
+
+	// UTC logs the times in UTC instead of the local time zone.
+	UTC bool `yaml:"utc" json:"utc"`
+}
+
+type tlsConfig struct {
+	// CertFile and KeyFile are the PEM-encoded certificate and key of the
+	// server. If they are empty, the server serves plaintext.
+	CertFile string `yaml:"certFile" json:"certFile"`
+	KeyFile  string `yaml:"keyFile" json:"keyFile"`
+
+	// ClientCAFile is the PEM-encoded bundle of the CAs that client
+	// certificates are verified with.
+	ClientCAFile string `yaml:"clientCAFile" json:"clientCAFile"`
+
+	// ClientAuth is none, request or require.
+	ClientAuth string `yaml:"clientAuth" json:"clientAuth"`
+
+	// ReloadInterval is the interval at which the certificate files are
+	// polled for changes. The certificates are also reloaded on SIGHUP.
+	ReloadInterval duration `yaml:"reloadInterval" json:"reloadInterval"`
+}
+
+type authConfig struct {
+	// JWKSFile is the JWKS file that the bearer tokens of the requests are
+	// validated against. If it's empty, the requests aren't authenticated.
+	JWKSFile string `yaml:"jwksFile" json:"jwksFile"`
+
+	// Issuer and Audience are the issuer and the audience that the tokens
+	// must be issued by and for.
+	Issuer   string `yaml:"issuer" json:"issuer"`
+	Audience string `yaml:"audience" json:"audience"`
+
+	// ReloadInterval is the interval at which the JWKS file is polled for
+	// changes. The file is also reloaded on SIGHUP.
+	ReloadInterval duration `yaml:"reloadInterval" json:"reloadInterval"`
+}
+
+type authzConfig struct {
+	// PolicyFile is the YAML or JSON file of the rules that allow the callers
+	// to call the methods. If it's empty, every caller may call every method.
+	PolicyFile string `yaml:"policyFile" json:"policyFile"`
+
+	// IdentityHeader is the metadata header that identifies the callers, in
+	// addition to their client certificates and bearer tokens.
+	IdentityHeader string `yaml:"identityHeader" json:"identityHeader"`
+
+	// ReloadInterval is the interval at which the policy file is polled for
+	// changes. The file is also reloaded on SIGHUP.
+	ReloadInterval duration `yaml:"reloadInterval" json:"reloadInterval"`
+}
+
+type rateLimitsConfig struct {
+	// Default is the limit of the methods that have no limits of their own.
+	Default rateLimitConfig `yaml:"default" json:"default"`
+
+	// Methods are the limits of the methods, by their full names. They can
+	// only be set in the config file.
+	Methods map[string]rateLimitConfig `yaml:"methods" json:"methods"`
+}
+
+type rateLimitConfig struct {
+	// Rate is the number of calls per second that every caller can make. The
+	// calls aren't limited if it's 0.
+	Rate float64 `yaml:"rate" json:"rate"`
+
+	// Burst is the number of calls that every caller can make at once.
+	Burst int `yaml:"burst" json:"burst"`
+}
+
+// defaultConfig returns the configuration used for the values that aren't
+// set.
+func defaultConfig() *config {
+	return &config{
+		Listen: listenConfig{Port: defaultPort},
+		Store: storeConfig{
+			Type:     defaultStore,
+			BoltPath: defaultBoltPath,
+		},
+		Datasets: datasetsConfig{ReloadInterval: duration(defaultReload)},
+		Routes: routesConfig{
+			Distance:     defaultGeodesic,
+			IdleSpeed:    routeguide.DefaultIdleSpeed,
+			GapThreshold: duration(routeguide.DefaultGapThreshold),
+		},
+		Notes: notesConfig{
+			TTL:                 duration(routeguide.DefaultRetentionPolicy.TTL),
+			MaxNotesPerLocation: routeguide.DefaultRetentionPolicy.MaxNotesPerLocation,
+			MaxBytes:            routeguide.DefaultRetentionPolicy.MaxBytes,
+			Log: noteLogConfig{
+				Sync:         routeguide.SyncInterval.String(),
+				SyncInterval: duration(time.Second),
+			},
+		},
+		Faults:  faultsConfig{Percent: defaultFaultPercent},
+		Logging: loggingConfig{Output: logOutputStderr},
+		TLS: tlsConfig{
+			ClientAuth:     clientAuthNone,
+			ReloadInterval: duration(routeguide.DefaultCertReloadInterval),
+		},
+		Auth: authConfig{ReloadInterval: duration(routeguide.DefaultJWKSReloadInterval)},
+		Authz: authzConfig{
+			IdentityHeader: routeguide.MetadataClientIDKey,
+			ReloadInterval: duration(routeguide.DefaultPolicyReloadInterval),
+		},
+		RateLimits: rateLimitsConfig{
+			Default: rateLimitConfig{Burst: defaultRateLimitBurst},
+		},
+	}
+}
+
+// loadConfig reads the config file at the given path, if it isn't empty, over
+// the defaults. Then it applies the environment variables, and the flags that
+// are set, in that order, and validates the result.
+func loadConfig(path string, flags *flag.FlagSet, flagPaths map[string]string) (*config, error) {
+	cfg := defaultConfig()
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		path, exists := flagPaths[f.Name]
+		if !exists || err != nil {
+			return
+		}
+
+		if setErr := cfg.set(path, f.Value.(flag.Getter).Get()); setErr != nil {
+			err = fmt.Errorf("-%s: %s", f.Name, setErr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readFile reads the YAML or JSON config file, depending on its extension.
+// Unknown and duplicate keys are rejected.
+func (c *config) readFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, c)
+	case ".json":
+		if err = checkJSONKeys(json.NewDecoder(bytes.NewReader(data)), ""); err != nil {
+			break
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	default:
+		return fmt.Errorf("unsupported config file extension %q", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	return nil
+}
+
+// checkJSONKeys returns an error if an object of the JSON value repeats a key,
+// since decoding it would silently keep the last value. The path is the path
+// of the value, e.g. notes.log.
+func checkJSONKeys(decoder *json.Decoder, path string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		keys := make(map[string]bool)
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+
+			key := path + token.(string)
+			if keys[key] {
+				return fmt.Errorf("duplicate key %s", key)
+			}
+			keys[key] = true
+
+			if err := checkJSONKeys(decoder, key+"."); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for decoder.More() {
+			if err := checkJSONKeys(decoder, path); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+
+	// the closing delimiter
+	_, err = decoder.Token()
+	return err
+}
+
+// applyEnv applies the environment variables named after the paths of the
+// config values, e.g. RG_NOTES_LOG_SYNC_INTERVAL for notes.log.syncInterval.
+// Lists are separated by commas.
+func (c *config) applyEnv(environ []string) error {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	for _, path := range configPaths(reflect.TypeOf(*c), "") {
+		value, exists := env[envName(path)]
+		if !exists {
+			continue
+		}
+
+		if err := c.set(path, value); err != nil {
+			return fmt.Errorf("%s: %s", envName(path), err)
+		}
+	}
+	return nil
+}
+
+// set sets the config value at the given path. If the value is a string, it's
+// parsed according to the type of the config value.
+func (c *config) set(path string, value interface{}) error {
+	field := reflect.ValueOf(c).Elem()
+	for _, name := range strings.Split(path, ".") {
+		field = fieldByTag(field, name)
+		if !field.IsValid() {
+			return fmt.Errorf("unknown config %s", path)
+		}
+	}
+
+	s, isString := value.(string)
+	if !isString {
+		v := reflect.ValueOf(value)
+		if !v.Type().ConvertibleTo(field.Type()) {
+			return fmt.Errorf("invalid value %v for %s", value, path)
+		}
+		field.Set(v.Convert(field.Type()))
+		return nil
+	}
+
+	var (
+		parsed interface{}
+		err    error
+	)
+	switch field.Interface().(type) {
+	case string:
+		parsed = s
+	case bool:
+		parsed, err = strconv.ParseBool(s)
+	case int:
+		parsed, err = strconv.Atoi(s)
+	case int64:
+		parsed, err = strconv.ParseInt(s, 10, 64)
+	case uint32:
+		var u uint64
+		u, err = strconv.ParseUint(s, 10, 32)
+		parsed = uint32(u)
+	case float64:
+		parsed, err = strconv.ParseFloat(s, 64)
+	case duration:
+		var d time.Duration
+		d, err = time.ParseDuration(s)
+		parsed = duration(d)
+	case []string:
+		list := []string{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		parsed = list
+	default:
+		return fmt.Errorf("unsupported type of %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q for %s", s, path)
+	}
+
+	field.Set(reflect.ValueOf(parsed))
+	return nil
+}
+
+// validate checks all the config values, and returns an error that lists all
+// the invalid ones.
+func (c *config) validate() error {
+	problems := []string{}
+	check := func(ok bool, path, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(c.Listen.Port > 0 && c.Listen.Port <= 65535, "listen.port", "must be between 1 and 65535, got %d", c.Listen.Port)
+	check(c.Listen.DebugPort >= 0 && c.Listen.DebugPort <= 65535, "listen.debugPort", "must be between 0 and 65535, got %d", c.Listen.DebugPort)
+	check(c.Listen.DebugPort == 0 || c.Listen.DebugPort != c.Listen.Port, "listen.debugPort", "must differ from listen.port")
+
+	st, err := ParseStoreType(c.Store.Type)
+	check(err == nil, "store.type", "must be one of memory, bolt, got %q", c.Store.Type)
+	check(st != storeBolt || c.Store.BoltPath != "", "store.boltPath", "is required by the bolt store")
+
+	check(c.Datasets.ReloadInterval >= 0, "datasets.reloadInterval", "must not be negative")
+
+	_, err = routeguide.ParseGeodesic(c.Routes.Distance)
+	check(err == nil, "routes.distance", "must be one of haversine, vincenty, got %q", c.Routes.Distance)
+	check(c.Routes.SnapTolerance >= 0, "routes.snapTolerance", "must not be negative")
+	check(c.Routes.IdleSpeed >= 0, "routes.idleSpeed", "must not be negative")
+	check(c.Routes.GapThreshold > 0, "routes.gapThreshold", "must be positive")
+
+	check(c.Notes.TTL >= 0, "notes.ttl", "must not be negative")
+	check(c.Notes.MaxNotesPerLocation >= 0, "notes.maxNotesPerLocation", "must not be negative")
+	check(c.Notes.MaxBytes >= 0, "notes.maxBytes", "must not be negative")
+	syncPolicy, err := routeguide.ParseSyncPolicy(c.Notes.Log.Sync)
+	check(err == nil, "notes.log.sync", "must be one of always, interval, never, got %q", c.Notes.Log.Sync)
+	check(syncPolicy != routeguide.SyncInterval || c.Notes.Log.SyncInterval > 0, "notes.log.syncInterval", "must be positive with the interval sync policy")
+
+	check(c.Faults.Percent >= 0 && c.Faults.Percent <= 100, "faults.percent", "must be between 0 and 100, got %g", c.Faults.Percent)
+
+	check(c.Limits.MaxRecvMsgBytes >= 0, "limits.maxRecvMsgBytes", "must not be negative")
+	check(c.Limits.MaxSendMsgBytes >= 0, "limits.maxSendMsgBytes", "must not be negative")
+
+	check(c.Logging.Output != "", "logging.output", "must be stderr, stdout or a file path")
+
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "tls", "certFile and keyFile must be set together")
+	switch c.TLS.ClientAuth {
+	case clientAuthNone:
+	case clientAuthRequest, clientAuthRequire:
+		check(c.TLS.CertFile != "", "tls.clientAuth", "requires certFile and keyFile")
+		check(c.TLS.ClientCAFile != "", "tls.clientAuth", "requires clientCAFile")
+	default:
+		check(false, "tls.clientAuth", "must be one of none, request, require, got %q", c.TLS.ClientAuth)
+	}
+	check(c.TLS.ClientCAFile == "" || c.TLS.CertFile != "", "tls.clientCAFile", "requires certFile and keyFile")
+	check(c.TLS.ReloadInterval >= 0, "tls.reloadInterval", "must not be negative")
+
+	if c.Auth.JWKSFile != "" {
+		check(c.Auth.Issuer != "", "auth.issuer", "is required to authenticate the requests")
+		check(c.Auth.Audience != "", "auth.audience", "is required to authenticate the requests")
+	}
+	check(c.Auth.ReloadInterval >= 0, "auth.reloadInterval", "must not be negative")
+	check(c.Authz.ReloadInterval >= 0, "authz.reloadInterval", "must not be negative")
+
+	checkRateLimit := func(path string, l rateLimitConfig) {
+		check(l.Rate >= 0, path+".rate", "must not be negative")
+		check(l.Rate <= 0 || l.Burst >= 1, path+".burst", "must be at least 1")
+	}
+	checkRateLimit("rateLimits.default", c.RateLimits.Default)
+	for method, l := range c.RateLimits.Methods {
+		check(strings.HasPrefix(method, "/") && strings.Count(method, "/") == 2, "rateLimits.methods", "%q must be a full method name, e.g. /routeguideproto.RouteGuide/GetFeature", method)
+		checkRateLimit("rateLimits.methods."+method, l)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// setupLogging points the logger at the configured output, and returns a
+// function that closes it.
+func setupLogging(c loggingConfig) (func(), error) {
+	flags := log.LstdFlags
+	if c.UTC {
+		flags |= log.LUTC
+	}
+	log.SetFlags(flags)
+
+	switch c.Output {
+	case logOutputStderr:
+		log.SetOutput(os.Stderr)
+	case logOutputStdout:
+		log.SetOutput(os.Stdout)
+	default:
+		f, err := os.OpenFile(c.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		log.SetOutput(f)
+		return func() { f.Close() }, nil
+	}
+	return func() {}, nil
+}
+
+// limitOptions returns the server options of the limits that are set.
+func limitOptions(c limitsConfig) []grpc.ServerOption {
+	opts := []grpc.ServerOption{}
+	if c.MaxRecvMsgBytes > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxRecvMsgBytes))
+	}
+	if c.MaxSendMsgBytes > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgBytes))
+	}
+	if c.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(c.MaxConcurrentStreams))
+	}
+	return opts
+}
+
+// clientAuthType returns the TLS client authentication type of the
+// configured clientAuth.
+func clientAuthType(clientAuth string) tls.ClientAuthType {
+	switch clientAuth {
+	case clientAuthRequest:
+		return tls.VerifyClientCertIfGiven
+	case clientAuthRequire:
+		return tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert
+	}
+}
+
+// configPaths returns the paths of all the config values of the struct type,
+// e.g. notes.log.dir.
+func configPaths(t reflect.Type, prefix string) []string {
+	paths := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		path := prefix + strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+		if field.Type.Kind() == reflect.Struct {
+			paths = append(paths, configPaths(field.Type, path+".")...)
+			continue
+		}
+
+		// maps can only be set in the config file
+		if field.Type.Kind() == reflect.Map {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// fieldByTag returns the field of the struct with the given YAML key.
+func fieldByTag(v reflect.Value, name string) reflect.Value {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		if strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0] == name {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// envName returns the environment variable of the config path, e.g.
+// RG_NOTES_LOG_SYNC_INTERVAL for notes.log.syncInterval.
+func envName(path string) string {
+	var (
+		name  strings.Builder
+		runes = []rune(path)
+	)
+	name.WriteString(envPrefix)
+	for i, r := range runes {
+		if r == '.' {
+			name.WriteRune('_')
+			continue
+		}
+
+		// words start at an upper case letter that follows a lower case
+		// letter, or that ends an acronym, e.g. clientCAFile
+		if unicode.IsUpper(r) && i > 0 && runes[i-1] != '.' {
+			afterLower := unicode.IsLower(runes[i-1])
+			endsAcronym := unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if afterLower || endsAcronym {
+				name.WriteRune('_')
+			}
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
+
+// duration is a time.Duration that's written as a string, e.g. 1m30s, in the
+// config file.
+type duration time.Duration
+
+func (d duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("durations must be strings, e.g. \"1m30s\"")
+	}
+	return d.parse(s)
+}
+
+func (d *duration) parse(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = duration(parsed)
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		env      map[string]string
+		args     []string
+		port     int
+		interval time.Duration
+	}{
+		{
+			name:     "defaults",
+			port:     defaultPort,
+			interval: defaultReload,
+		},
+		{
+			name:     "file",
+			file:     "listen:\n  port: 1000\ndatasets:\n  reloadInterval: 1m\n",
+			port:     1000,
+			interval: time.Minute,
+		},
+		{
+			name:     "env over file",
+			file:     "listen:\n  port: 1000\ndatasets:\n  reloadInterval: 1m\n",
+			env:      map[string]string{"RG_LISTEN_PORT": "2000"},
+			port:     2000,
+			interval: time.Minute,
+		},
+		{
+			name:     "flag over env",
+			file:     "listen:\n  port: 1000\n",
+			env:      map[string]string{"RG_LISTEN_PORT": "2000", "RG_DATASETS_RELOAD_INTERVAL": "2m"},
+			args:     []string{"-port=3000"},
+			port:     3000,
+			interval: 2 * time.Minute,
+		},
+		{
+			name:     "flag over file",
+			file:     "listen:\n  port: 1000\ndatasets:\n  reloadInterval: 1m\n",
+			args:     []string{"-port=3000", "-reload-interval=3m"},
+			port:     3000,
+			interval: 3 * time.Minute,
+		},
+		{
+			name:     "unset flag",
+			file:     "listen:\n  port: 1000\n",
+			args:     []string{"-reload-interval=3m"},
+			port:     1000,
+			interval: 3 * time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			flags := flag.NewFlagSet("server", flag.ContinueOnError)
+			flags.Int("port", defaultPort, "")
+			flags.Duration("reload-interval", defaultReload, "")
+			if err := flags.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+
+			var path string
+			if test.file != "" {
+				path = writeConfigFile(t, "config.yaml", test.file)
+			}
+
+			cfg, err := loadConfig(path, flags, map[string]string{
+				"port":            "listen.port",
+				"reload-interval": "datasets.reloadInterval",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Listen.Port != test.port {
+				t.Errorf("expected port %d, got %d", test.port, cfg.Listen.Port)
+			}
+			if time.Duration(cfg.Datasets.ReloadInterval) != test.interval {
+				t.Errorf("expected reload interval %s, got %s", test.interval, time.Duration(cfg.Datasets.ReloadInterval))
+			}
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		env      map[string]string
+		expected []string
+	}{
+		{
+			name:     "unknown YAML key",
+			file:     "config.yaml",
+			content:  "listen:\n  prot: 1000\n",
+			expected: []string{"field prot not found"},
+		},
+		{
+			name:     "unknown JSON key",
+			file:     "config.json",
+			content:  `{"listen": {"prot": 1000}}`,
+			expected: []string{`unknown field "prot"`},
+		},
+		{
+			name:     "duplicate YAML key",
+			file:     "config.yaml",
+			content:  "listen:\n  port: 1000\n  port: 2000\n",
+			expected: []string{"field port already set"},
+		},
+		{
+			name:     "duplicate JSON key",
+			file:     "config.json",
+			content:  `{"listen": {"port": 1000}, "notes": {"log": {"dir": "a", "dir": "b"}}}`,
+			expected: []string{"duplicate key notes.log.dir"},
+		},
+		{
+			name:     "duplicate JSON section",
+			file:     "config.json",
+			content:  `{"listen": {"port": 1000}, "store": {}, "listen": {"debugPort": 1001}}`,
+			expected: []string{"duplicate key listen"},
+		},
+		{
+			name:     "unsupported extension",
+			file:     "config.toml",
+			content:  "",
+			expected: []string{`unsupported config file extension ".toml"`},
+		},
+		{
+			name:     "invalid duration",
+			file:     "config.yaml",
+			content:  "datasets:\n  reloadInterval: soon\n",
+			expected: []string{"soon"},
+		},
+		{
+			name:     "invalid env value",
+			env:      map[string]string{"RG_LISTEN_PORT": "http"},
+			expected: []string{`RG_LISTEN_PORT: invalid value "http" for listen.port`},
+		},
+		{
+			name:    "all invalid values",
+			file:    "config.yaml",
+			content: "listen:\n  port: 70000\nstore:\n  type: sql\nnotes:\n  ttl: -1s\nfaults:\n  percent: 101\ntls:\n  clientAuth: require\nauth:\n  jwksFile: jwks.json\n",
+			expected: []string{
+				"invalid config:",
+				"listen.port: must be between 1 and 65535, got 70000",
+				`store.type: must be one of memory, bolt, got "sql"`,
+				"notes.ttl: must not be negative",
+				"faults.percent: must be between 0 and 100, got 101",
+				"tls.clientAuth: requires certFile and keyFile",
+				"tls.clientAuth: requires clientCAFile",
+				"auth.issuer: is required to authenticate the requests",
+				"auth.audience: is required to authenticate the requests",
+			},
+		},
+		{
+			name:     "bolt store without path",
+			file:     "config.json",
+			content:  `{"store": {"type": "bolt", "boltPath": ""}}`,
+			expected: []string{"store.boltPath: is required by the bolt store"},
+		},
+		{
+			name:     "rate limit of a method",
+			file:     "config.yaml",
+			content:  "rateLimits:\n  methods:\n    GetFeature:\n      rate: 1\n",
+			expected: []string{`rateLimits.methods: "GetFeature" must be a full method name`, "rateLimits.methods.GetFeature.burst: must be at least 1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			var path string
+			if test.file != "" {
+				path = writeConfigFile(t, test.file, test.content)
+			}
+
+			_, err := loadConfig(path, flag.NewFlagSet("server", flag.ContinueOnError), nil)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected the error to contain %q, got:\n%s", expected, err)
+				}
+			}
+		})
+	}
+}
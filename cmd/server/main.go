@@ -7,26 +7,76 @@ import (
 	"log"
 	"math/rand"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/ihcsim/routeguide"
 	pb "github.com/ihcsim/routeguide/proto"
+	yaml "gopkg.in/yaml.v2"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 const (
-	defaultPort     = 8080
-	faultPercent    = 0.3
-	pathHealthCheck = "/grpc.health.v1.Health/Check"
+	defaultPort           = 8080
+	defaultStore          = storeMemoryStr
+	defaultBoltPath       = "routeguide.db"
+	defaultReload         = 5 * time.Second
+	defaultGeodesic       = "haversine"
+	serviceName           = "routeguide.RouteGuide"
+	defaultFaultPercent   = 30
+	defaultRateLimitBurst = 10
+	pathHealthCheck       = "/grpc.health.v1.Health/Check"
 )
 
 func main() {
-	port := flag.Int("port", defaultPort, "Default port to listen on")
-	help := flag.Bool("help", false, "Print usage")
+	var (
+		defaults = defaultConfig()
+		flags    = flag.CommandLine
+	)
+
+	configFile := flags.String("config", "", "Path to a YAML or JSON config file. Its values are overridden by the RG_* environment variables, which are overridden by the flags")
+	printConfig := flags.Bool("print-config", false, "Print the effective config as YAML, and exit")
+	flags.Int("port", defaults.Listen.Port, "Default port to listen on")
+	flags.String("store", defaults.Store.Type, "The store of the features and recorded routes. Supported values: memory bolt")
+	flags.String("bolt-path", defaults.Store.BoltPath, "If the bolt store is used, this is the path to its database file")
+	flags.Duration("reload-interval", time.Duration(defaults.Datasets.ReloadInterval), "Interval at which the dataset files are polled for changes. Set to 0 to only reload on SIGHUP")
+	flags.String("distance", defaults.Routes.Distance, "The geodesic used to measure the distances of recorded routes. Supported values: haversine vincenty")
+	flags.Float64("snap-tolerance", defaults.Routes.SnapTolerance, "Distance in meters within which a recorded route point passes a named feature. Set to 0 to require exact matches")
+	flags.Float64("idle-speed", defaults.Routes.IdleSpeed, "Speed in meters per second below which a segment of a timed route is idle")
+	flags.Duration("gap-threshold", time.Duration(defaults.Routes.GapThreshold), "Time between two points of a timed route beyond which the segment between them is a gap")
+	flags.Duration("note-ttl", time.Duration(defaults.Notes.TTL), "How long RouteChat notes are kept. Set to 0 to keep them forever")
+	flags.Int("max-notes-per-location", defaults.Notes.MaxNotesPerLocation, "The number of RouteChat notes kept per location. Set to 0 for no limit")
+	flags.Int64("max-note-bytes", defaults.Notes.MaxBytes, "The total size of the RouteChat notes kept, before the least recently used locations are evicted. Set to 0 for no limit")
+	flags.String("note-log-dir", defaults.Notes.Log.Dir, "Directory of the log that RouteChat notes are persisted to, so that their history survives restarts. If omitted, the notes are only kept in memory")
+	flags.String("note-log-sync", defaults.Notes.Log.Sync, "When the note log is flushed to disk. Supported values: always interval never")
+	flags.Duration("note-log-sync-interval", time.Duration(defaults.Notes.Log.SyncInterval), "Interval at which the note log is flushed to disk, with the interval sync policy")
+	flags.Float64("fault-percent", defaults.Faults.Percent, "Percentage of the requests that fail with a random fault, between 0 and 100")
+	flags.Int("debug-port", defaults.Listen.DebugPort, "Port to serve the /debug/vars endpoint on. Set to 0 to disable it")
+	flags.String("log-output", defaults.Logging.Output, "Where the logs are written to. Supported values: stderr stdout, or the path of a file to append to")
+	flags.String("tls-cert-file", defaults.TLS.CertFile, "Path to the PEM-encoded certificate of the server. If omitted, the server serves plaintext")
+	flags.String("tls-key-file", defaults.TLS.KeyFile, "Path to the PEM-encoded private key of the server")
+	flags.String("tls-client-ca-file", defaults.TLS.ClientCAFile, "Path to the PEM-encoded CA bundle that client certificates are verified with")
+	flags.String("tls-client-auth", defaults.TLS.ClientAuth, "Whether clients must present certificates. Supported values: none request require")
+	flags.Duration("tls-reload-interval", time.Duration(defaults.TLS.ReloadInterval), "Interval at which the certificate files are polled for changes. Set to 0 to only reload on SIGHUP")
+	flags.String("auth-jwks-file", defaults.Auth.JWKSFile, "Path to the JWKS file that the bearer tokens of the requests are validated against. If omitted, the requests aren't authenticated")
+	flags.String("auth-issuer", defaults.Auth.Issuer, "The issuer that the bearer tokens must be issued by")
+	flags.String("auth-audience", defaults.Auth.Audience, "The audience that the bearer tokens must be issued for")
+	flags.Duration("auth-reload-interval", time.Duration(defaults.Auth.ReloadInterval), "Interval at which the JWKS file is polled for changes. Set to 0 to only reload on SIGHUP")
+	flags.String("authz-policy-file", defaults.Authz.PolicyFile, "Path to the YAML or JSON policy file of the methods that the callers may call. If omitted, every caller may call every method")
+	flags.String("authz-identity-header", defaults.Authz.IdentityHeader, "The metadata header that identifies the callers, in addition to their client certificates and bearer tokens")
+	flags.Duration("authz-reload-interval", time.Duration(defaults.Authz.ReloadInterval), "Interval at which the policy file is polled for changes. Set to 0 to only reload on SIGHUP")
+	flags.Float64("rate-limit", defaults.RateLimits.Default.Rate, "The number of calls per second that every caller can make to every method. Limits of individual methods can be set in the config file. Set to 0 to disable the limit")
+	flags.Int("rate-limit-burst", defaults.RateLimits.Default.Burst, "The number of calls that every caller can make to every method at once")
+	help := flags.Bool("help", false, "Print usage")
+
+	flags.Var(&stringsFlag{}, "features-file", "Path to a GeoJSON, CSV (name,lat,lng) or JSON (same shape as the embedded dataset) dataset file. Can be repeated. If omitted, the embedded dataset is used")
 	flag.Parse()
 
 	if *help {
@@ -34,40 +84,214 @@ func main() {
 		os.Exit(0)
 	}
 
+	cfg, err := loadConfig(*configFile, flags, flagPaths)
+	if err != nil {
+		log.Fatalf("[main] %s", err)
+	}
+
+	if *printConfig {
+		out, err := yaml.Marshal(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(out))
+		os.Exit(0)
+	}
+
+	closeLog, err := setupLogging(cfg.Logging)
+	if err != nil {
+		log.Fatalf("[main] fail to set up logging: %s", err)
+	}
+	defer closeLog()
+
+	if *configFile != "" {
+		log.Printf("[main] config file: %s", *configFile)
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, os.Kill)
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	port := cfg.Listen.Port
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
 		log.Fatalf("[main] fail to listen for tcp traffic at port %d", port)
 	}
-	log.Printf("[main] fault percentage: %.1f%%\n", faultPercent*100)
+
+	done := make(chan struct{})
+	var (
+		unaryInterceptors  []grpc.UnaryServerInterceptor
+		streamInterceptors []grpc.StreamServerInterceptor
+	)
+
+	if jwksFile := cfg.Auth.JWKSFile; jwksFile != "" {
+		tokens, err := routeguide.NewTokenValidator(jwksFile, cfg.Auth.Issuer, cfg.Auth.Audience, time.Duration(cfg.Auth.ReloadInterval))
+		if err != nil {
+			log.Fatalf("[main] fail to load JWKS: %s", err)
+		}
+
+		rotate := make(chan os.Signal, 1)
+		signal.Notify(rotate, syscall.SIGHUP)
+		go tokens.Run(rotate, done)
+
+		unaryInterceptors = append(unaryInterceptors, tokens.UnaryInterceptor(pathHealthCheck))
+		streamInterceptors = append(streamInterceptors, tokens.StreamInterceptor(pathHealthCheck))
+		log.Printf("[main] authentication: %s (issuer: %s, audience: %s)", jwksFile, cfg.Auth.Issuer, cfg.Auth.Audience)
+	}
+
+	if policyFile := cfg.Authz.PolicyFile; policyFile != "" {
+		policy, err := routeguide.NewPolicyEnforcer(policyFile, cfg.Authz.IdentityHeader, time.Duration(cfg.Authz.ReloadInterval))
+		if err != nil {
+			log.Fatalf("[main] fail to load policy: %s", err)
+		}
+
+		rotate := make(chan os.Signal, 1)
+		signal.Notify(rotate, syscall.SIGHUP)
+		go policy.Run(rotate, done)
+
+		unaryInterceptors = append(unaryInterceptors, policy.UnaryInterceptor(pathHealthCheck))
+		streamInterceptors = append(streamInterceptors, policy.StreamInterceptor(pathHealthCheck))
+		log.Printf("[main] authorization policy: %s (identity header: %s)", policyFile, cfg.Authz.IdentityHeader)
+	}
+
+	if limits := cfg.RateLimits; limits.Default.Rate > 0 || len(limits.Methods) > 0 {
+		methods := make(map[string]routeguide.RateLimit)
+		for method, limit := range limits.Methods {
+			methods[method] = routeguide.RateLimit(limit)
+		}
+
+		limiter, err := routeguide.NewRateLimiter(routeguide.RateLimit(limits.Default), methods)
+		if err != nil {
+			log.Fatalf("[main] fail to create rate limiter: %s", err)
+		}
+
+		unaryInterceptors = append(unaryInterceptors, limiter.UnaryInterceptor(pathHealthCheck))
+		streamInterceptors = append(streamInterceptors, limiter.StreamInterceptor(pathHealthCheck))
+		log.Printf("[main] rate limits: %+v (methods: %+v)", limits.Default, limits.Methods)
+	}
+
+	faults := faultInjector{percent: cfg.Faults.Percent}
+	log.Printf("[main] fault percentage: %.1f%%\n", faults.percent)
+	unaryInterceptors = append(unaryInterceptors, faults.unary)
+	streamInterceptors = append(streamInterceptors, faults.stream)
 
 	opts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(triggerFaultUnaryInterceptor),
-		grpc.StreamInterceptor(triggerFaultStreamInterceptor),
+		grpc.UnaryInterceptor(chainUnaryInterceptors(unaryInterceptors...)),
+		grpc.StreamInterceptor(chainStreamInterceptors(streamInterceptors...)),
+	}
+	opts = append(opts, limitOptions(cfg.Limits)...)
+
+	if cfg.TLS.CertFile != "" {
+		certs, err := routeguide.NewCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile, time.Duration(cfg.TLS.ReloadInterval))
+		if err != nil {
+			log.Fatalf("[main] fail to load TLS certificates: %s", err)
+		}
+
+		rotate := make(chan os.Signal, 1)
+		signal.Notify(rotate, syscall.SIGHUP)
+		go certs.Run(rotate, done)
+
+		creds := credentials.NewTLS(certs.ServerConfig(clientAuthType(cfg.TLS.ClientAuth)))
+		opts = append(opts, grpc.Creds(creds))
+		log.Printf("[main] TLS: %s (client auth: %s)", cfg.TLS.CertFile, cfg.TLS.ClientAuth)
 	}
 
 	hostname, err := os.Hostname()
 	if err != nil {
 		log.Fatal(err)
 	}
-	hostname = fmt.Sprintf("%s:%d", hostname, *port)
+	hostname = fmt.Sprintf("%s:%d", hostname, port)
 	log.Printf("[main] hostname: %s", hostname)
 
+	st, err := ParseStoreType(cfg.Store.Type)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("[main] store: %s", st)
+
+	featuresFiles := cfg.Datasets.Files
+	features, err := loadFeatures(featuresFiles)
+	if err != nil {
+		log.Fatalf("[main] fail to load features: %s", err)
+	}
+
+	featureStore, routeStore, closeStore, err := newStores(st, cfg.Store.BoltPath, features, len(featuresFiles) > 0)
+	if err != nil {
+		log.Fatalf("[main] fail to open %s stores: %s", st, err)
+	}
+	defer closeStore()
+
+	geodesic, err := routeguide.ParseGeodesic(cfg.Routes.Distance)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("[main] geodesic: %s", geodesic)
+
+	retention := routeguide.RetentionPolicy{
+		TTL:                 time.Duration(cfg.Notes.TTL),
+		MaxNotesPerLocation: cfg.Notes.MaxNotesPerLocation,
+		MaxBytes:            cfg.Notes.MaxBytes,
+	}
+	log.Printf("[main] note retention: %+v", retention)
+
+	var noteLog *routeguide.NoteLog
+	if dir := cfg.Notes.Log.Dir; dir != "" {
+		syncPolicy, err := routeguide.ParseSyncPolicy(cfg.Notes.Log.Sync)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		noteLog, err = routeguide.OpenNoteLog(dir, syncPolicy, time.Duration(cfg.Notes.Log.SyncInterval))
+		if err != nil {
+			log.Fatalf("[main] fail to open note log at %s: %s", dir, err)
+		}
+		defer noteLog.Close()
+		log.Printf("[main] note log: %s (sync: %s)", dir, syncPolicy)
+	}
+
+	if debugPort := cfg.Listen.DebugPort; debugPort > 0 {
+		go func() {
+			log.Printf("[main] serving /debug/vars at port %d", debugPort)
+			if err := http.ListenAndServe(fmt.Sprintf(":%d", debugPort), nil); err != nil {
+				log.Printf("[main] debug server: %s", err)
+			}
+		}()
+	}
+
 	grpcServer := grpc.NewServer(opts...)
-	routeGuideServer, err := routeguide.NewServer(hostname)
+	routeGuideServer, err := routeguide.NewServer(hostname, featureStore,
+		routeguide.WithGeodesic(geodesic),
+		routeguide.WithSnapTolerance(cfg.Routes.SnapTolerance),
+		routeguide.WithIdleSpeed(cfg.Routes.IdleSpeed),
+		routeguide.WithGapThreshold(time.Duration(cfg.Routes.GapThreshold)),
+		routeguide.WithRouteStore(routeStore),
+		routeguide.WithRetention(retention),
+		routeguide.WithNoteLog(noteLog))
 	if err != nil {
-		log.Fatalf("[main] fail to listen for tcp traffic at %s", hostname)
+		log.Fatalf("[main] fail to create server at %s: %s", hostname, err)
 	}
 	pb.RegisterRouteGuideServer(grpcServer, routeGuideServer)
 
 	healthServer := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
-	healthServer.SetServingStatus("routeguide.RouteGuide", grpc_health_v1.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(serviceName, grpc_health_v1.HealthCheckResponse_SERVING)
+
+	if len(featuresFiles) > 0 {
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+
+		reloader := routeguide.NewFeatureReloader(featureStore, featuresFiles, time.Duration(cfg.Datasets.ReloadInterval), func(err error) {
+			if err != nil {
+				healthServer.SetServingStatus(serviceName, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+				return
+			}
+			healthServer.SetServingStatus(serviceName, grpc_health_v1.HealthCheckResponse_SERVING)
+		})
+		go reloader.Run(reload, done)
+	}
 
 	go func() {
 		<-stop
+		close(done)
 		log.Println("[main] stopping")
 		healthServer.Shutdown()
 		grpcServer.GracefulStop()
@@ -80,9 +304,84 @@ func main() {
 	log.Println("[main] done")
 }
 
-func triggerFaultUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-	percent := int(faultPercent * 100)
-	if n := rand.Intn(100); info.FullMethod != pathHealthCheck && n > 0 && n <= percent {
+// flagPaths maps the flags to the config values they override.
+var flagPaths = map[string]string{
+	"port":                   "listen.port",
+	"debug-port":             "listen.debugPort",
+	"store":                  "store.type",
+	"bolt-path":              "store.boltPath",
+	"features-file":          "datasets.files",
+	"reload-interval":        "datasets.reloadInterval",
+	"distance":               "routes.distance",
+	"snap-tolerance":         "routes.snapTolerance",
+	"idle-speed":             "routes.idleSpeed",
+	"gap-threshold":          "routes.gapThreshold",
+	"note-ttl":               "notes.ttl",
+	"max-notes-per-location": "notes.maxNotesPerLocation",
+	"max-note-bytes":         "notes.maxBytes",
+	"note-log-dir":           "notes.log.dir",
+	"note-log-sync":          "notes.log.sync",
+	"note-log-sync-interval": "notes.log.syncInterval",
+	"fault-percent":          "faults.percent",
+	"log-output":             "logging.output",
+	"tls-cert-file":          "tls.certFile",
+	"tls-key-file":           "tls.keyFile",
+	"tls-client-ca-file":     "tls.clientCAFile",
+	"tls-client-auth":        "tls.clientAuth",
+	"tls-reload-interval":    "tls.reloadInterval",
+	"auth-jwks-file":         "auth.jwksFile",
+	"auth-issuer":            "auth.issuer",
+	"auth-audience":          "auth.audience",
+	"auth-reload-interval":   "auth.reloadInterval",
+	"authz-policy-file":      "authz.policyFile",
+	"authz-identity-header":  "authz.identityHeader",
+	"authz-reload-interval":  "authz.reloadInterval",
+	"rate-limit":             "rateLimits.default.rate",
+	"rate-limit-burst":       "rateLimits.default.burst",
+}
+
+// chainUnaryInterceptors returns an interceptor that runs the interceptors in
+// order, each wrapping the ones after it.
+func chainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return chained(ctx, req)
+	}
+}
+
+// chainStreamInterceptors returns an interceptor that runs the interceptors
+// in order, each wrapping the ones after it.
+func chainStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, next)
+			}
+		}
+		return chained(srv, ss)
+	}
+}
+
+// faultInjector fails the given percentage of the requests with a random
+// fault. The health checks are exempt.
+type faultInjector struct {
+	percent float64
+}
+
+func (f faultInjector) fail(method string) bool {
+	return method != pathHealthCheck && rand.Float64()*100 < f.percent
+}
+
+func (f faultInjector) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	if f.fail(info.FullMethod) {
 		err := routeguide.GetFault(info.FullMethod)
 		log.Printf("[interceptor] (fault) %+v\n", err)
 		return nil, err
@@ -91,9 +390,8 @@ func triggerFaultUnaryInterceptor(ctx context.Context, req interface{}, info *gr
 	return handler(ctx, req)
 }
 
-func triggerFaultStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-	percent := int(faultPercent * 100)
-	if n := rand.Intn(100); info.FullMethod != pathHealthCheck && n > 0 && n <= percent {
+func (f faultInjector) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if f.fail(info.FullMethod) {
 		err := routeguide.GetFault(info.FullMethod)
 		log.Printf("[interceptor] (fault) %+v\n", err)
 		return err
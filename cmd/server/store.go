@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ihcsim/routeguide"
+	pb "github.com/ihcsim/routeguide/proto"
+	bolt "go.etcd.io/bbolt"
+)
+
+type storeType int
+
+const (
+	storeMemory storeType = iota
+	storeBolt
+
+	storeMemoryStr  = "memory"
+	storeBoltStr    = "bolt"
+	storeUnknownStr = "unknown"
+)
+
+func ParseStoreType(st string) (storeType, error) {
+	switch strings.ToLower(st) {
+	case storeMemoryStr:
+		return storeMemory, nil
+	case storeBoltStr:
+		return storeBolt, nil
+	}
+
+	return -1, fmt.Errorf("Unsupported store type: %s", st)
+}
+
+func (s storeType) String() string {
+	switch s {
+	case storeMemory:
+		return storeMemoryStr
+	case storeBolt:
+		return storeBoltStr
+	default:
+		return storeUnknownStr
+	}
+}
+
+// newStores returns the feature and route stores of the given type. The
+// feature store holds the given features. The features of an existing bolt
+// database are only replaced with them if overwrite is true, otherwise it is
+// seeded with them when it's empty. The returned close function releases any
+// resources held by the stores.
+func newStores(st storeType, boltPath string, features []*pb.Feature, overwrite bool) (routeguide.FeatureStore, routeguide.RouteStore, func() error, error) {
+	noop := func() error { return nil }
+
+	switch st {
+	case storeMemory:
+		return routeguide.NewMemoryStore(features), routeguide.NewMemoryRouteStore(), noop, nil
+
+	case storeBolt:
+		db, err := bolt.Open(boltPath, 0600, &bolt.Options{Timeout: 5 * time.Second})
+		if err != nil {
+			return nil, nil, noop, err
+		}
+
+		featureStore, routeStore, err := newBoltStores(db, boltPath, features, overwrite)
+		if err != nil {
+			db.Close()
+			return nil, nil, noop, err
+		}
+		return featureStore, routeStore, db.Close, nil
+	}
+
+	return nil, nil, noop, fmt.Errorf("Unsupported store type: %s", st)
+}
+
+// newBoltStores returns the feature and route stores that share the bolt
+// database.
+func newBoltStores(db *bolt.DB, boltPath string, features []*pb.Feature, overwrite bool) (routeguide.FeatureStore, routeguide.RouteStore, error) {
+	featureStore, err := routeguide.NewBoltStore(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	count, err := featureStore.Count()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case overwrite:
+		// the features of the dataset files replace the saved ones, rather
+		// than being merged with them
+		if err := featureStore.Replace(features); err != nil {
+			return nil, nil, err
+		}
+		log.Printf("[main] replaced the features of %s with %d features", boltPath, len(features))
+	case count == 0:
+		if err := featureStore.Put(features...); err != nil {
+			return nil, nil, err
+		}
+		log.Printf("[main] saved %d features to %s", len(features), boltPath)
+	}
+
+	routeStore, err := routeguide.NewBoltRouteStore(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return featureStore, routeStore, nil
+}
+
+// loadFeatures reads the features in the given dataset files, skipping the
+// rows that were rejected. If no files are given, the embedded dataset is
+// returned.
+func loadFeatures(paths []string) ([]*pb.Feature, error) {
+	if len(paths) == 0 {
+		log.Println("[main] loading embedded dataset")
+		return routeguide.DefaultFeatures()
+	}
+
+	return routeguide.LoadDatasets(paths, false)
+}
+
+// stringsFlag is a flag that can be repeated to collect multiple values.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func (s *stringsFlag) Get() interface{} {
+	return []string(*s)
+}
@@ -0,0 +1,185 @@
+package routeguide
+
+import (
+	"fmt"
+	"math"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371008.8
+
+// e7ToRadians converts a coordinate in the E7 representation into radians.
+func e7ToRadians(e7 int32) float64 {
+	return float64(e7) / 1e7 * math.Pi / 180
+}
+
+// haversine returns the great-circle distance in meters between the two
+// points.
+func haversine(a, b *pb.Point) float64 {
+	var (
+		lat1 = e7ToRadians(a.GetLatitude())
+		lat2 = e7ToRadians(b.GetLatitude())
+		dLat = lat2 - lat1
+		dLng = e7ToRadians(b.GetLongitude()) - e7ToRadians(a.GetLongitude())
+		h    = hav(dLat) + math.Cos(lat1)*math.Cos(lat2)*hav(dLng)
+	)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// hav is the haversine function.
+func hav(theta float64) float64 {
+	s := math.Sin(theta / 2)
+	return s * s
+}
+
+// circleBounds returns an oriented rectangle that encloses all the points
+// within the given distance in meters of the center.
+func circleBounds(center *pb.Point, radius float64) *pb.Rectangle {
+	var (
+		lat    = e7ToRadians(center.GetLatitude())
+		lng    = e7ToRadians(center.GetLongitude())
+		dLat   = radius / earthRadius
+		bottom = lat - dLat
+		top    = lat + dLat
+		west   = -math.Pi
+		east   = math.Pi
+	)
+
+	// a circle that covers a pole spans all longitudes, otherwise the
+	// longitudes are bounded by the meridians tangent to the circle
+	if bottom > -math.Pi/2 && top < math.Pi/2 {
+		if ratio := math.Sin(dLat) / math.Cos(lat); ratio < 1 {
+			dLng := math.Asin(ratio)
+			west = normalizeLongitude(lng - dLng)
+			east = normalizeLongitude(lng + dLng)
+		}
+	}
+
+	return &pb.Rectangle{
+		Lo:       &pb.Point{Latitude: radiansToE7(math.Max(bottom, -math.Pi/2)), Longitude: radiansToE7(west)},
+		Hi:       &pb.Point{Latitude: radiansToE7(math.Min(top, math.Pi/2)), Longitude: radiansToE7(east)},
+		Oriented: true,
+	}
+}
+
+// polygon is a polygon whose vertex longitudes are unwrapped, so that no edge
+// is longer than 180 degrees of longitude. The unwrapped longitudes may fall
+// outside the -180 to 180 degrees range.
+type polygon struct {
+	lats, lngs []float64
+	west, east float64
+}
+
+// newPolygon returns the polygon enclosed by the given vertices, in the E7
+// representation.
+func newPolygon(vertices []*pb.Point) (*polygon, error) {
+	if len(vertices) < 3 {
+		return nil, fmt.Errorf("a polygon requires at least 3 vertices, got %d", len(vertices))
+	}
+
+	p := &polygon{
+		lats: make([]float64, len(vertices)),
+		lngs: make([]float64, len(vertices)),
+	}
+	for i, vertex := range vertices {
+		if err := checkPoint(vertex); err != nil {
+			return nil, fmt.Errorf("vertex %d: %s", i, err)
+		}
+
+		p.lats[i] = float64(vertex.Latitude)
+		p.lngs[i] = float64(vertex.Longitude)
+		if i > 0 {
+			// take the shorter way around from the previous vertex
+			for p.lngs[i]-p.lngs[i-1] > 180e7 {
+				p.lngs[i] -= 360e7
+			}
+			for p.lngs[i]-p.lngs[i-1] < -180e7 {
+				p.lngs[i] += 360e7
+			}
+		}
+	}
+
+	p.west, p.east = p.lngs[0], p.lngs[0]
+	for _, lng := range p.lngs {
+		p.west = math.Min(p.west, lng)
+		p.east = math.Max(p.east, lng)
+	}
+
+	// the closing edge must not wrap around the earth, which happens when
+	// the polygon encloses a pole
+	if closing := p.lngs[len(p.lngs)-1] - p.lngs[0]; closing > 180e7 || closing < -180e7 {
+		return nil, fmt.Errorf("polygons that enclose a pole are not supported")
+	}
+
+	return p, nil
+}
+
+// bounds returns an oriented rectangle that encloses the polygon.
+func (p *polygon) bounds() *pb.Rectangle {
+	bottom, top := p.lats[0], p.lats[0]
+	for _, lat := range p.lats {
+		bottom = math.Min(bottom, lat)
+		top = math.Max(top, lat)
+	}
+
+	west, east := int32(minE7), int32(maxE7)
+	if p.east-p.west < 360e7 {
+		west = radiansToE7(normalizeLongitude(p.west * math.Pi / 180e7))
+		east = radiansToE7(normalizeLongitude(p.east * math.Pi / 180e7))
+	}
+
+	return &pb.Rectangle{
+		Lo:       &pb.Point{Latitude: int32(bottom), Longitude: west},
+		Hi:       &pb.Point{Latitude: int32(top), Longitude: east},
+		Oriented: true,
+	}
+}
+
+// contains returns true if the point is inside the polygon, using the
+// even-odd rule.
+func (p *polygon) contains(point *pb.Point) bool {
+	var (
+		lat = float64(point.GetLatitude())
+		lng = float64(point.GetLongitude())
+	)
+
+	// move the point into the polygon's unwrapped longitude range
+	for lng < p.west {
+		lng += 360e7
+	}
+	for lng > p.east {
+		lng -= 360e7
+	}
+	if lng < p.west {
+		return false
+	}
+
+	inside := false
+	for i, j := 0, len(p.lats)-1; i < len(p.lats); j, i = i, i+1 {
+		if (p.lats[i] > lat) != (p.lats[j] > lat) {
+			crossing := p.lngs[i] + (lat-p.lats[i])/(p.lats[j]-p.lats[i])*(p.lngs[j]-p.lngs[i])
+			if lng < crossing {
+				inside = !inside
+			}
+		}
+	}
+
+	return inside
+}
+
+// radiansToE7 converts an angle in radians into the E7 representation.
+func radiansToE7(rad float64) int32 {
+	return int32(math.Round(rad * 180 / math.Pi * 1e7))
+}
+
+// normalizeLongitude wraps the longitude in radians into the -pi to pi range.
+func normalizeLongitude(lng float64) float64 {
+	lng = math.Mod(lng+math.Pi, 2*math.Pi)
+	if lng < 0 {
+		lng += 2 * math.Pi
+	}
+	return lng - math.Pi
+}
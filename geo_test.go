@@ -0,0 +1,338 @@
+package routeguide
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// testRectangle returns the rectangle between the given corners in degrees.
+func testRectangle(t *testing.T, loLat, loLng, hiLat, hiLng float64, oriented bool) *pb.Rectangle {
+	t.Helper()
+
+	return &pb.Rectangle{
+		Lo:       testPoint(t, loLat, loLng),
+		Hi:       testPoint(t, hiLat, hiLng),
+		Oriented: oriented,
+	}
+}
+
+// destination returns the point at the given distance in meters and bearing
+// in degrees from the start, on the sphere.
+func destination(start *pb.Point, distance, bearing float64) *pb.Point {
+	var (
+		lat1  = e7ToRadians(start.GetLatitude())
+		lng1  = e7ToRadians(start.GetLongitude())
+		theta = bearing * math.Pi / 180
+		d     = distance / earthRadius
+		lat2  = math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(theta))
+		lng2  = lng1 + math.Atan2(math.Sin(theta)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	)
+
+	return &pb.Point{Latitude: radiansToE7(lat2), Longitude: radiansToE7(normalizeLongitude(lng2))}
+}
+
+func TestInRangeOriented(t *testing.T) {
+	tests := []struct {
+		name      string
+		rectangle *pb.Rectangle
+		inside    [][2]float64
+		outside   [][2]float64
+	}{
+		{
+			name:      "unoriented",
+			rectangle: testRectangle(t, 10, 20, -10, -20, false),
+			inside:    [][2]float64{{0, 0}, {10, 20}, {-10, -20}, {5, -15}},
+			outside:   [][2]float64{{11, 0}, {0, 21}, {0, 179}},
+		},
+		{
+			name:      "oriented",
+			rectangle: testRectangle(t, -10, -20, 10, 20, true),
+			inside:    [][2]float64{{0, 0}, {10, 20}, {-10, -20}},
+			outside:   [][2]float64{{-11, 0}, {0, -21}, {0, 180}},
+		},
+		{
+			name:      "oriented across the antimeridian",
+			rectangle: testRectangle(t, -10, 170, 10, -170, true),
+			inside:    [][2]float64{{0, 170}, {0, 179.9}, {0, 180}, {0, -180}, {0, -175}, {10, -170}},
+			outside:   [][2]float64{{0, 0}, {0, 169}, {0, -169}, {11, 175}},
+		},
+		{
+			name:      "unoriented with the same corners",
+			rectangle: testRectangle(t, -10, 170, 10, -170, false),
+			inside:    [][2]float64{{0, 0}, {0, 169}, {0, -169}},
+			outside:   [][2]float64{{0, 175}, {0, -175}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, p := range test.inside {
+				if !inRange(testPoint(t, p[0], p[1]), test.rectangle) {
+					t.Errorf("expected %v to be in range", p)
+				}
+			}
+			for _, p := range test.outside {
+				if inRange(testPoint(t, p[0], p[1]), test.rectangle) {
+					t.Errorf("expected %v to be out of range", p)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPolygonErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		vertices [][2]float64
+		expected string
+	}{
+		{
+			name:     "too few vertices",
+			vertices: [][2]float64{{0, 0}, {1, 1}},
+			expected: "at least 3 vertices",
+		},
+		{
+			name:     "enclosed north pole",
+			vertices: [][2]float64{{80, 0}, {80, 120}, {80, -120}},
+			expected: "enclose a pole",
+		},
+		{
+			name:     "enclosed south pole",
+			vertices: [][2]float64{{-80, 0}, {-80, -90}, {-80, 180}, {-80, 90}},
+			expected: "enclose a pole",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vertices := make([]*pb.Point, len(test.vertices))
+			for i, v := range test.vertices {
+				vertices[i] = testPoint(t, v[0], v[1])
+			}
+
+			_, err := newPolygon(vertices)
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected an error containing %q, got %v", test.expected, err)
+			}
+		})
+	}
+
+	_, err := newPolygon([]*pb.Point{{Latitude: 0}, {Latitude: 910000000}, {Longitude: 10}})
+	if err == nil || !strings.HasPrefix(err.Error(), "vertex 1:") {
+		t.Errorf("expected an error of the invalid vertex, got %v", err)
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	tests := []struct {
+		name     string
+		vertices [][2]float64
+		bounds   [4]float64
+		inside   [][2]float64
+		outside  [][2]float64
+	}{
+		{
+			name:     "square",
+			vertices: [][2]float64{{0, 0}, {0, 10}, {10, 10}, {10, 0}},
+			bounds:   [4]float64{0, 0, 10, 10},
+			inside:   [][2]float64{{5, 5}, {0.1, 0.1}, {9.9, 9.9}},
+			outside:  [][2]float64{{-1, 5}, {5, 11}, {5, -180}, {5, 180}},
+		},
+		{
+			name:     "concave",
+			vertices: [][2]float64{{0, 0}, {0, 10}, {10, 10}, {10, 8}, {2, 8}, {2, 0}},
+			bounds:   [4]float64{0, 0, 10, 10},
+			inside:   [][2]float64{{1, 1}, {1, 9}, {9, 9}},
+			outside:  [][2]float64{{5, 5}, {9, 1}, {11, 9}},
+		},
+		{
+			name:     "across the antimeridian",
+			vertices: [][2]float64{{-10, 170}, {10, 170}, {10, -170}, {-10, -170}},
+			bounds:   [4]float64{-10, 170, 10, -170},
+			inside:   [][2]float64{{0, 175}, {0, 180}, {0, -180}, {0, -175}, {5, 179.99}},
+			outside:  [][2]float64{{0, 0}, {0, 165}, {0, -165}, {11, 180}},
+		},
+		{
+			name:     "across the antimeridian counterclockwise",
+			vertices: [][2]float64{{-10, -170}, {10, -170}, {10, 170}, {-10, 170}},
+			bounds:   [4]float64{-10, 170, 10, -170},
+			inside:   [][2]float64{{0, 175}, {0, -175}},
+			outside:  [][2]float64{{0, 0}, {0, 165}},
+		},
+		{
+			name:     "triangle west of the antimeridian",
+			vertices: [][2]float64{{0, -179}, {0, 179}, {5, 180}},
+			bounds:   [4]float64{0, 179, 5, -179},
+			inside:   [][2]float64{{1, 180}, {1, 179.8}, {1, -179.8}},
+			outside:  [][2]float64{{-1, 180}, {1, 0}, {4, 179}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vertices := make([]*pb.Point, len(test.vertices))
+			for i, v := range test.vertices {
+				vertices[i] = testPoint(t, v[0], v[1])
+			}
+
+			p, err := newPolygon(vertices)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := testRectangle(t, test.bounds[0], test.bounds[1], test.bounds[2], test.bounds[3], true)
+			if bounds := p.bounds(); !reflect.DeepEqual(bounds, expected) {
+				t.Errorf("expected the bounds %v, got %v", expected, bounds)
+			}
+
+			for _, point := range test.inside {
+				if !p.contains(testPoint(t, point[0], point[1])) {
+					t.Errorf("expected %v to be inside", point)
+				}
+				if !inRange(testPoint(t, point[0], point[1]), p.bounds()) {
+					t.Errorf("expected %v to be within the bounds", point)
+				}
+			}
+			for _, point := range test.outside {
+				if p.contains(testPoint(t, point[0], point[1])) {
+					t.Errorf("expected %v to be outside", point)
+				}
+			}
+		})
+	}
+}
+
+func TestCircleBounds(t *testing.T) {
+	const radius = 100000
+
+	tests := []struct {
+		name     string
+		center   *pb.Point
+		allLngs  bool
+		crossing bool
+		topPole  bool
+	}{
+		{
+			name:   "equator",
+			center: testPoint(t, 0, 0),
+		},
+		{
+			name:   "high latitude",
+			center: testPoint(t, 70, -30),
+		},
+		{
+			name:     "antimeridian",
+			center:   testPoint(t, -20, 179.5),
+			crossing: true,
+		},
+		{
+			name:     "antimeridian from the west",
+			center:   testPoint(t, 45, -179.9),
+			crossing: true,
+		},
+		{
+			name:    "north pole",
+			center:  testPoint(t, 89.5, 10),
+			allLngs: true,
+			topPole: true,
+		},
+		{
+			name:    "near the south pole",
+			center:  testPoint(t, -88.5, 100),
+			allLngs: false,
+		},
+		{
+			name:    "south pole",
+			center:  testPoint(t, -90, 0),
+			allLngs: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bounds := circleBounds(test.center, radius)
+			if !bounds.Oriented {
+				t.Fatal("expected an oriented rectangle")
+			}
+
+			west, east := bounds.Lo.Longitude, bounds.Hi.Longitude
+			if allLngs := west == minE7 && east == maxE7; allLngs != test.allLngs {
+				t.Errorf("expected all longitudes %t, got %d to %d", test.allLngs, west, east)
+			}
+			if crossing := west > east; crossing != test.crossing {
+				t.Errorf("expected the antimeridian crossing %t, got %d to %d", test.crossing, west, east)
+			}
+			if topPole := bounds.Hi.Latitude == 900000000; topPole != test.topPole {
+				t.Errorf("expected the north pole in the bounds %t, got the top %d", test.topPole, bounds.Hi.Latitude)
+			}
+
+			// every point on and within the circle is within the bounds
+			for _, distance := range []float64{0, radius / 2, radius * 0.999} {
+				for bearing := 0.0; bearing < 360; bearing += 5 {
+					point := destination(test.center, distance, bearing)
+					if !inRange(point, bounds) {
+						t.Fatalf("expected %v at %.0fm and %.0f degrees to be within %v", point, distance, bearing, bounds)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGridIndexQueryAcrossTheAntimeridian(t *testing.T) {
+	var (
+		index    = newGridIndex(defaultCellSize)
+		features = map[string]*pb.Point{
+			"west of the antimeridian": testPoint(t, 0, 179.95),
+			"on the antimeridian":      testPoint(t, 0, 180),
+			"east of the antimeridian": testPoint(t, 0, -179.95),
+			"greenwich":                testPoint(t, 0, 0),
+			"too far north":            testPoint(t, 2, 179.95),
+			"too far west":             testPoint(t, 0, 178),
+		}
+	)
+	for name, location := range features {
+		index.insert(&pb.Feature{Name: name, Location: location})
+	}
+
+	tests := []struct {
+		name      string
+		rectangle *pb.Rectangle
+		expected  []string
+	}{
+		{
+			name:      "oriented",
+			rectangle: testRectangle(t, -1, 179, 1, -179, true),
+			expected:  []string{"east of the antimeridian", "on the antimeridian", "west of the antimeridian"},
+		},
+		{
+			name:      "unoriented",
+			rectangle: testRectangle(t, -1, 179, 1, -179, false),
+			expected:  []string{"greenwich", "too far west"},
+		},
+		{
+			name:      "circle",
+			rectangle: circleBounds(testPoint(t, 0, 180), 20000),
+			expected:  []string{"east of the antimeridian", "on the antimeridian", "west of the antimeridian"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := []string{}
+			for _, feature := range index.query(test.rectangle) {
+				actual = append(actual, feature.Name)
+			}
+			sort.Strings(actual)
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package routeguide
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// Geodesic is a method of measuring the distances and bearings between points
+// on the earth.
+type Geodesic int
+
+const (
+	// Haversine treats the earth as a sphere with the mean earth radius. It's
+	// accurate to about 0.5%.
+	Haversine Geodesic = iota
+
+	// Vincenty treats the earth as the WGS-84 ellipsoid. It's accurate to
+	// within millimeters, but is slower than Haversine.
+	Vincenty
+
+	haversineStr = "haversine"
+	vincentyStr  = "vincenty"
+	unknownStr   = "unknown"
+)
+
+// WGS-84 ellipsoid parameters.
+const (
+	wgs84SemiMajor  = 6378137.0
+	wgs84Flattening = 1 / 298.257223563
+	wgs84SemiMinor  = wgs84SemiMajor * (1 - wgs84Flattening)
+
+	vincentyMaxIterations = 200
+)
+
+// ParseGeodesic returns the geodesic with the given name.
+func ParseGeodesic(g string) (Geodesic, error) {
+	switch strings.ToLower(g) {
+	case haversineStr:
+		return Haversine, nil
+	case vincentyStr:
+		return Vincenty, nil
+	}
+
+	return -1, fmt.Errorf("Unsupported geodesic: %s", g)
+}
+
+func (g Geodesic) String() string {
+	switch g {
+	case Haversine:
+		return haversineStr
+	case Vincenty:
+		return vincentyStr
+	default:
+		return unknownStr
+	}
+}
+
+// Distance returns the distance in meters between the two points.
+func (g Geodesic) Distance(a, b *pb.Point) float64 {
+	distance, _, _ := g.Inverse(a, b)
+	return distance
+}
+
+// Inverse returns the distance in meters between the two points, and the
+// initial and final bearings of the shortest path from a to b. The bearings
+// are in degrees clockwise from true north, in the range [0, 360).
+func (g Geodesic) Inverse(a, b *pb.Point) (distance, initialBearing, finalBearing float64) {
+	if g == Vincenty {
+		if distance, initial, final, ok := vincentyInverse(a, b); ok {
+			return distance, initial, final
+		}
+		// the iteration doesn't converge for nearly antipodal points, so
+		// fall back to the spherical solution
+	}
+
+	return haversine(a, b), sphericalBearing(a, b), math.Mod(sphericalBearing(b, a)+180, 360)
+}
+
+// sphericalBearing returns the initial bearing in degrees of the great circle
+// path from a to b.
+func sphericalBearing(a, b *pb.Point) float64 {
+	var (
+		lat1 = e7ToRadians(a.GetLatitude())
+		lat2 = e7ToRadians(b.GetLatitude())
+		dLng = e7ToRadians(b.GetLongitude()) - e7ToRadians(a.GetLongitude())
+		y    = math.Sin(dLng) * math.Cos(lat2)
+		x    = math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	)
+
+	return toBearing(math.Atan2(y, x))
+}
+
+// vincentyInverse solves the inverse geodesic problem on the WGS-84 ellipsoid,
+// using Vincenty's iterative formulae. It returns false if the iteration
+// doesn't converge.
+func vincentyInverse(a, b *pb.Point) (distance, initialBearing, finalBearing float64, ok bool) {
+	const f = wgs84Flattening
+
+	var (
+		l            = normalizeLongitude(e7ToRadians(b.GetLongitude()) - e7ToRadians(a.GetLongitude()))
+		u1           = math.Atan((1 - f) * math.Tan(e7ToRadians(a.GetLatitude())))
+		u2           = math.Atan((1 - f) * math.Tan(e7ToRadians(b.GetLatitude())))
+		sinU1, cosU1 = math.Sin(u1), math.Cos(u1)
+		sinU2, cosU2 = math.Sin(u2), math.Cos(u2)
+
+		lambda                    = l
+		sinLambda, cosLambda      float64
+		sinSigma, cosSigma, sigma float64
+		cosSqAlpha, cos2SigmaM    float64
+	)
+
+	for i := 0; ; i++ {
+		if i == vincentyMaxIterations {
+			return 0, 0, 0, false
+		}
+
+		sinLambda, cosLambda = math.Sin(lambda), math.Cos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			// coincident points
+			return 0, 0, 0, true
+		}
+
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cosSqAlpha != 0 {
+			// points on the equator have cosSqAlpha = 0
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+
+		c := f / 16 * cosSqAlpha * (4 + f*(4-3*cosSqAlpha))
+		previous := lambda
+		lambda = l + (1-c)*f*sinAlpha*(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+
+		if math.Abs(lambda-previous) < 1e-12 {
+			break
+		}
+		if math.Abs(lambda) > math.Pi {
+			return 0, 0, 0, false
+		}
+	}
+
+	var (
+		uSq        = cosSqAlpha * (wgs84SemiMajor*wgs84SemiMajor - wgs84SemiMinor*wgs84SemiMinor) / (wgs84SemiMinor * wgs84SemiMinor)
+		bigA       = 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+		bigB       = uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+		deltaSigma = bigB * sinSigma * (cos2SigmaM + bigB/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-bigB/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+	)
+
+	distance = wgs84SemiMinor * bigA * (sigma - deltaSigma)
+	initialBearing = toBearing(math.Atan2(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda))
+	finalBearing = toBearing(math.Atan2(cosU1*sinLambda, -sinU1*cosU2+cosU1*sinU2*cosLambda))
+	return distance, initialBearing, finalBearing, true
+}
+
+// toBearing converts an angle in radians into a bearing in degrees in the
+// range [0, 360).
+func toBearing(rad float64) float64 {
+	return math.Mod(rad*180/math.Pi+360, 360)
+}
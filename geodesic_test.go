@@ -0,0 +1,211 @@
+package routeguide
+
+import (
+	"math"
+	"testing"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+func testPoint(t *testing.T, lat, lng float64) *pb.Point {
+	t.Helper()
+
+	point, err := pointFromDegrees(lat, lng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return point
+}
+
+func dms(degrees, minutes, seconds float64) float64 {
+	if degrees < 0 {
+		return degrees - minutes/60 - seconds/3600
+	}
+	return degrees + minutes/60 + seconds/3600
+}
+
+func TestGeodesicInverse(t *testing.T) {
+	const (
+		// the points are rounded to E7, i.e. to about a centimeter
+		distanceTolerance = 0.05
+		bearingTolerance  = 1e-5
+	)
+
+	tests := []struct {
+		name           string
+		geodesic       Geodesic
+		a, b           *pb.Point
+		distance       float64
+		initialBearing float64
+		finalBearing   float64
+	}{
+		{
+			// the example of Vincenty's paper, from Flinders Peak to
+			// Buninyong
+			name:           "vincenty flinders peak",
+			geodesic:       Vincenty,
+			a:              testPoint(t, dms(-37, 57, 3.72030), dms(144, 25, 29.52440)),
+			b:              testPoint(t, dms(-37, 39, 10.15610), dms(143, 55, 35.38390)),
+			distance:       54972.271,
+			initialBearing: dms(306, 52, 5.37),
+			finalBearing:   dms(307, 10, 25.07),
+		},
+		{
+			name:           "vincenty equator",
+			geodesic:       Vincenty,
+			a:              testPoint(t, 0, 0),
+			b:              testPoint(t, 0, 1),
+			distance:       111319.491,
+			initialBearing: 90,
+			finalBearing:   90,
+		},
+		{
+			name:           "vincenty meridian quadrant",
+			geodesic:       Vincenty,
+			a:              testPoint(t, 0, 0),
+			b:              testPoint(t, 90, 0),
+			distance:       10001965.729,
+			initialBearing: 0,
+			finalBearing:   0,
+		},
+		{
+			name:           "vincenty southward",
+			geodesic:       Vincenty,
+			a:              testPoint(t, 1, 30),
+			b:              testPoint(t, -1, 30),
+			distance:       221148.777,
+			initialBearing: 180,
+			finalBearing:   180,
+		},
+		{
+			name:           "vincenty equator across the antimeridian",
+			geodesic:       Vincenty,
+			a:              testPoint(t, 0, 179.5),
+			b:              testPoint(t, 0, -179.5),
+			distance:       111319.491,
+			initialBearing: 90,
+			finalBearing:   90,
+		},
+		{
+			name:           "vincenty equator across the antimeridian westward",
+			geodesic:       Vincenty,
+			a:              testPoint(t, 0, -179.5),
+			b:              testPoint(t, 0, 179.5),
+			distance:       111319.491,
+			initialBearing: 270,
+			finalBearing:   270,
+		},
+		{
+			name:           "haversine equator",
+			geodesic:       Haversine,
+			a:              testPoint(t, 0, 0),
+			b:              testPoint(t, 0, 1),
+			distance:       earthRadius * math.Pi / 180,
+			initialBearing: 90,
+			finalBearing:   90,
+		},
+		{
+			name:           "haversine across the antimeridian",
+			geodesic:       Haversine,
+			a:              testPoint(t, 0, 179.5),
+			b:              testPoint(t, 0, -179.5),
+			distance:       earthRadius * math.Pi / 180,
+			initialBearing: 90,
+			finalBearing:   90,
+		},
+		{
+			name:     "coincident points",
+			geodesic: Vincenty,
+			a:        testPoint(t, 40.5, -74.25),
+			b:        testPoint(t, 40.5, -74.25),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			distance, initialBearing, finalBearing := test.geodesic.Inverse(test.a, test.b)
+			if math.Abs(distance-test.distance) > distanceTolerance {
+				t.Errorf("expected a distance of %.3fm, got %.3fm", test.distance, distance)
+			}
+			if bearingDiff(initialBearing, test.initialBearing) > bearingTolerance {
+				t.Errorf("expected an initial bearing of %.6f, got %.6f", test.initialBearing, initialBearing)
+			}
+			if bearingDiff(finalBearing, test.finalBearing) > bearingTolerance {
+				t.Errorf("expected a final bearing of %.6f, got %.6f", test.finalBearing, finalBearing)
+			}
+		})
+	}
+}
+
+// bearingDiff returns the difference between the bearings, in degrees.
+func bearingDiff(a, b float64) float64 {
+	diff := math.Mod(math.Abs(a-b), 360)
+	return math.Min(diff, 360-diff)
+}
+
+func TestVincentyAcrossTheAntimeridian(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      *pb.Point
+		mirroredA *pb.Point
+		mirroredB *pb.Point
+	}{
+		{
+			name:      "eastward",
+			a:         testPoint(t, 10, 179.5),
+			b:         testPoint(t, 10, -179.5),
+			mirroredA: testPoint(t, 10, -0.5),
+			mirroredB: testPoint(t, 10, 0.5),
+		},
+		{
+			name:      "westward",
+			a:         testPoint(t, -45, -179.9),
+			b:         testPoint(t, -44, 179.2),
+			mirroredA: testPoint(t, -45, 0.1),
+			mirroredB: testPoint(t, -44, -0.8),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			distance, initialBearing, finalBearing, ok := vincentyInverse(test.a, test.b)
+			if !ok {
+				t.Fatal("expected the iteration to converge")
+			}
+
+			// the same path shifted by 180 degrees of longitude doesn't cross
+			// the antimeridian
+			expectedDistance, expectedInitial, expectedFinal, ok := vincentyInverse(test.mirroredA, test.mirroredB)
+			if !ok {
+				t.Fatal("expected the iteration of the shifted path to converge")
+			}
+			if math.Abs(distance-expectedDistance) > 1e-3 {
+				t.Errorf("expected a distance of %.3fm, got %.3fm", expectedDistance, distance)
+			}
+			if bearingDiff(initialBearing, expectedInitial) > 1e-6 || bearingDiff(finalBearing, expectedFinal) > 1e-6 {
+				t.Errorf("expected the bearings (%f, %f), got (%f, %f)", expectedInitial, expectedFinal, initialBearing, finalBearing)
+			}
+			if haversineDistance := haversine(test.a, test.b); math.Abs(distance-haversineDistance) < 1 {
+				t.Errorf("expected the ellipsoidal distance to differ from the spherical distance %.3fm", haversineDistance)
+			}
+		})
+	}
+}
+
+func TestGeodesicAntipodalFallback(t *testing.T) {
+	var (
+		a = testPoint(t, 0, 0)
+		b = testPoint(t, 0.5, 179.7)
+	)
+
+	if _, _, _, ok := vincentyInverse(a, b); ok {
+		t.Skip("the points converged")
+	}
+
+	distance, initialBearing, finalBearing := Vincenty.Inverse(a, b)
+	expectedDistance, expectedInitial, expectedFinal := Haversine.Inverse(a, b)
+	if distance != expectedDistance || initialBearing != expectedInitial || finalBearing != expectedFinal {
+		t.Errorf("expected the spherical solution (%f, %f, %f), got (%f, %f, %f)",
+			expectedDistance, expectedInitial, expectedFinal, distance, initialBearing, finalBearing)
+	}
+}
@@ -0,0 +1,339 @@
+package routeguide
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+var (
+	// ErrGeofenceNotFound is returned when a watched geofence doesn't exist.
+	ErrGeofenceNotFound = errors.New("geofence not found")
+
+	// ErrGeofenceWatcherOverflow is returned when a geofence watcher falls
+	// too far behind the events.
+	ErrGeofenceWatcherOverflow = errors.New("watcher fell too far behind the geofence events")
+)
+
+// geofenceBufferSize is the number of events buffered for a geofence watcher,
+// before it's dropped.
+const geofenceBufferSize = 1024
+
+// geofences tracks the clients that are within the registered geofences, and
+// delivers their ENTER, EXIT and DWELL events to the watchers.
+type geofences struct {
+	fences   []*geofence
+	ids      map[string]*geofence
+	watchers map[*geofenceWatcher]struct{}
+	mutex    sync.Mutex
+}
+
+// geofence is a registered geofence, with the bounds of its area.
+type geofence struct {
+	*pb.Geofence
+	bounds   *pb.Rectangle
+	contains func(*pb.Point) bool
+	dwell    time.Duration
+}
+
+// geofenceSession tracks the visits of the client of a stream. Every stream is
+// tracked on its own, and its visits end with it. The visits are guarded by
+// the mutex of the geofences.
+type geofenceSession struct {
+	clientID string
+	visits   map[string]*geofenceVisit
+
+	// recorded is set if the points are evaluated at the times they were
+	// recorded at, rather than as they arrive. Then, the dwell durations are
+	// measured between the times of the points, and last is the time of the
+	// last timed point.
+	recorded bool
+	last     time.Time
+}
+
+// geofenceVisit is the stay of a client within a geofence.
+type geofenceVisit struct {
+	// location is the last point the client sent within the geofence.
+	location *pb.Point
+	entered  time.Time
+	dwelt    bool
+	dwell    *time.Timer
+}
+
+// geofenceWatcher receives the events of the watched geofences.
+type geofenceWatcher struct {
+	ids    map[string]bool
+	events chan *pb.GeofenceEvent
+	err    error
+}
+
+func newGeofences() *geofences {
+	return &geofences{
+		ids:      make(map[string]*geofence),
+		watchers: make(map[*geofenceWatcher]struct{}),
+	}
+}
+
+// session returns a new session of the client, whose points are evaluated as
+// they arrive, or at the times they were recorded at if recorded is set. The
+// session must be ended with leave.
+func (g *geofences) session(clientID string, recorded bool) *geofenceSession {
+	return &geofenceSession{
+		clientID: clientID,
+		visits:   make(map[string]*geofenceVisit),
+		recorded: recorded,
+	}
+}
+
+// newGeofence validates the area and the dwell duration of the geofence.
+func newGeofence(fence *pb.Geofence) (*geofence, error) {
+	g := &geofence{Geofence: fence}
+
+	switch area := fence.Area.(type) {
+	case *pb.Geofence_Circle:
+		circle := area.Circle
+		if err := checkPoint(circle.GetCenter()); err != nil {
+			return nil, err
+		}
+		if circle.RadiusMeters <= 0 {
+			return nil, fmt.Errorf("radius must be positive")
+		}
+
+		g.bounds = circleBounds(circle.Center, circle.RadiusMeters)
+		g.contains = func(point *pb.Point) bool {
+			return haversine(circle.Center, point) <= circle.RadiusMeters
+		}
+
+	case *pb.Geofence_Polygon:
+		polygon, err := newPolygon(area.Polygon.GetVertices())
+		if err != nil {
+			return nil, err
+		}
+
+		g.bounds = polygon.bounds()
+		g.contains = polygon.contains
+
+	default:
+		return nil, fmt.Errorf("a circle or a polygon is required")
+	}
+
+	if fence.Dwell != nil {
+		dwell, err := ptypes.Duration(fence.Dwell)
+		if err != nil {
+			return nil, err
+		}
+		if dwell < 0 {
+			return nil, fmt.Errorf("dwell duration must not be negative")
+		}
+		g.dwell = dwell
+	}
+
+	return g, nil
+}
+
+// create registers the geofence with a new ID, and returns it.
+func (g *geofences) create(fence *pb.Geofence) (*pb.Geofence, error) {
+	created, err := newGeofence(proto.Clone(fence).(*pb.Geofence))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	created.Id = id
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.fences = append(g.fences, created)
+	g.ids[id] = created
+
+	return proto.Clone(created.Geofence).(*pb.Geofence), nil
+}
+
+// watch returns a watcher of the events of the geofences with the given IDs,
+// or of all the geofences if no ID is given.
+func (g *geofences) watch(ids []string) (*geofenceWatcher, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	w := &geofenceWatcher{events: make(chan *pb.GeofenceEvent, geofenceBufferSize)}
+	if len(ids) > 0 {
+		w.ids = make(map[string]bool)
+		for _, id := range ids {
+			if _, exists := g.ids[id]; !exists {
+				return nil, fmt.Errorf("%s: %s", ErrGeofenceNotFound, id)
+			}
+			w.ids[id] = true
+		}
+	}
+
+	g.watchers[w] = struct{}{}
+	return w, nil
+}
+
+// stop unsubscribes the watcher, and returns the reason it was dropped, if it
+// was.
+func (g *geofences) stop(w *geofenceWatcher) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.drop(w, nil)
+	return w.err
+}
+
+// observe evaluates the point sent by the client of the session at the given
+// time against all the geofences, sending an ENTER event for every geofence
+// the client entered, and an EXIT event for every geofence it left. In a
+// recorded session, a point without a time is evaluated at the time of the
+// previous point.
+func (g *geofences) observe(s *geofenceSession, point *pb.Point, at time.Time) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if s.recorded {
+		if at.IsZero() {
+			at = s.last
+		}
+		s.last = at
+	}
+
+	for _, fence := range g.fences {
+		within := inRange(point, fence.bounds) && fence.contains(point)
+		visit, visiting := s.visits[fence.Id]
+
+		switch {
+		case within && visiting:
+			g.dwellUntil(s, fence, visit, at)
+			visit.location = point
+			if visit.entered.IsZero() {
+				visit.entered = at
+			}
+
+		case within:
+			visit = &geofenceVisit{location: point, entered: at}
+			s.visits[fence.Id] = visit
+			g.send(geofenceEvent(pb.GeofenceEvent_ENTER, fence.Id, s.clientID, point, at))
+
+			if fence.dwell > 0 && !s.recorded {
+				id, visit := fence.Id, visit
+				visit.dwell = time.AfterFunc(fence.dwell, func() {
+					g.dwelt(s, id, visit)
+				})
+			}
+
+		case visiting:
+			g.dwellUntil(s, fence, visit, at)
+			g.exit(s, fence.Id, visit, point, at)
+		}
+	}
+}
+
+// leave ends the session, sending an EXIT event for every geofence the client
+// is still within, with the last point it sent within it. The events of a
+// recorded session carry the time of its last point.
+func (g *geofences) leave(s *geofenceSession) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	at := s.last
+	if !s.recorded {
+		at = time.Now()
+	}
+
+	for _, fence := range g.fences {
+		if visit, visiting := s.visits[fence.Id]; visiting {
+			g.exit(s, fence.Id, visit, visit.location, at)
+		}
+	}
+}
+
+// exit ends the visit, and sends its EXIT event. The caller must hold the
+// mutex.
+func (g *geofences) exit(s *geofenceSession, id string, visit *geofenceVisit, location *pb.Point, at time.Time) {
+	if visit.dwell != nil {
+		visit.dwell.Stop()
+	}
+
+	delete(s.visits, id)
+	g.send(geofenceEvent(pb.GeofenceEvent_EXIT, id, s.clientID, location, at))
+}
+
+// dwellUntil sends the DWELL event of the visit of a recorded session, if the
+// client stayed within the geofence for its dwell duration by the given time.
+// The caller must hold the mutex.
+func (g *geofences) dwellUntil(s *geofenceSession, fence *geofence, visit *geofenceVisit, at time.Time) {
+	if !s.recorded || fence.dwell <= 0 || visit.dwelt || visit.entered.IsZero() || at.IsZero() {
+		return
+	}
+
+	if dwelt := visit.entered.Add(fence.dwell); !at.Before(dwelt) {
+		visit.dwelt = true
+		g.send(geofenceEvent(pb.GeofenceEvent_DWELL, fence.Id, s.clientID, visit.location, dwelt))
+	}
+}
+
+// dwelt sends the DWELL event of the visit, if the client is still within the
+// geofence.
+func (g *geofences) dwelt(s *geofenceSession, id string, visit *geofenceVisit) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if s.visits[id] != visit {
+		return
+	}
+	visit.dwelt = true
+	g.send(geofenceEvent(pb.GeofenceEvent_DWELL, id, s.clientID, visit.location, time.Now()))
+}
+
+// send delivers the event to the watchers of its geofence without blocking.
+// Watchers whose buffers are full are dropped. The caller must hold the
+// mutex.
+func (g *geofences) send(event *pb.GeofenceEvent) {
+	for w := range g.watchers {
+		if w.ids != nil && !w.ids[event.GeofenceId] {
+			continue
+		}
+
+		select {
+		case w.events <- event:
+		default:
+			g.drop(w, ErrGeofenceWatcherOverflow)
+		}
+	}
+}
+
+// drop unsubscribes the watcher, closing its events channel. The caller must
+// hold the mutex.
+func (g *geofences) drop(w *geofenceWatcher, err error) {
+	if _, exists := g.watchers[w]; !exists {
+		return
+	}
+
+	delete(g.watchers, w)
+	w.err = err
+	close(w.events)
+}
+
+// geofenceEvent returns the event at the given time. The time of the event is
+// unset if it's unknown.
+func geofenceEvent(eventType pb.GeofenceEvent_Type, id, clientID string, location *pb.Point, at time.Time) *pb.GeofenceEvent {
+	event := &pb.GeofenceEvent{
+		Type:       eventType,
+		GeofenceId: id,
+		ClientId:   clientID,
+		Location:   location,
+	}
+	if !at.IsZero() {
+		// the times of the timed points are already validated
+		event.Time, _ = ptypes.TimestampProto(at)
+	}
+	return event
+}
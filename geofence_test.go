@@ -0,0 +1,372 @@
+package routeguide
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// testGeofences returns geofences with a park circle that has the given dwell
+// duration, and a square polygon without one, along with their IDs by name.
+func testGeofences(t *testing.T, dwell time.Duration) (*geofences, map[string]string) {
+	t.Helper()
+
+	var (
+		g      = newGeofences()
+		ids    = map[string]string{}
+		fences = []*pb.Geofence{
+			{
+				Name: "park",
+				Area: &pb.Geofence_Circle{Circle: &pb.Circle{
+					Center:       testPoint(t, 10, 10),
+					RadiusMeters: 1000,
+				}},
+				Dwell: ptypes.DurationProto(dwell),
+			},
+			{
+				Name: "square",
+				Area: &pb.Geofence_Polygon{Polygon: &pb.Polygon{Vertices: []*pb.Point{
+					testPoint(t, 20, 20),
+					testPoint(t, 20, 21),
+					testPoint(t, 21, 21),
+					testPoint(t, 21, 20),
+				}}},
+			},
+		}
+	)
+
+	for _, fence := range fences {
+		created, err := g.create(fence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[created.Name] = created.Id
+	}
+	return g, ids
+}
+
+// drainEvents returns the events buffered for the watcher.
+func drainEvents(w *geofenceWatcher) []*pb.GeofenceEvent {
+	events := []*pb.GeofenceEvent{}
+	for {
+		select {
+		case event := <-w.events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// nextEvent waits for the next event of the watcher.
+func nextEvent(t *testing.T, w *geofenceWatcher) *pb.GeofenceEvent {
+	t.Helper()
+
+	select {
+	case event := <-w.events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("expected an event")
+		return nil
+	}
+}
+
+func TestGeofenceRecordedSession(t *testing.T) {
+	var (
+		start    = time.Unix(1600000000, 0)
+		inPark   = testPoint(t, 10, 10)
+		inPark2  = testPoint(t, 10, 10.001)
+		inSquare = testPoint(t, 20.5, 20.5)
+		outside  = testPoint(t, 10.5, 10.5)
+	)
+
+	type step struct {
+		point   *pb.Point
+		at      time.Duration
+		untimed bool
+	}
+	type event struct {
+		eventType pb.GeofenceEvent_Type
+		fence     string
+		location  *pb.Point
+		at        time.Duration
+		untimed   bool
+	}
+
+	tests := []struct {
+		name     string
+		steps    []step
+		expected []event
+	}{
+		{
+			name: "enter and exit",
+			steps: []step{
+				{point: inPark},
+				{point: outside, at: time.Minute},
+			},
+			expected: []event{
+				{eventType: pb.GeofenceEvent_ENTER, fence: "park", location: inPark},
+				{eventType: pb.GeofenceEvent_EXIT, fence: "park", location: outside, at: time.Minute},
+			},
+		},
+		{
+			name: "polygon",
+			steps: []step{
+				{point: outside},
+				{point: inSquare, at: time.Minute},
+				{point: outside, at: 2 * time.Minute},
+			},
+			expected: []event{
+				{eventType: pb.GeofenceEvent_ENTER, fence: "square", location: inSquare, at: time.Minute},
+				{eventType: pb.GeofenceEvent_EXIT, fence: "square", location: outside, at: 2 * time.Minute},
+			},
+		},
+		{
+			name: "dwell between points",
+			steps: []step{
+				{point: inPark},
+				{point: inPark2, at: 3 * time.Minute},
+				{point: inPark, at: 6 * time.Minute},
+			},
+			expected: []event{
+				{eventType: pb.GeofenceEvent_ENTER, fence: "park", location: inPark},
+				{eventType: pb.GeofenceEvent_DWELL, fence: "park", location: inPark2, at: 5 * time.Minute},
+				{eventType: pb.GeofenceEvent_EXIT, fence: "park", location: inPark, at: 6 * time.Minute},
+			},
+		},
+		{
+			name: "dwell before the exit",
+			steps: []step{
+				{point: inPark},
+				{point: outside, at: 10 * time.Minute},
+			},
+			expected: []event{
+				{eventType: pb.GeofenceEvent_ENTER, fence: "park", location: inPark},
+				{eventType: pb.GeofenceEvent_DWELL, fence: "park", location: inPark, at: 5 * time.Minute},
+				{eventType: pb.GeofenceEvent_EXIT, fence: "park", location: outside, at: 10 * time.Minute},
+			},
+		},
+		{
+			name: "dwell once",
+			steps: []step{
+				{point: inPark},
+				{point: inPark2, at: 6 * time.Minute},
+				{point: inPark, at: 12 * time.Minute},
+			},
+			expected: []event{
+				{eventType: pb.GeofenceEvent_ENTER, fence: "park", location: inPark},
+				{eventType: pb.GeofenceEvent_DWELL, fence: "park", location: inPark, at: 5 * time.Minute},
+				{eventType: pb.GeofenceEvent_EXIT, fence: "park", location: inPark, at: 12 * time.Minute},
+			},
+		},
+		{
+			name: "exit at the end of the stream",
+			steps: []step{
+				{point: inPark},
+				{point: inPark2, at: 2 * time.Minute},
+			},
+			expected: []event{
+				{eventType: pb.GeofenceEvent_ENTER, fence: "park", location: inPark},
+				{eventType: pb.GeofenceEvent_EXIT, fence: "park", location: inPark2, at: 2 * time.Minute},
+			},
+		},
+		{
+			name: "untimed points",
+			steps: []step{
+				{point: inPark, untimed: true},
+				{point: inPark2, at: time.Minute},
+				{point: inPark2, untimed: true},
+				{point: inPark, at: 7 * time.Minute},
+			},
+			expected: []event{
+				{eventType: pb.GeofenceEvent_ENTER, fence: "park", location: inPark, untimed: true},
+				{eventType: pb.GeofenceEvent_DWELL, fence: "park", location: inPark2, at: 6 * time.Minute},
+				{eventType: pb.GeofenceEvent_EXIT, fence: "park", location: inPark, at: 7 * time.Minute},
+			},
+		},
+		{
+			name: "untimed point after a timed point",
+			steps: []step{
+				{point: inPark, at: time.Minute},
+				{point: outside, untimed: true},
+			},
+			expected: []event{
+				{eventType: pb.GeofenceEvent_ENTER, fence: "park", location: inPark, at: time.Minute},
+				{eventType: pb.GeofenceEvent_EXIT, fence: "park", location: outside, at: time.Minute},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, ids := testGeofences(t, 5*time.Minute)
+			watcher, err := g.watch(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			session := g.session("client", true)
+			for _, step := range test.steps {
+				var at time.Time
+				if !step.untimed {
+					at = start.Add(step.at)
+				}
+				g.observe(session, step.point, at)
+			}
+			g.leave(session)
+
+			if len(session.visits) != 0 {
+				t.Errorf("expected the visits to end with the session, got %d", len(session.visits))
+			}
+
+			events := drainEvents(watcher)
+			if len(events) != len(test.expected) {
+				t.Fatalf("expected %d events, got %d: %v", len(test.expected), len(events), events)
+			}
+			for i, expected := range test.expected {
+				actual := events[i]
+				if actual.Type != expected.eventType || actual.GeofenceId != ids[expected.fence] || actual.ClientId != "client" {
+					t.Errorf("event %d: expected %s of %s, got %s of %s by %q", i, expected.eventType, ids[expected.fence], actual.Type, actual.GeofenceId, actual.ClientId)
+				}
+				if !proto.Equal(actual.Location, expected.location) {
+					t.Errorf("event %d: expected the location %v, got %v", i, expected.location, actual.Location)
+				}
+
+				if expected.untimed {
+					if actual.Time != nil {
+						t.Errorf("event %d: expected no time, got %v", i, actual.Time)
+					}
+					continue
+				}
+				actualTime, err := ptypes.Timestamp(actual.Time)
+				if err != nil {
+					t.Fatalf("event %d: %s", i, err)
+				}
+				if expectedTime := start.Add(expected.at); !actualTime.Equal(expectedTime) {
+					t.Errorf("event %d: expected the time %s, got %s", i, expectedTime, actualTime)
+				}
+			}
+		})
+	}
+}
+
+func TestGeofenceLiveDwell(t *testing.T) {
+	var (
+		g, ids = testGeofences(t, 10*time.Millisecond)
+		inPark = testPoint(t, 10, 10)
+	)
+	watcher, err := g.watch(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := g.session("client", false)
+	g.observe(session, inPark, time.Now())
+
+	for _, expected := range []pb.GeofenceEvent_Type{pb.GeofenceEvent_ENTER, pb.GeofenceEvent_DWELL} {
+		if event := nextEvent(t, watcher); event.Type != expected || event.GeofenceId != ids["park"] {
+			t.Fatalf("expected %s of %s, got %s of %s", expected, ids["park"], event.Type, event.GeofenceId)
+		}
+	}
+
+	g.leave(session)
+	if event := nextEvent(t, watcher); event.Type != pb.GeofenceEvent_EXIT || !proto.Equal(event.Location, inPark) {
+		t.Errorf("expected an EXIT at %v, got %s at %v", inPark, event.Type, event.Location)
+	}
+}
+
+func TestGeofenceLeaveStopsDwell(t *testing.T) {
+	var (
+		g, _   = testGeofences(t, 20*time.Millisecond)
+		inPark = testPoint(t, 10, 10)
+	)
+	watcher, err := g.watch(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := g.session("client", false)
+	g.observe(session, inPark, time.Now())
+	g.leave(session)
+
+	time.Sleep(50 * time.Millisecond)
+	events := drainEvents(watcher)
+	if len(events) != 2 || events[0].Type != pb.GeofenceEvent_ENTER || events[1].Type != pb.GeofenceEvent_EXIT {
+		t.Errorf("expected an ENTER and an EXIT, got %v", events)
+	}
+	if len(session.visits) != 0 {
+		t.Errorf("expected the visits to end with the session, got %d", len(session.visits))
+	}
+}
+
+func TestGeofenceWatchFilter(t *testing.T) {
+	var (
+		g, ids   = testGeofences(t, 0)
+		inPark   = testPoint(t, 10, 10)
+		inSquare = testPoint(t, 20.5, 20.5)
+	)
+
+	tests := []struct {
+		name     string
+		ids      []string
+		expected []string
+	}{
+		{
+			name:     "all",
+			expected: []string{ids["park"], ids["park"], ids["square"], ids["square"]},
+		},
+		{
+			name:     "park",
+			ids:      []string{ids["park"]},
+			expected: []string{ids["park"], ids["park"]},
+		},
+		{
+			name:     "square",
+			ids:      []string{ids["square"]},
+			expected: []string{ids["square"], ids["square"]},
+		},
+		{
+			name:     "both",
+			ids:      []string{ids["square"], ids["park"]},
+			expected: []string{ids["park"], ids["park"], ids["square"], ids["square"]},
+		},
+	}
+
+	watchers := make([]*geofenceWatcher, len(tests))
+	for i, test := range tests {
+		watcher, err := g.watch(test.ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		watchers[i] = watcher
+	}
+
+	// the client enters the park, leaves it for the square, and leaves the
+	// square as its stream ends
+	session := g.session("client", false)
+	g.observe(session, inPark, time.Now())
+	g.observe(session, inSquare, time.Now())
+	g.leave(session)
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events := drainEvents(watchers[i])
+			actual := make([]string, len(events))
+			for j, event := range events {
+				actual[j] = event.GeofenceId
+			}
+
+			if strings.Join(actual, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("expected the events of %v, got %v", test.expected, actual)
+			}
+		})
+	}
+
+	if _, err := g.watch([]string{ids["park"], "unknown"}); err == nil || !strings.HasPrefix(err.Error(), ErrGeofenceNotFound.Error()) {
+		t.Errorf("expected an %q error, got %v", ErrGeofenceNotFound, err)
+	}
+}
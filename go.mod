@@ -5,12 +5,14 @@ go 1.19
 require (
 	github.com/gogo/protobuf v1.2.0
 	github.com/golang/protobuf v1.2.1-0.20190109072247-347cf4a86c1c
+	go.etcd.io/bbolt v1.3.6
 	google.golang.org/grpc v1.18.0
 )
 
 require (
 	golang.org/x/net v0.0.0-20190125091013-d26f9f9a57f3 // indirect
-	golang.org/x/sys v0.0.0-20190129075346-302c3dd5f1cc // indirect
+	golang.org/x/sys v0.0.0-20200923182605-d9f96fdee20d // indirect
 	golang.org/x/text v0.3.0 // indirect
-	google.golang.org/genproto v0.0.0-20190128161407-8ac453e89fca // indirect
+	google.golang.org/genproto v0.0.0-20190128161407-8ac453e89fca
+	gopkg.in/yaml.v2 v2.4.0
 )
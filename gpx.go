@@ -0,0 +1,129 @@
+package routeguide
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+const (
+	// GPXContentType is the media type of GPX documents.
+	GPXContentType = "application/gpx+xml"
+
+	gpxNamespace = "http://www.topografix.com/GPX/1/1"
+	gpxCreator   = "routeguide"
+)
+
+// gpxDocument is a GPX document, limited to the elements that describe routes
+// and tracks. Documents are written as GPX 1.1, and read regardless of their
+// version.
+type gpxDocument struct {
+	XMLName  xml.Name     `xml:"gpx"`
+	Xmlns    string       `xml:"xmlns,attr,omitempty"`
+	Version  string       `xml:"version,attr"`
+	Creator  string       `xml:"creator,attr"`
+	Metadata *gpxMetadata `xml:"metadata,omitempty"`
+	Routes   []gpxRoute   `xml:"rte"`
+	Tracks   []gpxTrack   `xml:"trk"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name,omitempty"`
+	Desc string `xml:"desc,omitempty"`
+	Time string `xml:"time,omitempty"`
+}
+
+type gpxRoute struct {
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name,omitempty"`
+	Desc     string       `xml:"desc,omitempty"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  gpxCoordinate `xml:"lat,attr"`
+	Lon  gpxCoordinate `xml:"lon,attr"`
+	Time string        `xml:"time,omitempty"`
+}
+
+// gpxCoordinate is a coordinate in the E7 representation, that is written as
+// decimal degrees.
+type gpxCoordinate int32
+
+func (c gpxCoordinate) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{
+		Name:  name,
+		Value: strconv.FormatFloat(float64(c)/1e7, 'f', 7, 64),
+	}, nil
+}
+
+func (c *gpxCoordinate) UnmarshalXMLAttr(attr xml.Attr) error {
+	degrees, err := strconv.ParseFloat(attr.Value, 64)
+	if err != nil || math.IsNaN(degrees) || math.Abs(degrees) > 180 {
+		return fmt.Errorf("invalid %s %q", attr.Name.Local, attr.Value)
+	}
+
+	*c = gpxCoordinate(math.Round(degrees * 1e7))
+	return nil
+}
+
+// ExportGPX encodes the route as a GPX 1.1 document, with its points in a
+// single track segment. The points carry their times, if the route has them.
+func ExportGPX(route *pb.Route) ([]byte, error) {
+	segment := gpxSegment{Points: make([]gpxPoint, len(route.Points))}
+	for i, point := range route.Points {
+		segment.Points[i] = gpxPoint{
+			Lat: gpxCoordinate(point.Latitude),
+			Lon: gpxCoordinate(point.Longitude),
+		}
+
+		if len(route.PointTimes) == len(route.Points) {
+			if recorded, err := ptypes.Timestamp(route.PointTimes[i]); err == nil {
+				segment.Points[i].Time = recorded.UTC().Format(time.RFC3339Nano)
+			}
+		}
+	}
+
+	doc := gpxDocument{
+		Xmlns:   gpxNamespace,
+		Version: "1.1",
+		Creator: gpxCreator,
+		Metadata: &gpxMetadata{
+			Name: route.Id,
+			Desc: fmt.Sprintf("recorded by %s", route.ClientId),
+		},
+		Tracks: []gpxTrack{{
+			Name:     route.Id,
+			Segments: []gpxSegment{segment},
+		}},
+	}
+
+	if started, err := ptypes.Timestamp(route.StartedAt); err == nil {
+		doc.Metadata.Time = started.UTC().Format(time.RFC3339)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
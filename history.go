@@ -0,0 +1,241 @@
+package routeguide
+
+import (
+	"container/list"
+	"expvar"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// RetentionPolicy bounds the history of the route notes kept by the server.
+// Zero fields leave the history unbounded in that dimension.
+type RetentionPolicy struct {
+	// TTL is how long a note is kept after it's sent.
+	TTL time.Duration
+
+	// MaxNotesPerLocation is the number of notes kept per location. The
+	// oldest notes of a location are evicted first.
+	MaxNotesPerLocation int
+
+	// MaxBytes caps the encoded size of all the notes kept. When it's
+	// exceeded, the notes of the least recently used locations are evicted.
+	MaxBytes int64
+}
+
+// DefaultRetentionPolicy keeps the notes of the last day, up to 100 notes per
+// location and 64 MiB in total.
+var DefaultRetentionPolicy = RetentionPolicy{
+	TTL:                 24 * time.Hour,
+	MaxNotesPerLocation: 100,
+	MaxBytes:            64 << 20,
+}
+
+// noteStats exposes the size of the note history and the eviction counts on
+// the /debug/vars endpoint.
+var noteStats = expvar.NewMap("route_notes")
+
+const (
+	statNotes     = "notes"
+	statBytes     = "bytes"
+	statLocations = "locations"
+
+	statEvictedTTL       = "evicted_ttl"
+	statEvictedLocation  = "evicted_location_cap"
+	statEvictedMemoryCap = "evicted_memory_cap"
+)
+
+// noteHistory keeps the recent notes of every location, within the bounds of
+// a retention policy. Locations are kept in the order they were last used, so
+// that the coldest locations are evicted first. It's not safe for concurrent
+// use.
+type noteHistory struct {
+	policy    RetentionPolicy
+	locations map[string]*list.Element
+	lru       *list.List
+	bytes     int64
+	now       func() time.Time
+}
+
+// locationHistory is the history of a location, oldest note first.
+type locationHistory struct {
+	key      string
+	location *pb.Point
+	notes    []historyNote
+}
+
+type historyNote struct {
+	note   *pb.RouteNote
+	sentAt time.Time
+	size   int64
+}
+
+func newNoteHistory(policy RetentionPolicy) *noteHistory {
+	return &noteHistory{
+		policy:    policy,
+		locations: make(map[string]*list.Element),
+		lru:       list.New(),
+		now:       time.Now,
+	}
+}
+
+// append adds the note sent at the given time to the history of its location,
+// evicting the notes that fall outside the retention policy.
+func (h *noteHistory) append(key string, note *pb.RouteNote, sentAt time.Time) {
+	now := h.now()
+	h.expireCold(now)
+
+	// expiring the notes of the location may evict it, so it's looked up
+	// again afterwards
+	if element, exists := h.locations[key]; exists {
+		h.expire(element.Value.(*locationHistory), now)
+	}
+
+	element, exists := h.locations[key]
+	if !exists {
+		element = h.lru.PushFront(&locationHistory{key: key, location: note.Location})
+		h.locations[key] = element
+		noteStats.Add(statLocations, 1)
+	}
+	h.lru.MoveToFront(element)
+
+	location := element.Value.(*locationHistory)
+
+	size := int64(proto.Size(note))
+	location.notes = append(location.notes, historyNote{note: note, sentAt: sentAt, size: size})
+	h.bytes += size
+	noteStats.Add(statNotes, 1)
+	noteStats.Add(statBytes, size)
+
+	if max := h.policy.MaxNotesPerLocation; max > 0 && len(location.notes) > max {
+		h.evict(location, len(location.notes)-max, statEvictedLocation)
+	}
+
+	for h.policy.MaxBytes > 0 && h.bytes > h.policy.MaxBytes {
+		coldest := h.lru.Back().Value.(*locationHistory)
+		if coldest == location {
+			// the location of the note is the only one left, so only its
+			// oldest notes are evicted
+			h.evict(location, 1, statEvictedMemoryCap)
+			continue
+		}
+		h.evict(coldest, len(coldest.notes), statEvictedMemoryCap)
+	}
+}
+
+// get returns the unexpired notes of the location, marking it as used.
+func (h *noteHistory) get(key string) []*pb.RouteNote {
+	element, exists := h.locations[key]
+	if !exists {
+		return nil
+	}
+	h.lru.MoveToFront(element)
+
+	location := element.Value.(*locationHistory)
+	h.expire(location, h.now())
+	return location.copyNotes()
+}
+
+// within returns the unexpired notes of the locations within the area,
+// marking them as used.
+func (h *noteHistory) within(area *pb.Rectangle) []*pb.RouteNote {
+	var (
+		now   = h.now()
+		notes = []*pb.RouteNote{}
+		used  = []*list.Element{}
+	)
+
+	for _, element := range h.locations {
+		location := element.Value.(*locationHistory)
+		if !inRange(location.location, area) {
+			continue
+		}
+
+		h.expire(location, now)
+		notes = append(notes, location.copyNotes()...)
+		used = append(used, element)
+	}
+
+	for _, element := range used {
+		if _, exists := h.locations[element.Value.(*locationHistory).key]; exists {
+			h.lru.MoveToFront(element)
+		}
+	}
+
+	return notes
+}
+
+// snapshot returns all the notes in the history, from the least recently used
+// location to the most recently used, so that appending them to an empty
+// history restores the order of the locations.
+func (h *noteHistory) snapshot() []loggedNote {
+	notes := []loggedNote{}
+	for element := h.lru.Back(); element != nil; element = element.Prev() {
+		for _, n := range element.Value.(*locationHistory).notes {
+			notes = append(notes, loggedNote{note: n.note, sentAt: n.sentAt})
+		}
+	}
+	return notes
+}
+
+// expire evicts the notes of the location that outlived the TTL.
+func (h *noteHistory) expire(location *locationHistory, now time.Time) {
+	if h.policy.TTL <= 0 {
+		return
+	}
+
+	expired := 0
+	for expired < len(location.notes) && now.Sub(location.notes[expired].sentAt) > h.policy.TTL {
+		expired++
+	}
+	if expired > 0 {
+		h.evict(location, expired, statEvictedTTL)
+	}
+}
+
+// expireCold evicts the coldest locations whose notes all outlived the TTL.
+// The expired notes of the other locations are evicted when they are used.
+func (h *noteHistory) expireCold(now time.Time) {
+	if h.policy.TTL <= 0 {
+		return
+	}
+
+	for element := h.lru.Back(); element != nil; element = h.lru.Back() {
+		location := element.Value.(*locationHistory)
+		newest := location.notes[len(location.notes)-1]
+		if now.Sub(newest.sentAt) <= h.policy.TTL {
+			return
+		}
+		h.evict(location, len(location.notes), statEvictedTTL)
+	}
+}
+
+// evict removes the n oldest notes of the location. A location without notes
+// is removed from the history.
+func (h *noteHistory) evict(location *locationHistory, n int, reason string) {
+	var size int64
+	for _, evicted := range location.notes[:n] {
+		size += evicted.size
+	}
+
+	location.notes = append(location.notes[:0:0], location.notes[n:]...)
+	h.bytes -= size
+	noteStats.Add(statNotes, int64(-n))
+	noteStats.Add(statBytes, -size)
+	noteStats.Add(reason, int64(n))
+
+	if len(location.notes) == 0 {
+		h.lru.Remove(h.locations[location.key])
+		delete(h.locations, location.key)
+		noteStats.Add(statLocations, -1)
+	}
+}
+
+func (l *locationHistory) copyNotes() []*pb.RouteNote {
+	notes := make([]*pb.RouteNote, len(l.notes))
+	for i, n := range l.notes {
+		notes[i] = n.note
+	}
+	return notes
+}
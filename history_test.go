@@ -0,0 +1,203 @@
+package routeguide
+
+import (
+	"expvar"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// historyLocations are the locations of the notes in the history tests, by
+// their keys. Their notes have the same encoded size.
+var historyLocations = map[string]*pb.Point{
+	"a": {Latitude: 1, Longitude: 1},
+	"b": {Latitude: 2, Longitude: 2},
+	"c": {Latitude: 3, Longitude: 3},
+}
+
+func testHistoryNote(key, message string) *pb.RouteNote {
+	return &pb.RouteNote{Location: historyLocations[key], Message: message}
+}
+
+// noteStat returns the value of the note history counter.
+func noteStat(name string) int64 {
+	if v, ok := noteStats.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestNoteHistoryEviction(t *testing.T) {
+	// the size of every note in the tests
+	size := int64(proto.Size(testHistoryNote("a", "00")))
+
+	type step struct {
+		at      time.Duration
+		key     string
+		message string
+
+		// get marks the location as used instead of appending a note
+		get bool
+	}
+
+	tests := []struct {
+		name     string
+		policy   RetentionPolicy
+		steps    []step
+		expected []string
+		evicted  map[string]int64
+	}{
+		{
+			name:   "unbounded",
+			policy: RetentionPolicy{},
+			steps: []step{
+				{key: "a", message: "a1"},
+				{at: 48 * time.Hour, key: "b", message: "b1"},
+				{at: 48 * time.Hour, key: "a", message: "a2"},
+			},
+			expected: []string{"b1", "a1", "a2"},
+		},
+		{
+			name:   "expired notes of the location",
+			policy: RetentionPolicy{TTL: time.Minute},
+			steps: []step{
+				{key: "a", message: "a1"},
+				{at: 30 * time.Second, key: "a", message: "a2"},
+				{at: 30 * time.Second, key: "b", message: "b1"},
+				{at: 80 * time.Second, key: "a", message: "a3"},
+			},
+			expected: []string{"b1", "a2", "a3"},
+			evicted:  map[string]int64{statEvictedTTL: 1},
+		},
+		{
+			name:   "expired cold locations",
+			policy: RetentionPolicy{TTL: time.Minute},
+			steps: []step{
+				{key: "a", message: "a1"},
+				{at: 10 * time.Second, key: "b", message: "b1"},
+				{at: 50 * time.Second, key: "c", message: "c1"},
+				{at: 90 * time.Second, key: "c", message: "c2"},
+			},
+			expected: []string{"c1", "c2"},
+			evicted:  map[string]int64{statEvictedTTL: 2},
+		},
+		{
+			name:   "expired notes of a used location",
+			policy: RetentionPolicy{TTL: time.Minute},
+			steps: []step{
+				{key: "a", message: "a1"},
+				{at: 50 * time.Second, key: "a", message: "a2"},
+				{at: 90 * time.Second, key: "a", get: true},
+			},
+			expected: []string{"a2"},
+			evicted:  map[string]int64{statEvictedTTL: 1},
+		},
+		{
+			name:   "location cap",
+			policy: RetentionPolicy{MaxNotesPerLocation: 2},
+			steps: []step{
+				{key: "a", message: "a1"},
+				{key: "a", message: "a2"},
+				{key: "b", message: "b1"},
+				{key: "a", message: "a3"},
+				{key: "a", message: "a4"},
+			},
+			expected: []string{"b1", "a3", "a4"},
+			evicted:  map[string]int64{statEvictedLocation: 2},
+		},
+		{
+			name:   "memory cap evicts the least recently used location",
+			policy: RetentionPolicy{MaxBytes: 3 * size},
+			steps: []step{
+				{key: "a", message: "a1"},
+				{key: "a", message: "a2"},
+				{key: "b", message: "b1"},
+				{key: "a", get: true},
+				{key: "c", message: "c1"},
+			},
+			expected: []string{"a1", "a2", "c1"},
+			evicted:  map[string]int64{statEvictedMemoryCap: 1},
+		},
+		{
+			name:   "memory cap evicts whole locations",
+			policy: RetentionPolicy{MaxBytes: 3 * size},
+			steps: []step{
+				{key: "a", message: "a1"},
+				{key: "a", message: "a2"},
+				{key: "b", message: "b1"},
+				{key: "c", message: "c1"},
+			},
+			expected: []string{"b1", "c1"},
+			evicted:  map[string]int64{statEvictedMemoryCap: 2},
+		},
+		{
+			name:   "memory cap of a single location",
+			policy: RetentionPolicy{MaxBytes: 2 * size},
+			steps: []step{
+				{key: "a", message: "a1"},
+				{key: "a", message: "a2"},
+				{key: "a", message: "a3"},
+			},
+			expected: []string{"a2", "a3"},
+			evicted:  map[string]int64{statEvictedMemoryCap: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				start = time.Unix(1600000000, 0)
+				now   = start
+				h     = newNoteHistory(test.policy)
+			)
+			h.now = func() time.Time { return now }
+
+			stats := []string{statNotes, statBytes, statLocations, statEvictedTTL, statEvictedLocation, statEvictedMemoryCap}
+			before := map[string]int64{}
+			for _, stat := range stats {
+				before[stat] = noteStat(stat)
+			}
+
+			for _, step := range test.steps {
+				now = start.Add(step.at)
+				if step.get {
+					h.get(step.key)
+					continue
+				}
+				h.append(step.key, testHistoryNote(step.key, step.message), now)
+			}
+
+			actual := []string{}
+			for _, n := range h.snapshot() {
+				actual = append(actual, n.note.Message)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected the notes %v, got %v", test.expected, actual)
+			}
+
+			expected := map[string]int64{
+				statNotes:     int64(len(test.expected)),
+				statBytes:     int64(len(test.expected)) * size,
+				statLocations: int64(len(h.locations)),
+			}
+			for stat, n := range test.evicted {
+				expected[stat] = n
+			}
+			for _, stat := range stats {
+				if delta := noteStat(stat) - before[stat]; delta != expected[stat] {
+					t.Errorf("expected %s to change by %d, got %d", stat, expected[stat], delta)
+				}
+			}
+
+			if h.bytes != int64(len(test.expected))*size {
+				t.Errorf("expected %d bytes, got %d", int64(len(test.expected))*size, h.bytes)
+			}
+			if h.lru.Len() != len(h.locations) {
+				t.Errorf("expected %d locations in the LRU list, got %d", len(h.locations), h.lru.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package routeguide
+
+import (
+	"math"
+	"sort"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// defaultCellSize is the width and height of a grid cell in the E7
+// representation, i.e. 0.1 degrees.
+const defaultCellSize = 1000000
+
+type pointKey struct {
+	latitude, longitude int32
+}
+
+type cellKey struct {
+	row, col int64
+}
+
+// gridIndex is a spatial index that buckets features into fixed-size
+// latitude-longitude cells. Point lookups are O(1) and rectangle queries only
+// visit the cells that overlap the rectangle. It is not safe for concurrent
+// use.
+type gridIndex struct {
+	cellSize int64
+	points   map[pointKey]*pb.Feature
+	cells    map[cellKey][]*pb.Feature
+}
+
+func newGridIndex(cellSize int32) *gridIndex {
+	return &gridIndex{
+		cellSize: int64(cellSize),
+		points:   make(map[pointKey]*pb.Feature),
+		cells:    make(map[cellKey][]*pb.Feature),
+	}
+}
+
+// insert adds the feature to the index, replacing any feature at the same
+// location.
+func (g *gridIndex) insert(feature *pb.Feature) {
+	key := keyOf(feature.Location)
+	if _, exists := g.points[key]; exists {
+		g.remove(feature.Location)
+	}
+
+	g.points[key] = feature
+	cell := g.cellOf(feature.Location)
+	g.cells[cell] = append(g.cells[cell], feature)
+}
+
+// remove deletes the feature at the given point from the index.
+func (g *gridIndex) remove(point *pb.Point) {
+	key := keyOf(point)
+	if _, exists := g.points[key]; !exists {
+		return
+	}
+	delete(g.points, key)
+
+	cell := g.cellOf(point)
+	features := g.cells[cell]
+	for i, feature := range features {
+		if keyOf(feature.Location) == key {
+			features = append(features[:i], features[i+1:]...)
+			break
+		}
+	}
+
+	if len(features) == 0 {
+		delete(g.cells, cell)
+		return
+	}
+	g.cells[cell] = features
+}
+
+func (g *gridIndex) get(point *pb.Point) *pb.Feature {
+	return g.points[keyOf(point)]
+}
+
+// query returns the features within the given rectangle.
+func (g *gridIndex) query(rectangle *pb.Rectangle) []*pb.Feature {
+	// split a rectangle that crosses the antimeridian into the parts on either
+	// side of it
+	if west, east := rectangle.GetLo().GetLongitude(), rectangle.GetHi().GetLongitude(); rectangle.GetOriented() && west > east {
+		var (
+			bottom, top = rectangle.GetLo().GetLatitude(), rectangle.GetHi().GetLatitude()
+			westOf      = &pb.Rectangle{Lo: &pb.Point{Latitude: bottom, Longitude: west}, Hi: &pb.Point{Latitude: top, Longitude: maxE7}}
+			eastOf      = &pb.Rectangle{Lo: &pb.Point{Latitude: bottom, Longitude: minE7}, Hi: &pb.Point{Latitude: top, Longitude: east}}
+		)
+		return append(g.query(westOf), g.query(eastOf)...)
+	}
+
+	var (
+		lo, hi   = rectangleBounds(rectangle)
+		loCell   = g.cellOf(lo)
+		hiCell   = g.cellOf(hi)
+		rows     = hiCell.row - loCell.row + 1
+		cols     = hiCell.col - loCell.col + 1
+		features = []*pb.Feature{}
+	)
+
+	collect := func(candidates []*pb.Feature) {
+		for _, feature := range candidates {
+			if inRange(feature.Location, rectangle) {
+				features = append(features, feature)
+			}
+		}
+	}
+
+	// a rectangle that spans more cells than there are populated ones is
+	// cheaper to answer by visiting the populated cells directly
+	if rows*cols > int64(len(g.cells)) {
+		for cell, candidates := range g.cells {
+			if cell.row >= loCell.row && cell.row <= hiCell.row && cell.col >= loCell.col && cell.col <= hiCell.col {
+				collect(candidates)
+			}
+		}
+		return features
+	}
+
+	for row := loCell.row; row <= hiCell.row; row++ {
+		for col := loCell.col; col <= hiCell.col; col++ {
+			collect(g.cells[cellKey{row: row, col: col}])
+		}
+	}
+	return features
+}
+
+// nearest returns up to k features closest to the given point, ordered by
+// their distance. If maxDistance is positive, features further than
+// maxDistance meters are excluded. The populated cells are visited in the
+// order of their minimum possible distance from the point, until none of the
+// remaining cells can contain a nearer feature.
+func (g *gridIndex) nearest(point *pb.Point, k int, maxDistance float64) []*pb.NearbyFeature {
+	type candidate struct {
+		cell     cellKey
+		distance float64
+	}
+
+	candidates := make([]candidate, 0, len(g.cells))
+	for cell := range g.cells {
+		distance := g.minDistance(point, cell)
+		if maxDistance > 0 && distance > maxDistance {
+			continue
+		}
+		candidates = append(candidates, candidate{cell: cell, distance: distance})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	set := newNearestSet(point, k, maxDistance)
+	for _, c := range candidates {
+		if set.full(c.distance) {
+			break
+		}
+
+		for _, feature := range g.cells[c.cell] {
+			set.offer(feature)
+		}
+	}
+
+	return set.sorted()
+}
+
+// minDistance returns a lower bound of the distance in meters between the
+// point and any point in the cell.
+//
+// By the haversine formula, hav(d) = hav(dLat) + cos(lat1)cos(lat2)hav(dLng).
+// Substituting the smallest latitude and longitude differences between the
+// point and the cell, and the cosine of the latitude furthest from the
+// equator, gives a distance that can't be larger than the actual one.
+func (g *gridIndex) minDistance(point *pb.Point, cell cellKey) float64 {
+	var (
+		lat    = e7ToRadians(point.GetLatitude())
+		lng    = e7ToRadians(point.GetLongitude())
+		bottom = math.Max(float64(cell.row*g.cellSize)/1e7, -90) * math.Pi / 180
+		top    = math.Min(float64((cell.row+1)*g.cellSize)/1e7, 90) * math.Pi / 180
+		left   = float64(cell.col*g.cellSize) / 1e7 * math.Pi / 180
+		right  = float64((cell.col+1)*g.cellSize) / 1e7 * math.Pi / 180
+	)
+
+	var dLat float64
+	if lat < bottom {
+		dLat = bottom - lat
+	} else if lat > top {
+		dLat = lat - top
+	}
+
+	var dLng float64
+	if lng < left || lng > right {
+		dLng = math.Min(angularGap(lng, left), angularGap(lng, right))
+	}
+
+	var (
+		furthest = math.Min(math.Max(math.Abs(lat), math.Max(math.Abs(bottom), math.Abs(top))), math.Pi/2)
+		cos      = math.Cos(furthest)
+		h        = hav(dLat) + cos*cos*hav(dLng)
+	)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// angularGap returns the smallest angle between the two longitudes, taking
+// the antimeridian into account.
+func angularGap(a, b float64) float64 {
+	gap := math.Mod(math.Abs(a-b), 2*math.Pi)
+	if gap > math.Pi {
+		gap = 2*math.Pi - gap
+	}
+	return gap
+}
+
+func (g *gridIndex) cellOf(point *pb.Point) cellKey {
+	return cellKey{
+		row: floorDiv(int64(point.GetLatitude()), g.cellSize),
+		col: floorDiv(int64(point.GetLongitude()), g.cellSize),
+	}
+}
+
+func keyOf(point *pb.Point) pointKey {
+	return pointKey{latitude: point.GetLatitude(), longitude: point.GetLongitude()}
+}
+
+// rectangleBounds returns the bottom-left and top-right corners of the
+// rectangle.
+func rectangleBounds(rectangle *pb.Rectangle) (*pb.Point, *pb.Point) {
+	var (
+		bottom, top = rectangle.GetLo().GetLatitude(), rectangle.GetHi().GetLatitude()
+		left, right = rectangle.GetLo().GetLongitude(), rectangle.GetHi().GetLongitude()
+	)
+	if bottom > top {
+		bottom, top = top, bottom
+	}
+	if left > right {
+		left, right = right, left
+	}
+
+	return &pb.Point{Latitude: bottom, Longitude: left}, &pb.Point{Latitude: top, Longitude: right}
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,111 @@
+package routeguide
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// randIndexPoint returns a point around the origin, so that the points fall on
+// both sides of the equator and the prime meridian. A third of the points are
+// on the edges of the grid cells.
+func randIndexPoint(r *rand.Rand, cellSize int32) *pb.Point {
+	if r.Intn(3) == 0 {
+		return &pb.Point{
+			Latitude:  (r.Int31n(21) - 10) * cellSize,
+			Longitude: (r.Int31n(21) - 10) * cellSize,
+		}
+	}
+
+	return &pb.Point{
+		Latitude:  r.Int31n(20*cellSize) - 10*cellSize,
+		Longitude: r.Int31n(20*cellSize) - 10*cellSize,
+	}
+}
+
+// scanQuery returns the names of the features within the rectangle, by
+// checking every feature.
+func scanQuery(features map[pointKey]*pb.Feature, rectangle *pb.Rectangle) []string {
+	names := []string{}
+	for _, feature := range features {
+		if inRange(feature.Location, rectangle) {
+			names = append(names, feature.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestGridIndexMatchesScan(t *testing.T) {
+	const cellSize = 1000
+
+	var (
+		r        = rand.New(rand.NewSource(1))
+		index    = newGridIndex(cellSize)
+		features = map[pointKey]*pb.Feature{}
+	)
+
+	for i := 0; i < 2000; i++ {
+		feature := &pb.Feature{Name: fmt.Sprintf("feature #%d", i), Location: randIndexPoint(r, cellSize)}
+		index.insert(feature)
+		features[keyOf(feature.Location)] = feature
+
+		// remove some of the features again
+		if r.Intn(4) == 0 {
+			removed := randIndexPoint(r, cellSize)
+			index.remove(removed)
+			delete(features, keyOf(removed))
+		}
+	}
+
+	for i := 0; i < 2000; i++ {
+		point := randIndexPoint(r, cellSize)
+		expected := features[keyOf(point)]
+		if actual := index.get(point); actual != expected {
+			t.Fatalf("get(%v): expected %v, got %v", point, expected, actual)
+		}
+	}
+
+	for i := 0; i < 500; i++ {
+		rectangle := &pb.Rectangle{
+			Lo:       randIndexPoint(r, cellSize),
+			Hi:       randIndexPoint(r, cellSize),
+			Oriented: r.Intn(4) == 0,
+		}
+
+		actual := []string{}
+		for _, feature := range index.query(rectangle) {
+			actual = append(actual, feature.Name)
+		}
+		sort.Strings(actual)
+
+		if expected := scanQuery(features, rectangle); fmt.Sprint(actual) != fmt.Sprint(expected) {
+			t.Fatalf("query(%v): expected %d features, got %d", rectangle, len(expected), len(actual))
+		}
+	}
+}
+
+func TestFloorDiv(t *testing.T) {
+	tests := []struct {
+		a, b     int64
+		expected int64
+	}{
+		{a: 0, b: 10, expected: 0},
+		{a: 9, b: 10, expected: 0},
+		{a: 10, b: 10, expected: 1},
+		{a: -1, b: 10, expected: -1},
+		{a: -10, b: 10, expected: -1},
+		{a: -11, b: 10, expected: -2},
+		{a: minE7, b: defaultCellSize, expected: -1800},
+		{a: maxE7, b: defaultCellSize, expected: 1800},
+	}
+
+	for _, test := range tests {
+		if actual := floorDiv(test.a, test.b); actual != test.expected {
+			t.Errorf("floorDiv(%d, %d): expected %d, got %d", test.a, test.b, test.expected, actual)
+		}
+	}
+}
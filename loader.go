@@ -0,0 +1,354 @@
+package routeguide
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// RejectedRow describes an entry of a dataset file that couldn't be converted
+// into a feature.
+type RejectedRow struct {
+	Line   int
+	Reason string
+}
+
+func (r RejectedRow) String() string {
+	return fmt.Sprintf("line %d: %s", r.Line, r.Reason)
+}
+
+// LoadDatasets reads the features in the dataset files at the given paths,
+// logging the rejected rows with their line numbers. The rejected rows are
+// skipped, unless strict is set, in which case a file with rejected rows, or
+// files without any features, fail the load. Reloads are strict, so that a
+// broken file can't replace a good dataset.
+func LoadDatasets(paths []string, strict bool) ([]*pb.Feature, error) {
+	features := []*pb.Feature{}
+	for _, path := range paths {
+		loaded, rejected, err := LoadFeatures(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+
+		for _, row := range rejected {
+			log.Printf("[loader] %s: rejected %s", path, row)
+		}
+		if strict && len(rejected) > 0 {
+			return nil, fmt.Errorf("%s: %d rejected rows, first at %s", path, len(rejected), rejected[0])
+		}
+
+		log.Printf("[loader] loaded %d features from %s (%d rejected)", len(loaded), path, len(rejected))
+		features = append(features, loaded...)
+	}
+
+	if strict && len(features) == 0 {
+		return nil, fmt.Errorf("no features found in %v", paths)
+	}
+	return features, nil
+}
+
+// LoadFeatures reads the features in the dataset file at the given path. Files
+// with the .csv extension are read as CSV files with name, latitude and
+// longitude columns in degrees. JSON arrays are read as features in the same
+// shape as the embedded dataset. All other files are read as GeoJSON feature
+// collections of points. Entries that can't be converted are skipped and
+// returned as rejected rows.
+func LoadFeatures(path string) ([]*pb.Feature, []RejectedRow, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		return parseCSV(data)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return parseFeatures(data)
+	}
+
+	return parseGeoJSON(data)
+}
+
+func parseCSV(data []byte) ([]*pb.Feature, []RejectedRow, error) {
+	var (
+		features = []*pb.Feature{}
+		rejected = []RejectedRow{}
+		reader   = csv.NewReader(bytes.NewReader(data))
+	)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				rejected = append(rejected, RejectedRow{Line: parseErr.Line, Reason: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) != 3 {
+			rejected = append(rejected, RejectedRow{Line: line, Reason: fmt.Sprintf("expected 3 fields (name,lat,lng), got %d", len(record))})
+			continue
+		}
+
+		// skip the optional header
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			continue
+		}
+
+		lat, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			rejected = append(rejected, RejectedRow{Line: line, Reason: fmt.Sprintf("invalid latitude %q", record[1])})
+			continue
+		}
+
+		lng, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil {
+			rejected = append(rejected, RejectedRow{Line: line, Reason: fmt.Sprintf("invalid longitude %q", record[2])})
+			continue
+		}
+
+		point, err := pointFromDegrees(lat, lng)
+		if err != nil {
+			rejected = append(rejected, RejectedRow{Line: line, Reason: err.Error()})
+			continue
+		}
+
+		features = append(features, &pb.Feature{Name: strings.TrimSpace(record[0]), Location: point})
+	}
+
+	return features, rejected, nil
+}
+
+// parseFeatures decodes a JSON array of features in the E7 representation,
+// one feature at a time, so that rejected features can be reported with their
+// line numbers.
+func parseFeatures(data []byte) ([]*pb.Feature, []RejectedRow, error) {
+	var (
+		features = []*pb.Feature{}
+		rejected = []RejectedRow{}
+		decoder  = json.NewDecoder(bytes.NewReader(data))
+	)
+
+	if err := expectDelim(decoder, '['); err != nil {
+		return nil, nil, fmt.Errorf("line %d: %s", lineAt(data, decoder.InputOffset()), err)
+	}
+
+	for decoder.More() {
+		line := lineAt(data, nextValueOffset(data, decoder.InputOffset()))
+
+		var feature pb.Feature
+		if err := decoder.Decode(&feature); err != nil {
+			if _, ok := err.(*json.UnmarshalTypeError); ok {
+				rejected = append(rejected, RejectedRow{Line: line, Reason: err.Error()})
+				continue
+			}
+			return nil, nil, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		if err := checkPoint(feature.Location); err != nil {
+			rejected = append(rejected, RejectedRow{Line: line, Reason: err.Error()})
+			continue
+		}
+
+		features = append(features, &feature)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, fmt.Errorf("line %d: %s", lineAt(data, decoder.InputOffset()), err)
+	}
+
+	return features, rejected, nil
+}
+
+type geoJSONFeature struct {
+	Type     string `json:"type"`
+	Geometry *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// parseGeoJSON decodes the features of a GeoJSON FeatureCollection one at a
+// time, so that rejected features can be reported with their line numbers.
+func parseGeoJSON(data []byte) ([]*pb.Feature, []RejectedRow, error) {
+	var (
+		features = []*pb.Feature{}
+		rejected = []RejectedRow{}
+		decoder  = json.NewDecoder(bytes.NewReader(data))
+	)
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return nil, nil, fmt.Errorf("line %d: %s", lineAt(data, decoder.InputOffset()), err)
+	}
+
+	var collectionType string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %s", lineAt(data, decoder.InputOffset()), err)
+		}
+
+		switch token {
+		case "type":
+			if err := decoder.Decode(&collectionType); err != nil {
+				return nil, nil, fmt.Errorf("line %d: %s", lineAt(data, decoder.InputOffset()), err)
+			}
+
+		case "features":
+			if err := expectDelim(decoder, '['); err != nil {
+				return nil, nil, fmt.Errorf("line %d: %s", lineAt(data, decoder.InputOffset()), err)
+			}
+
+			for decoder.More() {
+				line := lineAt(data, nextValueOffset(data, decoder.InputOffset()))
+
+				var f geoJSONFeature
+				if err := decoder.Decode(&f); err != nil {
+					if _, ok := err.(*json.UnmarshalTypeError); ok {
+						rejected = append(rejected, RejectedRow{Line: line, Reason: err.Error()})
+						continue
+					}
+					return nil, nil, fmt.Errorf("line %d: %s", line, err)
+				}
+
+				feature, err := f.toFeature()
+				if err != nil {
+					rejected = append(rejected, RejectedRow{Line: line, Reason: err.Error()})
+					continue
+				}
+				features = append(features, feature)
+			}
+
+			if _, err := decoder.Token(); err != nil {
+				return nil, nil, fmt.Errorf("line %d: %s", lineAt(data, decoder.InputOffset()), err)
+			}
+
+		default:
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return nil, nil, fmt.Errorf("line %d: %s", lineAt(data, decoder.InputOffset()), err)
+			}
+		}
+	}
+
+	if collectionType != "FeatureCollection" {
+		return nil, nil, fmt.Errorf("unsupported GeoJSON type %q, expected FeatureCollection", collectionType)
+	}
+
+	return features, rejected, nil
+}
+
+func (f geoJSONFeature) toFeature() (*pb.Feature, error) {
+	if f.Type != "Feature" {
+		return nil, fmt.Errorf("unsupported type %q, expected Feature", f.Type)
+	}
+
+	if f.Geometry == nil || f.Geometry.Type != "Point" {
+		return nil, fmt.Errorf("unsupported geometry, expected Point")
+	}
+
+	// GeoJSON positions are ordered as longitude, latitude and an optional
+	// altitude
+	var coordinates []float64
+	if err := json.Unmarshal(f.Geometry.Coordinates, &coordinates); err != nil {
+		return nil, fmt.Errorf("invalid coordinates: %s", err)
+	}
+	if n := len(coordinates); n < 2 || n > 3 {
+		return nil, fmt.Errorf("expected 2 or 3 coordinates, got %d", n)
+	}
+
+	point, err := pointFromDegrees(coordinates[1], coordinates[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var name string
+	if value, ok := f.Properties["name"]; ok {
+		if name, ok = value.(string); !ok {
+			return nil, fmt.Errorf("name property must be a string")
+		}
+	}
+
+	return &pb.Feature{Name: name, Location: point}, nil
+}
+
+// lineAt returns the line number of the given offset.
+func lineAt(data []byte, offset int64) int {
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// nextValueOffset skips the separator and whitespace at the given offset, so
+// that the line number of the next value points at the value itself.
+func nextValueOffset(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && strings.ContainsRune(", \t\r\n", rune(data[offset])) {
+		offset++
+	}
+	return offset
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("expected %q, got %v", delim, token)
+	}
+
+	return nil
+}
+
+// checkPoint ensures that the point is within the valid E7 latitude and
+// longitude ranges.
+func checkPoint(point *pb.Point) error {
+	if point == nil {
+		return fmt.Errorf("missing location")
+	}
+
+	if lat := point.GetLatitude(); lat < -900000000 || lat > 900000000 {
+		return fmt.Errorf("latitude %d out of range", lat)
+	}
+
+	if lng := point.GetLongitude(); lng < minE7 || lng > maxE7 {
+		return fmt.Errorf("longitude %d out of range", lng)
+	}
+
+	return nil
+}
+
+// pointFromDegrees converts the latitude and longitude in degrees into a
+// point in the E7 representation.
+func pointFromDegrees(lat, lng float64) (*pb.Point, error) {
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("latitude %v out of range", lat)
+	}
+
+	if math.IsNaN(lng) || lng < -180 || lng > 180 {
+		return nil, fmt.Errorf("longitude %v out of range", lng)
+	}
+
+	return &pb.Point{
+		Latitude:  int32(math.Round(lat * 1e7)),
+		Longitude: int32(math.Round(lng * 1e7)),
+	}, nil
+}
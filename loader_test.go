@@ -0,0 +1,91 @@
+package routeguide
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeDataset(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDatasets(t *testing.T) {
+	var (
+		valid    = writeDataset(t, "valid.csv", "name,lat,lng\nFlinders Peak,-37.95,144.42\n")
+		rejected = writeDataset(t, "rejected.csv", "name,lat,lng\nFlinders Peak,-37.95,144.42\nBuninyong,north,143.92\n")
+		empty    = writeDataset(t, "empty.csv", "name,lat,lng\n")
+	)
+
+	tests := []struct {
+		name     string
+		paths    []string
+		strict   bool
+		features int
+		expected string
+	}{
+		{
+			name:     "valid",
+			paths:    []string{valid},
+			features: 1,
+		},
+		{
+			name:     "valid strict",
+			paths:    []string{valid},
+			strict:   true,
+			features: 1,
+		},
+		{
+			name:     "rejected rows are skipped",
+			paths:    []string{valid, rejected},
+			features: 2,
+		},
+		{
+			name:     "rejected rows fail a strict load",
+			paths:    []string{valid, rejected},
+			strict:   true,
+			expected: "1 rejected rows, first at line 3",
+		},
+		{
+			name:  "no features",
+			paths: []string{empty},
+		},
+		{
+			name:     "no features fail a strict load",
+			paths:    []string{empty},
+			strict:   true,
+			expected: "no features found",
+		},
+		{
+			name:     "missing file",
+			paths:    []string{filepath.Join(t.TempDir(), "missing.csv")},
+			expected: "missing.csv",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			features, err := LoadDatasets(test.paths, test.strict)
+			if test.expected != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expected) {
+					t.Fatalf("expected an error containing %q, got %v", test.expected, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(features) != test.features {
+				t.Errorf("expected %d features, got %d", test.features, len(features))
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package routeguide
+
+import (
+	"container/heap"
+	"sort"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// nearestSet keeps the k features closest to a point. It is a max-heap on the
+// distance, so that the furthest of the kept features can be evicted in
+// O(log k).
+type nearestSet struct {
+	point       *pb.Point
+	k           int
+	maxDistance float64
+	features    []*pb.NearbyFeature
+}
+
+func newNearestSet(point *pb.Point, k int, maxDistance float64) *nearestSet {
+	return &nearestSet{
+		point:       point,
+		k:           k,
+		maxDistance: maxDistance,
+		features:    make([]*pb.NearbyFeature, 0, k),
+	}
+}
+
+// offer considers the feature for inclusion in the set.
+func (n *nearestSet) offer(feature *pb.Feature) {
+	distance := haversine(n.point, feature.Location)
+	if n.maxDistance > 0 && distance > n.maxDistance {
+		return
+	}
+
+	nearby := &pb.NearbyFeature{Feature: feature, DistanceMeters: distance}
+	if len(n.features) < n.k {
+		heap.Push(n, nearby)
+		return
+	}
+
+	if nearer(nearby, n.features[0]) {
+		n.features[0] = nearby
+		heap.Fix(n, 0)
+	}
+}
+
+// full returns true if the set has k features, and none of the features
+// further than the given distance can displace them.
+func (n *nearestSet) full(distance float64) bool {
+	return len(n.features) == n.k && n.features[0].DistanceMeters <= distance
+}
+
+// sorted returns the features in the set, ordered by their distance.
+func (n *nearestSet) sorted() []*pb.NearbyFeature {
+	sorted := make([]*pb.NearbyFeature, len(n.features))
+	copy(sorted, n.features)
+	sort.Slice(sorted, func(i, j int) bool {
+		return nearer(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+func (n *nearestSet) Len() int { return len(n.features) }
+
+func (n *nearestSet) Less(i, j int) bool { return nearer(n.features[j], n.features[i]) }
+
+func (n *nearestSet) Swap(i, j int) { n.features[i], n.features[j] = n.features[j], n.features[i] }
+
+func (n *nearestSet) Push(x interface{}) { n.features = append(n.features, x.(*pb.NearbyFeature)) }
+
+func (n *nearestSet) Pop() interface{} {
+	last := n.features[len(n.features)-1]
+	n.features = n.features[:len(n.features)-1]
+	return last
+}
+
+// nearer orders features by their distance, breaking ties by their location so
+// that the results are deterministic.
+func nearer(a, b *pb.NearbyFeature) bool {
+	if a.DistanceMeters != b.DistanceMeters {
+		return a.DistanceMeters < b.DistanceMeters
+	}
+
+	if a.Feature.Location.Latitude != b.Feature.Location.Latitude {
+		return a.Feature.Location.Latitude < b.Feature.Location.Latitude
+	}
+	return a.Feature.Location.Longitude < b.Feature.Location.Longitude
+}
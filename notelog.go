@@ -0,0 +1,605 @@
+package routeguide
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// SyncPolicy determines when the appended notes are flushed to disk.
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes every note before it's delivered. No acknowledged
+	// note is lost, but every note waits for the disk.
+	SyncAlways SyncPolicy = iota
+
+	// SyncInterval flushes the notes periodically. The notes appended since
+	// the last flush can be lost if the host crashes.
+	SyncInterval
+
+	// SyncNever leaves the flushing to the operating system.
+	SyncNever
+
+	syncAlwaysStr   = "always"
+	syncIntervalStr = "interval"
+	syncNeverStr    = "never"
+)
+
+const (
+	// defaultMaxSegmentBytes is the size at which the active segment is
+	// sealed, and a new segment is started.
+	defaultMaxSegmentBytes = 8 << 20
+
+	// compactionSegments is the number of segments sealed since the last
+	// snapshot that triggers a compaction.
+	compactionSegments = 4
+
+	segmentExt  = ".log"
+	snapshotExt = ".snapshot"
+	tempExt     = ".tmp"
+
+	// recordHeaderSize is the size of the length and checksum that precede
+	// every record.
+	recordHeaderSize = 8
+
+	// maxRecordBytes is the largest payload of a record. Longer lengths are
+	// read as corrupt, rather than allocated.
+	maxRecordBytes = defaultMaxSegmentBytes
+)
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errCorruptRecord = errors.New("corrupt record")
+)
+
+// ParseSyncPolicy returns the sync policy with the given name.
+func ParseSyncPolicy(p string) (SyncPolicy, error) {
+	switch strings.ToLower(p) {
+	case syncAlwaysStr:
+		return SyncAlways, nil
+	case syncIntervalStr:
+		return SyncInterval, nil
+	case syncNeverStr:
+		return SyncNever, nil
+	}
+
+	return -1, fmt.Errorf("Unsupported sync policy: %s", p)
+}
+
+func (p SyncPolicy) String() string {
+	switch p {
+	case SyncAlways:
+		return syncAlwaysStr
+	case SyncInterval:
+		return syncIntervalStr
+	case SyncNever:
+		return syncNeverStr
+	default:
+		return unknownStr
+	}
+}
+
+// NoteLog is an append-only log of route notes on local disk, so that the
+// RouteChat history survives restarts. The log is split into segments. When
+// enough segments are sealed, the log is compacted into a snapshot of the
+// retained history, and the segments it covers are deleted.
+type NoteLog struct {
+	dir        string
+	policy     SyncPolicy
+	maxSegment int64
+	active     *os.File
+	activeSeq  int64
+	activeSize int64
+	sealed     int
+	dirty      bool
+	compacting bool
+	compaction sync.WaitGroup
+	stop       chan struct{}
+	done       chan struct{}
+	mutex      sync.Mutex
+
+	// closeOnce closes the log once, and closeErr is the error it returned
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// loggedNote is a note in the log, and the time it was sent at.
+type loggedNote struct {
+	note   *pb.RouteNote
+	sentAt time.Time
+}
+
+// OpenNoteLog opens the note log in the given directory, creating it if it
+// doesn't exist. With the SyncInterval policy, the log is flushed at the given
+// interval.
+func OpenNoteLog(dir string, policy SyncPolicy, interval time.Duration) (*NoteLog, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	l := &NoteLog{
+		dir:        dir,
+		policy:     policy,
+		maxSegment: defaultMaxSegmentBytes,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	if policy == SyncInterval {
+		if interval <= 0 {
+			return nil, fmt.Errorf("sync interval must be positive")
+		}
+		go l.syncEvery(interval)
+	} else {
+		close(l.done)
+	}
+
+	return l, nil
+}
+
+// Replay calls fn for every note in the log, in the order they were
+// appended. A torn record at the end of the last segment, left by a crash, is
+// truncated. Replay must be called once, before any note is appended.
+func (l *NoteLog) Replay(fn func(note *pb.RouteNote, sentAt time.Time)) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	snapshot, segments, err := l.files()
+	if err != nil {
+		return err
+	}
+
+	if snapshot > 0 {
+		if _, err := replayFile(l.path(snapshot, snapshotExt), fn); err != nil {
+			return err
+		}
+	}
+
+	for i, seq := range segments {
+		valid, err := replayFile(l.path(seq, segmentExt), fn)
+		if err == nil {
+			continue
+		}
+		if err != errCorruptRecord {
+			return err
+		}
+
+		if i < len(segments)-1 {
+			log.Printf("[notelog] (error) segment %d is corrupt after %d bytes, skipping the rest of it", seq, valid)
+			continue
+		}
+
+		log.Printf("[notelog] truncating torn record at the end of segment %d, after %d bytes", seq, valid)
+		if err := os.Truncate(l.path(seq, segmentExt), valid); err != nil {
+			return err
+		}
+	}
+
+	// appends continue in the last segment
+	l.activeSeq = snapshot + 1
+	if len(segments) > 0 {
+		l.activeSeq = segments[len(segments)-1]
+		l.sealed = len(segments) - 1
+	}
+	return l.openActive()
+}
+
+// Append appends the note to the active segment. With the SyncAlways policy,
+// the note is flushed to disk before Append returns. It returns true if
+// enough segments are sealed for the log to be compacted.
+func (l *NoteLog) Append(note *pb.RouteNote, sentAt time.Time) (bool, error) {
+	payload, err := encodeRecord(note, sentAt)
+	if err != nil {
+		return false, err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.active == nil {
+		return false, fmt.Errorf("note log is closed")
+	}
+
+	if l.activeSize > 0 && l.activeSize+int64(len(payload)) > l.maxSegment {
+		if err := l.rotate(); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := l.active.Write(payload); err != nil {
+		return false, err
+	}
+	l.activeSize += int64(len(payload))
+	l.dirty = true
+
+	if l.policy == SyncAlways {
+		if err := l.sync(); err != nil {
+			return false, err
+		}
+	}
+
+	return l.sealed >= compactionSegments && !l.compacting, nil
+}
+
+// Seal seals the active segment, returning the sequence number of the last
+// sealed segment. The notes appended up to the seal are covered by a
+// compaction through that sequence number, which must follow every Seal.
+func (l *NoteLog) Seal() (int64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	through := l.activeSeq
+	if err := l.rotate(); err != nil {
+		return 0, err
+	}
+	l.compacting = true
+	l.compaction.Add(1)
+
+	return through, nil
+}
+
+// Compact replaces the segments up to and including the given sequence number
+// with a snapshot of the given notes, which must be the retained history of
+// those segments. The snapshot is written and flushed before the segments are
+// deleted, so that a crash never loses the retained notes.
+func (l *NoteLog) Compact(through int64, notes []loggedNote) error {
+	defer func() {
+		l.mutex.Lock()
+		l.compacting = false
+		l.mutex.Unlock()
+		l.compaction.Done()
+	}()
+
+	temp := l.path(through, snapshotExt+tempExt)
+	if err := writeSnapshot(temp, notes); err != nil {
+		os.Remove(temp)
+		return err
+	}
+
+	// the rename commits the snapshot, the files it replaces are ignored by
+	// subsequent replays even if they can't be deleted
+	if err := os.Rename(temp, l.path(through, snapshotExt)); err != nil {
+		return err
+	}
+	if err := syncDir(l.dir); err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	l.sealed = int(l.activeSeq - through - 1)
+	l.mutex.Unlock()
+
+	names, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range names {
+		seq, ext, ok := parseLogFile(info.Name())
+		if !ok || seq > through || (seq == through && ext == snapshotExt) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(l.dir, info.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	log.Printf("[notelog] compacted the segments through %d into a snapshot of %d notes", through, len(notes))
+	return nil
+}
+
+// Close waits for the compaction in progress, if any, then flushes and closes
+// the log. Later calls return the error of the first one.
+func (l *NoteLog) Close() error {
+	l.closeOnce.Do(func() {
+		l.closeErr = l.close()
+	})
+	return l.closeErr
+}
+
+func (l *NoteLog) close() error {
+	close(l.stop)
+	<-l.done
+	l.compaction.Wait()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.active == nil {
+		return nil
+	}
+
+	err := l.sync()
+	if closeErr := l.active.Close(); err == nil {
+		err = closeErr
+	}
+	l.active = nil
+
+	return err
+}
+
+// syncEvery flushes the log at the given interval, until the log is closed.
+func (l *NoteLog) syncEvery(interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mutex.Lock()
+			if l.active != nil {
+				if err := l.sync(); err != nil {
+					log.Printf("[notelog] (error) %s", err)
+				}
+			}
+			l.mutex.Unlock()
+		}
+	}
+}
+
+// sync flushes the active segment if notes were appended since the last
+// flush. The caller must hold the mutex.
+func (l *NoteLog) sync() error {
+	if !l.dirty {
+		return nil
+	}
+
+	if err := l.active.Sync(); err != nil {
+		return err
+	}
+	l.dirty = false
+
+	return nil
+}
+
+// rotate seals the active segment, and starts a new one. The caller must hold
+// the mutex.
+func (l *NoteLog) rotate() error {
+	if err := l.sync(); err != nil {
+		return err
+	}
+	if err := l.active.Close(); err != nil {
+		return err
+	}
+
+	l.activeSeq++
+	l.sealed++
+	return l.openActive()
+}
+
+// openActive opens the active segment for appending. The caller must hold the
+// mutex.
+func (l *NoteLog) openActive() error {
+	file, err := os.OpenFile(l.path(l.activeSeq, segmentExt), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.active, l.activeSize = file, info.Size()
+	return syncDir(l.dir)
+}
+
+// files returns the sequence number of the latest snapshot, and the sequence
+// numbers of the segments after it, in order. Temporary files and the files
+// replaced by the snapshot are deleted.
+func (l *NoteLog) files() (int64, []int64, error) {
+	infos, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var (
+		snapshot int64
+		segments = []int64{}
+	)
+	for _, info := range infos {
+		seq, ext, ok := parseLogFile(info.Name())
+		if !ok {
+			continue
+		}
+
+		switch ext {
+		case snapshotExt:
+			if seq > snapshot {
+				snapshot = seq
+			}
+		case segmentExt:
+			segments = append(segments, seq)
+		}
+	}
+
+	for _, info := range infos {
+		seq, ext, ok := parseLogFile(info.Name())
+		if strings.HasSuffix(info.Name(), tempExt) || (ok && seq <= snapshot && !(seq == snapshot && ext == snapshotExt)) {
+			if err := os.Remove(filepath.Join(l.dir, info.Name())); err != nil {
+				return 0, nil, err
+			}
+		}
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	first := sort.Search(len(segments), func(i int) bool { return segments[i] > snapshot })
+
+	return snapshot, segments[first:], nil
+}
+
+func (l *NoteLog) path(seq int64, ext string) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d%s", seq, ext))
+}
+
+// parseLogFile returns the sequence number and the extension of a segment or
+// snapshot file name.
+func parseLogFile(name string) (int64, string, bool) {
+	ext := filepath.Ext(name)
+	if ext != segmentExt && ext != snapshotExt {
+		return 0, "", false
+	}
+
+	seq, err := strconv.ParseInt(strings.TrimSuffix(name, ext), 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return seq, ext, true
+}
+
+// replayFile calls fn for every record in the file. If a record is torn or
+// corrupt, errCorruptRecord is returned along with the size of the valid
+// records before it.
+func replayFile(path string, fn func(*pb.RouteNote, time.Time)) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		size   = info.Size()
+		reader = bufio.NewReader(file)
+		header = make([]byte, recordHeaderSize)
+		valid  int64
+	)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return valid, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return valid, errCorruptRecord
+			}
+			return valid, err
+		}
+
+		var (
+			length   = binary.BigEndian.Uint32(header[:4])
+			checksum = binary.BigEndian.Uint32(header[4:])
+		)
+		if length > maxRecordBytes || int64(length) > size-valid-recordHeaderSize {
+			return valid, errCorruptRecord
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return valid, errCorruptRecord
+			}
+			return valid, err
+		}
+
+		if crc32.Checksum(payload, crcTable) != checksum {
+			return valid, errCorruptRecord
+		}
+
+		note, sentAt, err := decodeRecord(payload)
+		if err != nil {
+			return valid, errCorruptRecord
+		}
+
+		fn(note, sentAt)
+		valid += int64(recordHeaderSize + len(payload))
+	}
+}
+
+// writeSnapshot writes the notes into a new file, and flushes it.
+func writeSnapshot(path string, notes []loggedNote) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, logged := range notes {
+		record, err := encodeRecord(logged.note, logged.sentAt)
+		if err != nil {
+			return err
+		}
+
+		if _, err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// encodeRecord encodes the note as a record of its length, its checksum, the
+// time it was sent at in Unix nanoseconds, and the note itself.
+func encodeRecord(note *pb.RouteNote, sentAt time.Time) ([]byte, error) {
+	data, err := proto.Marshal(note)
+	if err != nil {
+		return nil, err
+	}
+
+	if 8+len(data) > maxRecordBytes {
+		return nil, fmt.Errorf("note of %d bytes is too large to log", len(data))
+	}
+
+	record := make([]byte, recordHeaderSize+8+len(data))
+	payload := record[recordHeaderSize:]
+	binary.BigEndian.PutUint64(payload[:8], uint64(sentAt.UnixNano()))
+	copy(payload[8:], data)
+
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, crcTable))
+	return record, nil
+}
+
+func decodeRecord(payload []byte) (*pb.RouteNote, time.Time, error) {
+	if len(payload) < 8 {
+		return nil, time.Time{}, errCorruptRecord
+	}
+
+	note := &pb.RouteNote{}
+	if err := proto.Unmarshal(payload[8:], note); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return note, time.Unix(0, int64(binary.BigEndian.Uint64(payload[:8]))), nil
+}
+
+// syncDir flushes the directory, so that the files created or renamed in it
+// survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
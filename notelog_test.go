@@ -0,0 +1,287 @@
+package routeguide
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// openTestLog opens the note log in the directory, returning the messages of
+// the replayed notes.
+func openTestLog(t *testing.T, dir string) (*NoteLog, []string) {
+	t.Helper()
+
+	l, err := OpenNoteLog(dir, SyncNever, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []string{}
+	if err := l.Replay(func(note *pb.RouteNote, sentAt time.Time) {
+		messages = append(messages, note.Message)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return l, messages
+}
+
+func appendTestNotes(t *testing.T, l *NoteLog, messages ...string) {
+	t.Helper()
+
+	for _, message := range messages {
+		note := &pb.RouteNote{Location: &pb.Point{Latitude: 1, Longitude: 2}, Message: message}
+		if _, err := l.Append(note, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func appendToFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func recordHeader(length, checksum uint32) []byte {
+	header := make([]byte, recordHeaderSize)
+	binary.BigEndian.PutUint32(header[:4], length)
+	binary.BigEndian.PutUint32(header[4:], checksum)
+	return header
+}
+
+func TestNoteLogRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, path string)
+	}{
+		{
+			name: "torn header",
+			corrupt: func(t *testing.T, path string) {
+				appendToFile(t, path, []byte{0, 0, 0})
+			},
+		},
+		{
+			name: "torn payload",
+			corrupt: func(t *testing.T, path string) {
+				appendToFile(t, path, append(recordHeader(100, 0), make([]byte, 10)...))
+			},
+		},
+		{
+			name: "length beyond the segment size",
+			corrupt: func(t *testing.T, path string) {
+				appendToFile(t, path, recordHeader(0xffffffff, 0))
+			},
+		},
+		{
+			name: "checksum mismatch",
+			corrupt: func(t *testing.T, path string) {
+				appendToFile(t, path, append(recordHeader(16, 0), make([]byte, 16)...))
+			},
+		},
+		{
+			name: "flipped bit in the last record",
+			corrupt: func(t *testing.T, path string) {
+				l, _ := openTestLog(t, filepath.Dir(path))
+				appendTestNotes(t, l, "corrupt")
+				if err := l.Close(); err != nil {
+					t.Fatal(err)
+				}
+
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data[len(data)-1] ^= 1
+				if err := ioutil.WriteFile(path, data, 0600); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			l, _ := openTestLog(t, dir)
+			appendTestNotes(t, l, "a", "b")
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			path := l.path(1, segmentExt)
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.corrupt(t, path)
+
+			l, messages := openTestLog(t, dir)
+			if expected := []string{"a", "b"}; !reflect.DeepEqual(messages, expected) {
+				t.Errorf("expected the notes %v to be replayed, got %v", expected, messages)
+			}
+
+			truncated, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if truncated.Size() != info.Size() {
+				t.Errorf("expected the segment to be truncated to %d bytes, got %d", info.Size(), truncated.Size())
+			}
+
+			// appends continue after the valid records
+			appendTestNotes(t, l, "c")
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			l, messages = openTestLog(t, dir)
+			defer l.Close()
+			if expected := []string{"a", "b", "c"}; !reflect.DeepEqual(messages, expected) {
+				t.Errorf("expected the notes %v to be replayed, got %v", expected, messages)
+			}
+		})
+	}
+}
+
+func TestNoteLogCorruptSealedSegment(t *testing.T) {
+	dir := t.TempDir()
+
+	l, _ := openTestLog(t, dir)
+	appendTestNotes(t, l, "a")
+	l.mutex.Lock()
+	err := l.rotate()
+	l.mutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendTestNotes(t, l, "b")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the rest of a corrupt sealed segment is skipped, and it isn't truncated
+	appendToFile(t, l.path(1, segmentExt), append(recordHeader(16, 0), make([]byte, 16)...))
+
+	l, messages := openTestLog(t, dir)
+	defer l.Close()
+	if expected := []string{"a", "b"}; !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected the notes %v to be replayed, got %v", expected, messages)
+	}
+}
+
+func TestNoteLogRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	l, _ := openTestLog(t, dir)
+	record, err := encodeRecord(&pb.RouteNote{Location: &pb.Point{Latitude: 1, Longitude: 2}, Message: "00"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every segment holds two records
+	l.maxSegment = int64(2 * len(record))
+
+	messages := []string{}
+	compact := false
+	for i := 0; i < 2*(compactionSegments+1); i++ {
+		message := fmt.Sprintf("%02d", i)
+		messages = append(messages, message)
+
+		compact, err = l.Append(&pb.RouteNote{Location: &pb.Point{Latitude: 1, Longitude: 2}, Message: message}, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !compact {
+		t.Errorf("expected a compaction after %d sealed segments", compactionSegments)
+	}
+
+	_, segments, err := l.files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int64{1, 2, 3, 4, 5}; !reflect.DeepEqual(segments, expected) {
+		t.Errorf("expected the segments %v, got %v", expected, segments)
+	}
+	for _, seq := range segments {
+		info, err := os.Stat(l.path(seq, segmentExt))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != l.maxSegment {
+			t.Errorf("segment %d: expected %d bytes, got %d", seq, l.maxSegment, info.Size())
+		}
+	}
+
+	// the compaction keeps the last note of the sealed segments
+	through, err := l.Seal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := messages[len(messages)-1]
+	if err := l.Compact(through, []loggedNote{{
+		note:   &pb.RouteNote{Location: &pb.Point{Latitude: 1, Longitude: 2}, Message: last},
+		sentAt: time.Now(),
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	appendTestNotes(t, l, "after")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, segments, err := l.files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot != through || !reflect.DeepEqual(segments, []int64{through + 1}) {
+		t.Errorf("expected the snapshot %d and the segment %d, got %d and %v", through, through+1, snapshot, segments)
+	}
+
+	l, replayed := openTestLog(t, dir)
+	defer l.Close()
+	if expected := []string{last, "after"}; !reflect.DeepEqual(replayed, expected) {
+		t.Errorf("expected the notes %v to be replayed, got %v", expected, replayed)
+	}
+}
+
+func TestNoteLogCloseTwice(t *testing.T) {
+	l, _ := openTestLog(t, t.TempDir())
+	appendTestNotes(t, l, "a")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("expected the second close to succeed, got %v", err)
+	}
+
+	// the error of the first close is returned by the later ones
+	l, _ = openTestLog(t, t.TempDir())
+	appendTestNotes(t, l, "a")
+	if err := l.active.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	err := l.Close()
+	if err == nil {
+		t.Fatal("expected the close of a closed segment to fail")
+	}
+	if again := l.Close(); again != err {
+		t.Errorf("expected the second close to return %v, got %v", err, again)
+	}
+}
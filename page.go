@@ -0,0 +1,216 @@
+package routeguide
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+var errInvalidPageToken = errors.New("invalid page token")
+
+// pageCursor is the position of the last feature of a page, encoded into the
+// page token. Pages are resumed after the cursor, rather than at an offset, so
+// that features created or deleted between pages don't shift the results.
+type pageCursor struct {
+	Query     uint64  `json:"q"`
+	Name      string  `json:"n,omitempty"`
+	Distance  float64 `json:"d,omitempty"`
+	Latitude  int32   `json:"lat"`
+	Longitude int32   `json:"lng"`
+}
+
+// featurePager lists pages of features according to a ListFeaturesPageRequest.
+type featurePager struct {
+	req      *pb.ListFeaturesPageRequest
+	regex    *regexp.Regexp
+	pageSize int
+	query    uint64
+}
+
+func newFeaturePager(req *pb.ListFeaturesPageRequest) (*featurePager, error) {
+	p := &featurePager{
+		req:      req,
+		pageSize: int(req.PageSize),
+		query:    fingerprint(req),
+	}
+
+	if p.pageSize < 0 {
+		return nil, fmt.Errorf("page size must not be negative")
+	}
+	if p.pageSize == 0 {
+		p.pageSize = defaultPageSize
+	}
+	if p.pageSize > maxPageSize {
+		p.pageSize = maxPageSize
+	}
+
+	if req.OrderBy == pb.ListFeaturesPageRequest_DISTANCE {
+		if err := checkPoint(req.Reference); err != nil {
+			return nil, fmt.Errorf("reference point: %s", err)
+		}
+	}
+
+	if req.NameRegex != "" {
+		regex, err := regexp.Compile(req.NameRegex)
+		if err != nil {
+			return nil, fmt.Errorf("name regex: %s", err)
+		}
+		p.regex = regex
+	}
+
+	return p, nil
+}
+
+// accept returns true if the feature passes the filters of the request.
+func (p *featurePager) accept(feature *pb.Feature) bool {
+	if p.req.ExcludeUnnamed && feature.Name == "" {
+		return false
+	}
+
+	if !strings.HasPrefix(feature.Name, p.req.NamePrefix) {
+		return false
+	}
+
+	if p.regex != nil && !p.regex.MatchString(feature.Name) {
+		return false
+	}
+
+	return true
+}
+
+// page orders the candidate features and returns the page that follows the
+// request's page token, along with the token of the next page.
+func (p *featurePager) page(candidates []*pb.Feature) ([]*pb.Feature, string, error) {
+	cursors := make([]pageCursor, len(candidates))
+	for i, feature := range candidates {
+		cursors[i] = p.cursorOf(feature)
+	}
+
+	sort.Sort(byCursor{features: candidates, cursors: cursors, order: p.req.OrderBy})
+
+	start := 0
+	if p.req.PageToken != "" {
+		after, err := p.decode(p.req.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+
+		start = sort.Search(len(cursors), func(i int) bool {
+			return lessCursor(after, cursors[i], p.req.OrderBy)
+		})
+	}
+
+	end := start + p.pageSize
+	if end >= len(candidates) {
+		return candidates[start:], "", nil
+	}
+
+	token, err := p.encode(cursors[end-1])
+	if err != nil {
+		return nil, "", err
+	}
+
+	return candidates[start:end], token, nil
+}
+
+func (p *featurePager) cursorOf(feature *pb.Feature) pageCursor {
+	cursor := pageCursor{
+		Query:     p.query,
+		Latitude:  feature.Location.GetLatitude(),
+		Longitude: feature.Location.GetLongitude(),
+	}
+
+	switch p.req.OrderBy {
+	case pb.ListFeaturesPageRequest_NAME:
+		cursor.Name = feature.Name
+	case pb.ListFeaturesPageRequest_DISTANCE:
+		cursor.Distance = haversine(p.req.Reference, feature.Location)
+	}
+
+	return cursor
+}
+
+func (p *featurePager) encode(cursor pageCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func (p *featurePager) decode(token string) (pageCursor, error) {
+	var cursor pageCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, errInvalidPageToken
+	}
+
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, errInvalidPageToken
+	}
+
+	// a token can only be used with the request it was issued for
+	if cursor.Query != p.query {
+		return cursor, fmt.Errorf("%s: the request doesn't match the request of the previous page", errInvalidPageToken)
+	}
+
+	return cursor, nil
+}
+
+// fingerprint hashes the fields of the request that determine the order and
+// the contents of the pages.
+func fingerprint(req *pb.ListFeaturesPageRequest) uint64 {
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%v|%d|%v|%q|%q|%v",
+		req.Rectangle, req.OrderBy, req.Reference, req.NamePrefix, req.NameRegex, req.ExcludeUnnamed)
+	return hash.Sum64()
+}
+
+type byCursor struct {
+	features []*pb.Feature
+	cursors  []pageCursor
+	order    pb.ListFeaturesPageRequest_Order
+}
+
+func (b byCursor) Len() int { return len(b.features) }
+
+func (b byCursor) Less(i, j int) bool { return lessCursor(b.cursors[i], b.cursors[j], b.order) }
+
+func (b byCursor) Swap(i, j int) {
+	b.features[i], b.features[j] = b.features[j], b.features[i]
+	b.cursors[i], b.cursors[j] = b.cursors[j], b.cursors[i]
+}
+
+// lessCursor orders the cursors by the requested order, breaking ties by
+// location so that every feature has a unique position.
+func lessCursor(a, b pageCursor, order pb.ListFeaturesPageRequest_Order) bool {
+	switch order {
+	case pb.ListFeaturesPageRequest_NAME:
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+	case pb.ListFeaturesPageRequest_DISTANCE:
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+	}
+
+	if a.Latitude != b.Latitude {
+		return a.Latitude < b.Latitude
+	}
+	return a.Longitude < b.Longitude
+}
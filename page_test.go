@@ -0,0 +1,161 @@
+package routeguide
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+func pageFeatures(n int) []*pb.Feature {
+	features := make([]*pb.Feature, n)
+	for i := range features {
+		features[i] = &pb.Feature{
+			Name:     fmt.Sprintf("feature #%02d", n-i),
+			Location: &pb.Point{Latitude: 400000000 + int32(i)*1000, Longitude: -740000000 + int32(i%3)*1000},
+		}
+	}
+	return features
+}
+
+func TestFeaturePagerRoundTrip(t *testing.T) {
+	orders := []pb.ListFeaturesPageRequest_Order{
+		pb.ListFeaturesPageRequest_LOCATION,
+		pb.ListFeaturesPageRequest_NAME,
+		pb.ListFeaturesPageRequest_DISTANCE,
+	}
+
+	for _, order := range orders {
+		t.Run(order.String(), func(t *testing.T) {
+			var (
+				features = pageFeatures(25)
+				seen     = map[string]bool{}
+				req      = &pb.ListFeaturesPageRequest{
+					PageSize:  10,
+					OrderBy:   order,
+					Reference: &pb.Point{Latitude: 400000000, Longitude: -740000000},
+				}
+				previous *pb.Feature
+				pages    int
+			)
+
+			for {
+				pager, err := newFeaturePager(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// the candidates are passed in a different order every time
+				candidates := append([]*pb.Feature{}, features[pages:]...)
+				candidates = append(candidates, features[:pages]...)
+
+				page, token, err := pager.page(candidates)
+				if err != nil {
+					t.Fatal(err)
+				}
+				pages++
+
+				for _, feature := range page {
+					if seen[feature.Name] {
+						t.Fatalf("page %d: %s was listed twice", pages, feature.Name)
+					}
+					seen[feature.Name] = true
+
+					if previous != nil && !lessCursor(pager.cursorOf(previous), pager.cursorOf(feature), order) {
+						t.Errorf("page %d: %s is listed after %s", pages, feature.Name, previous.Name)
+					}
+					previous = feature
+				}
+
+				if token == "" {
+					break
+				}
+				req.PageToken = token
+			}
+
+			if pages != 3 {
+				t.Errorf("expected 3 pages, got %d", pages)
+			}
+			if len(seen) != len(features) {
+				t.Errorf("expected %d features, got %d", len(features), len(seen))
+			}
+		})
+	}
+}
+
+func TestFeaturePagerRejectsTokens(t *testing.T) {
+	var (
+		features = pageFeatures(25)
+		req      = &pb.ListFeaturesPageRequest{PageSize: 10, NamePrefix: "feature"}
+	)
+
+	pager, err := newFeaturePager(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, token, err := pager.page(features)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		req   *pb.ListFeaturesPageRequest
+		token string
+	}{
+		{
+			name:  "other request",
+			req:   &pb.ListFeaturesPageRequest{PageSize: 10, NamePrefix: "other"},
+			token: token,
+		},
+		{
+			name:  "other order",
+			req:   &pb.ListFeaturesPageRequest{PageSize: 10, NamePrefix: "feature", OrderBy: pb.ListFeaturesPageRequest_NAME},
+			token: token,
+		},
+		{
+			name:  "tampered query",
+			req:   req,
+			token: base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(data), `"q":`, `"q":1`, 1))),
+		},
+		{
+			name:  "not base64",
+			req:   req,
+			token: "!" + token,
+		},
+		{
+			name:  "truncated",
+			req:   req,
+			token: token[:len(token)/2],
+		},
+		{
+			name:  "not a cursor",
+			req:   req,
+			token: base64.RawURLEncoding.EncodeToString([]byte(`["cursor"]`)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := *test.req
+			req.PageToken = test.token
+
+			pager, err := newFeaturePager(&req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = pager.page(features)
+			if err == nil || !strings.HasPrefix(err.Error(), errInvalidPageToken.Error()) {
+				t.Errorf("expected an %q error, got %v", errInvalidPageToken, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package routeguide
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultPolicyReloadInterval is the interval at which the policy file is
+// polled for changes.
+const DefaultPolicyReloadInterval = 10 * time.Second
+
+// The kinds of caller identities, which prefix the identities in the policy,
+// e.g. san:spiffe://routeguide/client.
+const (
+	identityHeader  = "header"
+	identitySAN     = "san"
+	identitySubject = "sub"
+
+	// anyIdentity matches every caller, and anyMethod matches every method.
+	anyIdentity = "*"
+	anyMethod   = "*"
+)
+
+// policy is the parsed policy file. A caller may call a method if any rule
+// allows any of its identities to call it. Everything else is denied.
+type policy struct {
+	Rules []*policyRule `yaml:"rules"`
+}
+
+// policyRule allows the identities to call the methods. A method is either a
+// full method name, e.g. /routeguideproto.RouteGuide/GetFeature, all the
+// methods of a service, e.g. /routeguideproto.RouteGuide/*, or *.
+type policyRule struct {
+	Identities []string `yaml:"identities"`
+	Methods    []string `yaml:"methods"`
+}
+
+// allows returns true if the rule allows any of the identities to call the
+// method.
+func (r *policyRule) allows(identities []string, method string) bool {
+	matched := false
+	for _, m := range r.Methods {
+		if m == anyMethod || m == method || (strings.HasSuffix(m, "/*") && strings.HasPrefix(method, strings.TrimSuffix(m, "*"))) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+
+	for _, allowed := range r.Identities {
+		if allowed == anyIdentity {
+			return true
+		}
+		for _, identity := range identities {
+			if identity == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validate checks that the rule names its identities and methods in a form
+// that can match.
+func (r *policyRule) validate() error {
+	if len(r.Identities) == 0 {
+		return fmt.Errorf("no identities")
+	}
+	if len(r.Methods) == 0 {
+		return fmt.Errorf("no methods")
+	}
+
+	for _, identity := range r.Identities {
+		if identity == anyIdentity {
+			continue
+		}
+
+		kind := strings.SplitN(identity, ":", 2)[0]
+		if !strings.Contains(identity, ":") || (kind != identityHeader && kind != identitySAN && kind != identitySubject) {
+			return fmt.Errorf("identity %q must be *, or prefixed with %s:, %s: or %s:", identity, identityHeader, identitySAN, identitySubject)
+		}
+	}
+
+	for _, method := range r.Methods {
+		if method != anyMethod && (!strings.HasPrefix(method, "/") || strings.Count(method, "/") != 2) {
+			return fmt.Errorf("method %q must be *, /<service>/<method> or /<service>/*", method)
+		}
+	}
+	return nil
+}
+
+// PolicyEnforcer authorizes the calls of the methods with the rules of a
+// policy file. The file is reloaded whenever it changes on disk, so that the
+// rules can be changed without a restart.
+type PolicyEnforcer struct {
+	file    string
+	header  string
+	watcher *fileWatcher
+
+	mutex  sync.RWMutex
+	policy *policy
+}
+
+// NewPolicyEnforcer loads the YAML or JSON policy file. The callers are
+// identified by the given metadata header, by the SANs of their verified
+// client certificates, and by the subjects of their bearer tokens. The file is
+// polled for changes at the given interval. A zero interval disables polling.
+func NewPolicyEnforcer(file, header string, interval time.Duration) (*PolicyEnforcer, error) {
+	e := &PolicyEnforcer{
+		file:   file,
+		header: strings.ToLower(header),
+	}
+	e.watcher = newFileWatcher("policy", []string{file}, interval, e.Reload)
+
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Run reloads the policy file whenever a signal is received on the trigger
+// channel, or whenever the file changes. It returns when the stop channel is
+// closed.
+func (e *PolicyEnforcer) Run(trigger <-chan os.Signal, stop <-chan struct{}) {
+	e.watcher.Run(trigger, stop)
+}
+
+// Reload reads the rules of the policy file. If the file can't be read or
+// holds an invalid rule, the previous rules are kept.
+func (e *PolicyEnforcer) Reload() error {
+	data, err := ioutil.ReadFile(e.file)
+	if err != nil {
+		return err
+	}
+
+	loaded := &policy{}
+	if err := yaml.UnmarshalStrict(data, loaded); err != nil {
+		return fmt.Errorf("%s: %s", e.file, err)
+	}
+
+	for i, rule := range loaded.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("%s: rule %d: %s", e.file, i, err)
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.policy = loaded
+	log.Printf("[policy] loaded %d rules from %s", len(loaded.Rules), e.file)
+	return nil
+}
+
+// Allowed returns true if any of the identities may call the method.
+func (e *PolicyEnforcer) Allowed(identities []string, method string) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	for _, rule := range e.policy.Rules {
+		if rule.allows(identities, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnaryInterceptor rejects the unary requests that the policy doesn't allow,
+// except for the exempt methods.
+func (e *PolicyEnforcer) UnaryInterceptor(exempt ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !contains(exempt, info.FullMethod) {
+			if err := e.authorize(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor rejects the streams that the policy doesn't allow, except
+// for the exempt methods.
+func (e *PolicyEnforcer) StreamInterceptor(exempt ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !contains(exempt, info.FullMethod) {
+			if err := e.authorize(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (e *PolicyEnforcer) authorize(ctx context.Context, method string) error {
+	identities := e.identities(ctx)
+	if e.Allowed(identities, method) {
+		return nil
+	}
+
+	log.Printf("[policy] (denied) %s: %v", method, identities)
+	return status.Errorf(codes.PermissionDenied, "%s isn't allowed for %v", method, identities)
+}
+
+// identities returns the identities of the caller, prefixed with their kinds.
+func (e *PolicyEnforcer) identities(ctx context.Context) []string {
+	identities := []string{}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && e.header != "" {
+		for _, value := range md.Get(e.header) {
+			identities = append(identities, identityHeader+":"+value)
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+			cert := info.State.PeerCertificates[0]
+			for _, uri := range cert.URIs {
+				identities = append(identities, identitySAN+":"+uri.String())
+			}
+			for _, name := range cert.DNSNames {
+				identities = append(identities, identitySAN+":"+name)
+			}
+			for _, email := range cert.EmailAddresses {
+				identities = append(identities, identitySAN+":"+email)
+			}
+		}
+	}
+
+	if claims, ok := ClaimsFromContext(ctx); ok && claims.Subject != "" {
+		identities = append(identities, identitySubject+":"+claims.Subject)
+	}
+	return identities
+}
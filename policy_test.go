@@ -0,0 +1,143 @@
+package routeguide
+
+import "testing"
+
+func TestPolicyRuleAllows(t *testing.T) {
+	const (
+		getFeature   = "/routeguideproto.RouteGuide/GetFeature"
+		listFeatures = "/routeguideproto.RouteGuide/ListFeatures"
+		healthCheck  = "/grpc.health.v1.Health/Check"
+	)
+
+	tests := []struct {
+		name       string
+		rule       *policyRule
+		identities []string
+		method     string
+		expected   bool
+	}{
+		{
+			name:       "header identity and full method",
+			rule:       &policyRule{Identities: []string{"header:client"}, Methods: []string{getFeature}},
+			identities: []string{"header:client"},
+			method:     getFeature,
+			expected:   true,
+		},
+		{
+			name:       "other method",
+			rule:       &policyRule{Identities: []string{"header:client"}, Methods: []string{getFeature}},
+			identities: []string{"header:client"},
+			method:     listFeatures,
+		},
+		{
+			name:       "san identity and service wildcard",
+			rule:       &policyRule{Identities: []string{"san:spiffe://routeguide/client"}, Methods: []string{"/routeguideproto.RouteGuide/*"}},
+			identities: []string{"header:other", "san:spiffe://routeguide/client"},
+			method:     listFeatures,
+			expected:   true,
+		},
+		{
+			name:       "service wildcard of another service",
+			rule:       &policyRule{Identities: []string{"san:spiffe://routeguide/client"}, Methods: []string{"/routeguideproto.RouteGuide/*"}},
+			identities: []string{"san:spiffe://routeguide/client"},
+			method:     healthCheck,
+		},
+		{
+			name:       "service wildcard of a service with the same prefix",
+			rule:       &policyRule{Identities: []string{"sub:alice"}, Methods: []string{"/routeguideproto.Route/*"}},
+			identities: []string{"sub:alice"},
+			method:     getFeature,
+		},
+		{
+			name:       "sub identity and any method",
+			rule:       &policyRule{Identities: []string{"sub:alice"}, Methods: []string{"*"}},
+			identities: []string{"sub:alice"},
+			method:     healthCheck,
+			expected:   true,
+		},
+		{
+			name:       "identity of another kind",
+			rule:       &policyRule{Identities: []string{"sub:alice"}, Methods: []string{"*"}},
+			identities: []string{"header:alice", "san:alice"},
+			method:     getFeature,
+		},
+		{
+			name:       "any identity",
+			rule:       &policyRule{Identities: []string{"*"}, Methods: []string{getFeature}},
+			identities: []string{},
+			method:     getFeature,
+			expected:   true,
+		},
+		{
+			name:       "any identity and other method",
+			rule:       &policyRule{Identities: []string{"*"}, Methods: []string{getFeature}},
+			identities: []string{"sub:alice"},
+			method:     listFeatures,
+		},
+		{
+			name:       "no identities",
+			rule:       &policyRule{Identities: []string{"header:client"}, Methods: []string{"*"}},
+			identities: []string{},
+			method:     getFeature,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := test.rule.allows(test.identities, test.method); actual != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPolicyRuleValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  *policyRule
+		valid bool
+	}{
+		{
+			name:  "all identity kinds",
+			rule:  &policyRule{Identities: []string{"header:client", "san:spiffe://routeguide/client", "sub:alice", "*"}, Methods: []string{"/routeguideproto.RouteGuide/GetFeature"}},
+			valid: true,
+		},
+		{
+			name:  "service wildcard and any method",
+			rule:  &policyRule{Identities: []string{"*"}, Methods: []string{"/routeguideproto.RouteGuide/*", "*"}},
+			valid: true,
+		},
+		{
+			name: "no identities",
+			rule: &policyRule{Methods: []string{"*"}},
+		},
+		{
+			name: "no methods",
+			rule: &policyRule{Identities: []string{"*"}},
+		},
+		{
+			name: "identity without kind",
+			rule: &policyRule{Identities: []string{"alice"}, Methods: []string{"*"}},
+		},
+		{
+			name: "identity of unknown kind",
+			rule: &policyRule{Identities: []string{"email:alice@routeguide"}, Methods: []string{"*"}},
+		},
+		{
+			name: "method without service",
+			rule: &policyRule{Identities: []string{"*"}, Methods: []string{"GetFeature"}},
+		},
+		{
+			name: "method with too many parts",
+			rule: &policyRule{Identities: []string{"*"}, Methods: []string{"/routeguideproto.RouteGuide/GetFeature/*"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.rule.validate(); (err == nil) != test.valid {
+				t.Errorf("expected valid=%t, got error %v", test.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package routeguide
+
+import (
+	"sort"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// presence tracks the RouteChat participants at every location. A participant
+// is at the location of the last note it sent. It's not safe for concurrent
+// use.
+type presence struct {
+	locations map[string]map[string]*participant
+}
+
+// participant is a participant at a location. A participant can chat on more
+// than one stream at a time, so it's at the location until all its streams
+// left.
+type participant struct {
+	*pb.Participant
+	streams int
+}
+
+func newPresence() *presence {
+	return &presence{locations: make(map[string]map[string]*participant)}
+}
+
+// join adds a stream of the participant to the location, returning true if
+// the participant wasn't at the location yet.
+func (p *presence) join(key, id string, location *pb.Point, now *timestamp.Timestamp) bool {
+	participants, exists := p.locations[key]
+	if !exists {
+		participants = make(map[string]*participant)
+		p.locations[key] = participants
+	}
+
+	if joined, exists := participants[id]; exists {
+		joined.streams++
+		return false
+	}
+
+	participants[id] = &participant{
+		Participant: &pb.Participant{Id: id, Location: location, JoinedAt: now},
+		streams:     1,
+	}
+	return true
+}
+
+// leave removes a stream of the participant from the location, returning true
+// if the participant has no streams left at the location.
+func (p *presence) leave(key, id string) bool {
+	participants := p.locations[key]
+	joined, exists := participants[id]
+	if !exists {
+		return false
+	}
+
+	joined.streams--
+	if joined.streams > 0 {
+		return false
+	}
+
+	delete(participants, id)
+	if len(participants) == 0 {
+		delete(p.locations, key)
+	}
+	return true
+}
+
+// at returns the participants at the location, in the order they joined.
+func (p *presence) at(key string) []*pb.Participant {
+	participants := []*pb.Participant{}
+	for _, joined := range p.locations[key] {
+		participants = append(participants, joined.Participant)
+	}
+
+	sort.Slice(participants, func(i, j int) bool {
+		a, b := participants[i].JoinedAt, participants[j].JoinedAt
+		if a.Seconds != b.Seconds {
+			return a.Seconds < b.Seconds
+		}
+		if a.Nanos != b.Nanos {
+			return a.Nanos < b.Nanos
+		}
+		return participants[i].Id < participants[j].Id
+	})
+	return participants
+}
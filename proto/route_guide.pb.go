@@ -7,6 +7,8 @@ import (
 	context "context"
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
+	duration "github.com/golang/protobuf/ptypes/duration"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
 	grpc "google.golang.org/grpc"
 	math "math"
 )
@@ -22,6 +24,156 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type RouteNote_Kind int32
+
+const (
+	// A message sent to the location.
+	RouteNote_NOTE RouteNote_Kind = 0
+	// Subscribes the stream to the location, or to the area if it's set.
+	RouteNote_SUBSCRIBE RouteNote_Kind = 1
+	// Unsubscribes the stream from the location, or from the area if it's
+	// set.
+	RouteNote_UNSUBSCRIBE RouteNote_Kind = 2
+	// Acknowledges a note to its sender, once it's accepted by the server.
+	// It carries the id, sender_id, sent_at and sequence of the note. ACK
+	// notes are only sent by the server.
+	RouteNote_ACK RouteNote_Kind = 3
+	// Announces that the participant identified by sender_id arrived at the
+	// location, at sent_at. JOIN notes are only sent by the server.
+	RouteNote_JOIN RouteNote_Kind = 4
+	// Announces that the participant identified by sender_id moved away from
+	// the location, or disconnected, at sent_at. LEAVE notes are only sent by
+	// the server.
+	RouteNote_LEAVE RouteNote_Kind = 5
+)
+
+var RouteNote_Kind_name = map[int32]string{
+	0: "NOTE",
+	1: "SUBSCRIBE",
+	2: "UNSUBSCRIBE",
+	3: "ACK",
+	4: "JOIN",
+	5: "LEAVE",
+}
+
+var RouteNote_Kind_value = map[string]int32{
+	"NOTE":        0,
+	"SUBSCRIBE":   1,
+	"UNSUBSCRIBE": 2,
+	"ACK":         3,
+	"JOIN":        4,
+	"LEAVE":       5,
+}
+
+func (x RouteNote_Kind) String() string {
+	return proto.EnumName(RouteNote_Kind_name, int32(x))
+}
+
+func (RouteNote_Kind) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{6, 0}
+}
+
+// The orderings of the features.
+type ListFeaturesPageRequest_Order int32
+
+const (
+	// Ordered by latitude and then longitude.
+	ListFeaturesPageRequest_LOCATION ListFeaturesPageRequest_Order = 0
+	// Ordered by name.
+	ListFeaturesPageRequest_NAME ListFeaturesPageRequest_Order = 1
+	// Ordered by the geodesic distance from the reference point.
+	ListFeaturesPageRequest_DISTANCE ListFeaturesPageRequest_Order = 2
+)
+
+var ListFeaturesPageRequest_Order_name = map[int32]string{
+	0: "LOCATION",
+	1: "NAME",
+	2: "DISTANCE",
+}
+
+var ListFeaturesPageRequest_Order_value = map[string]int32{
+	"LOCATION": 0,
+	"NAME":     1,
+	"DISTANCE": 2,
+}
+
+func (x ListFeaturesPageRequest_Order) String() string {
+	return proto.EnumName(ListFeaturesPageRequest_Order_name, int32(x))
+}
+
+func (ListFeaturesPageRequest_Order) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{13, 0}
+}
+
+type FeatureEvent_Type int32
+
+const (
+	// The Feature was created.
+	FeatureEvent_ADDED FeatureEvent_Type = 0
+	// The Feature was updated.
+	FeatureEvent_MODIFIED FeatureEvent_Type = 1
+	// The Feature was deleted. The Feature is the last version before it was
+	// deleted.
+	FeatureEvent_DELETED FeatureEvent_Type = 2
+	// All the current Features have been sent. The event has no Feature.
+	FeatureEvent_SYNCED FeatureEvent_Type = 3
+)
+
+var FeatureEvent_Type_name = map[int32]string{
+	0: "ADDED",
+	1: "MODIFIED",
+	2: "DELETED",
+	3: "SYNCED",
+}
+
+var FeatureEvent_Type_value = map[string]int32{
+	"ADDED":    0,
+	"MODIFIED": 1,
+	"DELETED":  2,
+	"SYNCED":   3,
+}
+
+func (x FeatureEvent_Type) String() string {
+	return proto.EnumName(FeatureEvent_Type_name, int32(x))
+}
+
+func (FeatureEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{16, 0}
+}
+
+type GeofenceEvent_Type int32
+
+const (
+	// The client sent a point within the Geofence, after a point outside of
+	// it or no point at all.
+	GeofenceEvent_ENTER GeofenceEvent_Type = 0
+	// The client sent a point outside of the Geofence, after a point within
+	// it, or its stream ended while it was within it.
+	GeofenceEvent_EXIT GeofenceEvent_Type = 1
+	// The client stayed within the Geofence for its dwell duration.
+	GeofenceEvent_DWELL GeofenceEvent_Type = 2
+)
+
+var GeofenceEvent_Type_name = map[int32]string{
+	0: "ENTER",
+	1: "EXIT",
+	2: "DWELL",
+}
+
+var GeofenceEvent_Type_value = map[string]int32{
+	"ENTER": 0,
+	"EXIT":  1,
+	"DWELL": 2,
+}
+
+func (x GeofenceEvent_Type) String() string {
+	return proto.EnumName(GeofenceEvent_Type_name, int32(x))
+}
+
+func (GeofenceEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{25, 0}
+}
+
 // Points are represented as latitude-longitude pairs in the E7 representation
 // (degrees multiplied by 10**7 and rounded to the nearest integer).
 // Latitudes should be in the range +/- 90 degrees and longitude should be in
@@ -79,7 +231,12 @@ type Rectangle struct {
 	// One corner of the rectangle.
 	Lo *Point `protobuf:"bytes,1,opt,name=lo,proto3" json:"lo,omitempty"`
 	// The other corner of the rectangle.
-	Hi                   *Point   `protobuf:"bytes,2,opt,name=hi,proto3" json:"hi,omitempty"`
+	Hi *Point `protobuf:"bytes,2,opt,name=hi,proto3" json:"hi,omitempty"`
+	// If true, lo is the south-west corner and hi is the north-east corner of
+	// the rectangle. A rectangle whose lo longitude is greater than its hi
+	// longitude then crosses the antimeridian. Otherwise, the corners can be
+	// given in any order and the rectangle never crosses the antimeridian.
+	Oriented             bool     `protobuf:"varint,3,opt,name=oriented,proto3" json:"oriented,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -124,13 +281,117 @@ func (m *Rectangle) GetHi() *Point {
 	return nil
 }
 
+func (m *Rectangle) GetOriented() bool {
+	if m != nil {
+		return m.Oriented
+	}
+	return false
+}
+
+// A Circle is the area within a geodesic distance of its center.
+type Circle struct {
+	// The center of the circle.
+	Center *Point `protobuf:"bytes,1,opt,name=center,proto3" json:"center,omitempty"`
+	// The radius of the circle in meters.
+	RadiusMeters         float64  `protobuf:"fixed64,2,opt,name=radius_meters,json=radiusMeters,proto3" json:"radius_meters,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Circle) Reset()         { *m = Circle{} }
+func (m *Circle) String() string { return proto.CompactTextString(m) }
+func (*Circle) ProtoMessage()    {}
+func (*Circle) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{2}
+}
+
+func (m *Circle) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Circle.Unmarshal(m, b)
+}
+func (m *Circle) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Circle.Marshal(b, m, deterministic)
+}
+func (m *Circle) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Circle.Merge(m, src)
+}
+func (m *Circle) XXX_Size() int {
+	return xxx_messageInfo_Circle.Size(m)
+}
+func (m *Circle) XXX_DiscardUnknown() {
+	xxx_messageInfo_Circle.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Circle proto.InternalMessageInfo
+
+func (m *Circle) GetCenter() *Point {
+	if m != nil {
+		return m.Center
+	}
+	return nil
+}
+
+func (m *Circle) GetRadiusMeters() float64 {
+	if m != nil {
+		return m.RadiusMeters
+	}
+	return 0
+}
+
+// A Polygon is the area enclosed by its vertices. The edges between
+// consecutive vertices, and between the last and the first vertex, are
+// straight lines in latitude and longitude. Each edge takes the shorter way
+// around the earth, so a polygon may cross the antimeridian.
+type Polygon struct {
+	// The vertices of the polygon. At least 3 vertices are required.
+	Vertices             []*Point `protobuf:"bytes,1,rep,name=vertices,proto3" json:"vertices,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Polygon) Reset()         { *m = Polygon{} }
+func (m *Polygon) String() string { return proto.CompactTextString(m) }
+func (*Polygon) ProtoMessage()    {}
+func (*Polygon) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{3}
+}
+
+func (m *Polygon) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Polygon.Unmarshal(m, b)
+}
+func (m *Polygon) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Polygon.Marshal(b, m, deterministic)
+}
+func (m *Polygon) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Polygon.Merge(m, src)
+}
+func (m *Polygon) XXX_Size() int {
+	return xxx_messageInfo_Polygon.Size(m)
+}
+func (m *Polygon) XXX_DiscardUnknown() {
+	xxx_messageInfo_Polygon.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Polygon proto.InternalMessageInfo
+
+func (m *Polygon) GetVertices() []*Point {
+	if m != nil {
+		return m.Vertices
+	}
+	return nil
+}
+
 // A feature names something at a given point.
 // If a feature could not be named, the name is empty.
 type Feature struct {
 	// The name of the feature.
 	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	// The point where the feature is detected.
-	Location             *Point   `protobuf:"bytes,2,opt,name=location,proto3" json:"location,omitempty"`
+	Location *Point `protobuf:"bytes,2,opt,name=location,proto3" json:"location,omitempty"`
+	// The version of the feature, which changes every time the feature is
+	// modified. It is used to detect concurrent modifications.
+	Version              int64    `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -140,7 +401,7 @@ func (m *Feature) Reset()         { *m = Feature{} }
 func (m *Feature) String() string { return proto.CompactTextString(m) }
 func (*Feature) ProtoMessage()    {}
 func (*Feature) Descriptor() ([]byte, []int) {
-	return fileDescriptor_b7d679f20da65b7b, []int{2}
+	return fileDescriptor_b7d679f20da65b7b, []int{4}
 }
 
 func (m *Feature) XXX_Unmarshal(b []byte) error {
@@ -175,12 +436,85 @@ func (m *Feature) GetLocation() *Point {
 	return nil
 }
 
+func (m *Feature) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+// A DeleteFeatureRequest identifies the Feature to be deleted.
+type DeleteFeatureRequest struct {
+	// The location of the feature.
+	Location *Point `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
+	// The expected version of the feature.
+	Version              int64    `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteFeatureRequest) Reset()         { *m = DeleteFeatureRequest{} }
+func (m *DeleteFeatureRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteFeatureRequest) ProtoMessage()    {}
+func (*DeleteFeatureRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{5}
+}
+
+func (m *DeleteFeatureRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteFeatureRequest.Unmarshal(m, b)
+}
+func (m *DeleteFeatureRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteFeatureRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteFeatureRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteFeatureRequest.Merge(m, src)
+}
+func (m *DeleteFeatureRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteFeatureRequest.Size(m)
+}
+func (m *DeleteFeatureRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteFeatureRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteFeatureRequest proto.InternalMessageInfo
+
+func (m *DeleteFeatureRequest) GetLocation() *Point {
+	if m != nil {
+		return m.Location
+	}
+	return nil
+}
+
+func (m *DeleteFeatureRequest) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 // A RouteNote is a message sent while at a given point.
 type RouteNote struct {
 	// The location from which the message is sent.
 	Location *Point `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
 	// The message to be sent.
-	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Message string         `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Kind    RouteNote_Kind `protobuf:"varint,3,opt,name=kind,proto3,enum=routeguideproto.RouteNote_Kind" json:"kind,omitempty"`
+	// The area of a SUBSCRIBE or UNSUBSCRIBE note. Subscribing to an area
+	// delivers the notes sent from all the locations within it.
+	Area *Rectangle `protobuf:"bytes,4,opt,name=area,proto3" json:"area,omitempty"`
+	// The ID of the note, unique among the notes of its sender. A note that's
+	// resent with the same ID is acknowledged again, but isn't delivered
+	// twice. If it's empty, the server assigns one.
+	Id string `protobuf:"bytes,5,opt,name=id,proto3" json:"id,omitempty"`
+	// The identity of the sender, assigned by the server.
+	SenderId string `protobuf:"bytes,6,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	// The time the note was accepted by the server.
+	SentAt *timestamp.Timestamp `protobuf:"bytes,7,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	// The position of the note in the order the server accepted the notes in,
+	// assigned by the server. Clients can use it to deduplicate redeliveries,
+	// e.g. the history of overlapping subscriptions.
+	Sequence             int64    `protobuf:"varint,8,opt,name=sequence,proto3" json:"sequence,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -190,7 +524,7 @@ func (m *RouteNote) Reset()         { *m = RouteNote{} }
 func (m *RouteNote) String() string { return proto.CompactTextString(m) }
 func (*RouteNote) ProtoMessage()    {}
 func (*RouteNote) Descriptor() ([]byte, []int) {
-	return fileDescriptor_b7d679f20da65b7b, []int{3}
+	return fileDescriptor_b7d679f20da65b7b, []int{6}
 }
 
 func (m *RouteNote) XXX_Unmarshal(b []byte) error {
@@ -225,6 +559,48 @@ func (m *RouteNote) GetMessage() string {
 	return ""
 }
 
+func (m *RouteNote) GetKind() RouteNote_Kind {
+	if m != nil {
+		return m.Kind
+	}
+	return RouteNote_NOTE
+}
+
+func (m *RouteNote) GetArea() *Rectangle {
+	if m != nil {
+		return m.Area
+	}
+	return nil
+}
+
+func (m *RouteNote) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *RouteNote) GetSenderId() string {
+	if m != nil {
+		return m.SenderId
+	}
+	return ""
+}
+
+func (m *RouteNote) GetSentAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.SentAt
+	}
+	return nil
+}
+
+func (m *RouteNote) GetSequence() int64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
 // A RouteSummary is received in response to a RecordRoute rpc.
 // It contains the number of individual points received, the number
 // of detected features, and the total distance covered as the cumulative
@@ -232,22 +608,46 @@ func (m *RouteNote) GetMessage() string {
 type RouteSummary struct {
 	// The number of points received.
 	PointCount int32 `protobuf:"varint,1,opt,name=point_count,json=pointCount,proto3" json:"point_count,omitempty"`
-	// The number of known features passed while traversing the route.
+	// The number of distinct named features passed while traversing the route.
 	FeatureCount int32 `protobuf:"varint,2,opt,name=feature_count,json=featureCount,proto3" json:"feature_count,omitempty"`
 	// The distance covered in meters.
 	Distance int32 `protobuf:"varint,3,opt,name=distance,proto3" json:"distance,omitempty"`
 	// The duration of the traversal in seconds.
-	ElapsedTime          int32    `protobuf:"varint,4,opt,name=elapsed_time,json=elapsedTime,proto3" json:"elapsed_time,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	ElapsedTime int32 `protobuf:"varint,4,opt,name=elapsed_time,json=elapsedTime,proto3" json:"elapsed_time,omitempty"`
+	// The distances in meters between each pair of consecutive points.
+	SegmentDistances []float64 `protobuf:"fixed64,5,rep,packed,name=segment_distances,json=segmentDistances,proto3" json:"segment_distances,omitempty"`
+	// The initial bearing of the route in degrees clockwise from true north,
+	// taken from the first segment of non-zero length.
+	InitialBearing float64 `protobuf:"fixed64,6,opt,name=initial_bearing,json=initialBearing,proto3" json:"initial_bearing,omitempty"`
+	// The final bearing of the route in degrees clockwise from true north,
+	// taken from the last segment of non-zero length.
+	FinalBearing float64 `protobuf:"fixed64,7,opt,name=final_bearing,json=finalBearing,proto3" json:"final_bearing,omitempty"`
+	// The distinct named features passed while traversing the route, in the
+	// order they were first passed. A point passes a feature if it's within the
+	// server's snapping tolerance of the feature.
+	FeaturesPassed []*Feature `protobuf:"bytes,8,rep,name=features_passed,json=featuresPassed,proto3" json:"features_passed,omitempty"`
+	// The ID of the recorded route, used to retrieve it with GetRoute.
+	RouteId string `protobuf:"bytes,9,opt,name=route_id,json=routeId,proto3" json:"route_id,omitempty"`
+	// The average speed in meters per second, over the moving segments.
+	AverageSpeed float64 `protobuf:"fixed64,10,opt,name=average_speed,json=averageSpeed,proto3" json:"average_speed,omitempty"`
+	// The highest speed of a moving segment, in meters per second.
+	MaxSpeed float64 `protobuf:"fixed64,11,opt,name=max_speed,json=maxSpeed,proto3" json:"max_speed,omitempty"`
+	// The time spent moving, in seconds.
+	MovingTime int32 `protobuf:"varint,12,opt,name=moving_time,json=movingTime,proto3" json:"moving_time,omitempty"`
+	// The time spent idle, in seconds.
+	IdleTime int32 `protobuf:"varint,13,opt,name=idle_time,json=idleTime,proto3" json:"idle_time,omitempty"`
+	// The gaps of the route, in order.
+	Gaps                 []*RouteGap `protobuf:"bytes,14,rep,name=gaps,proto3" json:"gaps,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
 
 func (m *RouteSummary) Reset()         { *m = RouteSummary{} }
 func (m *RouteSummary) String() string { return proto.CompactTextString(m) }
 func (*RouteSummary) ProtoMessage()    {}
 func (*RouteSummary) Descriptor() ([]byte, []int) {
-	return fileDescriptor_b7d679f20da65b7b, []int{4}
+	return fileDescriptor_b7d679f20da65b7b, []int{7}
 }
 
 func (m *RouteSummary) XXX_Unmarshal(b []byte) error {
@@ -296,123 +696,1474 @@ func (m *RouteSummary) GetElapsedTime() int32 {
 	return 0
 }
 
-func init() {
-	proto.RegisterType((*Point)(nil), "routeguideproto.Point")
-	proto.RegisterType((*Rectangle)(nil), "routeguideproto.Rectangle")
-	proto.RegisterType((*Feature)(nil), "routeguideproto.Feature")
-	proto.RegisterType((*RouteNote)(nil), "routeguideproto.RouteNote")
-	proto.RegisterType((*RouteSummary)(nil), "routeguideproto.RouteSummary")
+func (m *RouteSummary) GetSegmentDistances() []float64 {
+	if m != nil {
+		return m.SegmentDistances
+	}
+	return nil
 }
 
-func init() { proto.RegisterFile("route_guide.proto", fileDescriptor_b7d679f20da65b7b) }
-
-var fileDescriptor_b7d679f20da65b7b = []byte{
-	// 381 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0xcd, 0x4a, 0xf3, 0x40,
-	0x14, 0x86, 0x9b, 0x7c, 0xed, 0xd7, 0xe6, 0x24, 0x22, 0x9e, 0x85, 0x84, 0xa0, 0xa8, 0x11, 0xa4,
-	0xab, 0x52, 0xea, 0x0d, 0x28, 0x05, 0x5b, 0x41, 0x44, 0xa3, 0x1b, 0x71, 0x51, 0xc6, 0xe4, 0x98,
-	0x0e, 0x24, 0x99, 0x92, 0x4c, 0x16, 0x5e, 0x87, 0xd7, 0xe1, 0x3d, 0x4a, 0x66, 0x92, 0xfa, 0x53,
-	0x5b, 0xdd, 0xe5, 0x3c, 0xe7, 0x9d, 0xf7, 0xfc, 0x05, 0x76, 0x72, 0x51, 0x4a, 0x9a, 0xc5, 0x25,
-	0x8f, 0x68, 0xb0, 0xc8, 0x85, 0x14, 0xb8, 0xad, 0x90, 0x22, 0x0a, 0xf8, 0xe7, 0xd0, 0xb9, 0x11,
-	0x3c, 0x93, 0xe8, 0x41, 0x2f, 0x61, 0x92, 0xcb, 0x32, 0x22, 0xd7, 0x38, 0x34, 0xfa, 0x9d, 0x60,
-	0x19, 0xe3, 0x1e, 0x58, 0x89, 0xc8, 0x62, 0x9d, 0x34, 0x55, 0xf2, 0x03, 0xf8, 0x8f, 0x60, 0x05,
-	0x14, 0x4a, 0x96, 0xc5, 0x09, 0xe1, 0x09, 0x98, 0x89, 0x50, 0x06, 0xf6, 0x68, 0x77, 0xf0, 0xad,
-	0xda, 0x40, 0x95, 0x0a, 0xcc, 0x44, 0x54, 0xba, 0x39, 0x57, 0x5e, 0x1b, 0x74, 0x73, 0xee, 0xdf,
-	0x42, 0xf7, 0x82, 0x98, 0x2c, 0x73, 0x42, 0x84, 0x76, 0xc6, 0x52, 0xdd, 0x9d, 0x15, 0xa8, 0x6f,
-	0x1c, 0x41, 0x2f, 0x11, 0x21, 0x93, 0x5c, 0x64, 0xbf, 0x98, 0x2d, 0x75, 0xfe, 0x03, 0x58, 0x41,
-	0x25, 0xb9, 0x16, 0xf2, 0xab, 0x81, 0xf1, 0x37, 0x03, 0x74, 0xa1, 0x9b, 0x52, 0x51, 0xb0, 0x58,
-	0x2f, 0xc3, 0x0a, 0x9a, 0xd0, 0x7f, 0x35, 0xc0, 0x51, 0xde, 0x77, 0x65, 0x9a, 0xb2, 0xfc, 0x05,
-	0x0f, 0xc0, 0x5e, 0x54, 0xaf, 0x67, 0xa1, 0x28, 0x33, 0x59, 0x2f, 0x16, 0x14, 0x1a, 0x57, 0x04,
-	0x8f, 0x61, 0xeb, 0x59, 0xcf, 0x57, 0x4b, 0xf4, 0x7a, 0x9d, 0x1a, 0x6a, 0x91, 0x07, 0xbd, 0x88,
-	0x17, 0x92, 0x65, 0x21, 0xb9, 0xff, 0xf4, 0x6d, 0x9a, 0x18, 0x8f, 0xc0, 0xa1, 0x84, 0x2d, 0x0a,
-	0x8a, 0x66, 0x92, 0xa7, 0xe4, 0xb6, 0x55, 0xde, 0xae, 0xd9, 0x3d, 0x4f, 0x69, 0xf4, 0x66, 0x02,
-	0xa8, 0xae, 0x26, 0xd5, 0x4c, 0x78, 0x06, 0x30, 0x21, 0xd9, 0x6c, 0x75, 0xcd, 0xb8, 0x9e, 0xbb,
-	0xc2, 0xeb, 0x17, 0x7e, 0x0b, 0xa7, 0xe0, 0x5c, 0xf1, 0xa2, 0xb1, 0x28, 0xd0, 0x5b, 0xd1, 0x2e,
-	0x7f, 0x88, 0x4d, 0x3e, 0x43, 0x03, 0xa7, 0x60, 0x07, 0x14, 0x8a, 0x3c, 0x52, 0xfd, 0xad, 0x6d,
-	0x66, 0x7f, 0xb5, 0xc0, 0xa7, 0x2d, 0xfb, 0xad, 0xbe, 0x81, 0x97, 0xf5, 0x55, 0xc7, 0x73, 0x26,
-	0x7f, 0x6a, 0xa8, 0xb9, 0xb8, 0xb7, 0x21, 0x57, 0x19, 0x0d, 0x8d, 0xa7, 0xff, 0x0a, 0x9f, 0xbe,
-	0x07, 0x00, 0x00, 0xff, 0xff, 0xad, 0x71, 0xbb, 0x29, 0x40, 0x03, 0x00, 0x00,
+func (m *RouteSummary) GetInitialBearing() float64 {
+	if m != nil {
+		return m.InitialBearing
+	}
+	return 0
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
-
-// RouteGuideClient is the client API for RouteGuide service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type RouteGuideClient interface {
-	// Obtains the feature at a given position
-	GetFeature(ctx context.Context, in *Point, opts ...grpc.CallOption) (*Feature, error)
-	// Obtains the Features available within the given Rectangle. Results are
-	// streamed rather than returned at once (e.g. in a response message with a
-	// repeated field), as the rectangle may cover a large area and contain a
-	// huge number of features.
-	ListFeatures(ctx context.Context, in *Rectangle, opts ...grpc.CallOption) (RouteGuide_ListFeaturesClient, error)
-	// Accepts a stream of Points on a route being traversed, returning a
-	// RouteSummary when traversal is completed.
-	RecordRoute(ctx context.Context, opts ...grpc.CallOption) (RouteGuide_RecordRouteClient, error)
-	// Accepts a stream of RouteNotes sent while a route is being traversed,
-	// while receiving other RouteNotes (e.g. from other users).
-	RouteChat(ctx context.Context, opts ...grpc.CallOption) (RouteGuide_RouteChatClient, error)
+func (m *RouteSummary) GetFinalBearing() float64 {
+	if m != nil {
+		return m.FinalBearing
+	}
+	return 0
 }
 
-type routeGuideClient struct {
-	cc *grpc.ClientConn
+func (m *RouteSummary) GetFeaturesPassed() []*Feature {
+	if m != nil {
+		return m.FeaturesPassed
+	}
+	return nil
 }
 
-func NewRouteGuideClient(cc *grpc.ClientConn) RouteGuideClient {
-	return &routeGuideClient{cc}
+func (m *RouteSummary) GetRouteId() string {
+	if m != nil {
+		return m.RouteId
+	}
+	return ""
 }
 
-func (c *routeGuideClient) GetFeature(ctx context.Context, in *Point, opts ...grpc.CallOption) (*Feature, error) {
-	out := new(Feature)
-	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/GetFeature", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *RouteSummary) GetAverageSpeed() float64 {
+	if m != nil {
+		return m.AverageSpeed
 	}
-	return out, nil
+	return 0
 }
 
-func (c *routeGuideClient) ListFeatures(ctx context.Context, in *Rectangle, opts ...grpc.CallOption) (RouteGuide_ListFeaturesClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[0], "/routeguideproto.RouteGuide/ListFeatures", opts...)
-	if err != nil {
-		return nil, err
+func (m *RouteSummary) GetMaxSpeed() float64 {
+	if m != nil {
+		return m.MaxSpeed
 	}
-	x := &routeGuideListFeaturesClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
+	return 0
+}
+
+func (m *RouteSummary) GetMovingTime() int32 {
+	if m != nil {
+		return m.MovingTime
 	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
+	return 0
+}
+
+func (m *RouteSummary) GetIdleTime() int32 {
+	if m != nil {
+		return m.IdleTime
 	}
-	return x, nil
+	return 0
 }
 
-type RouteGuide_ListFeaturesClient interface {
-	Recv() (*Feature, error)
-	grpc.ClientStream
+func (m *RouteSummary) GetGaps() []*RouteGap {
+	if m != nil {
+		return m.Gaps
+	}
+	return nil
 }
 
-type routeGuideListFeaturesClient struct {
-	grpc.ClientStream
+// A TimedPoint is a Point recorded at the given time.
+type TimedPoint struct {
+	Point *Point `protobuf:"bytes,1,opt,name=point,proto3" json:"point,omitempty"`
+	// The time the point was recorded at. If it's unset, the point isn't used
+	// to measure the times and speeds of the route. The times must not
+	// decrease along the route.
+	Time                 *timestamp.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
-func (x *routeGuideListFeaturesClient) Recv() (*Feature, error) {
-	m := new(Feature)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
-	}
-	return m, nil
+func (m *TimedPoint) Reset()         { *m = TimedPoint{} }
+func (m *TimedPoint) String() string { return proto.CompactTextString(m) }
+func (*TimedPoint) ProtoMessage()    {}
+func (*TimedPoint) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{8}
 }
 
-func (c *routeGuideClient) RecordRoute(ctx context.Context, opts ...grpc.CallOption) (RouteGuide_RecordRouteClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[1], "/routeguideproto.RouteGuide/RecordRoute", opts...)
-	if err != nil {
+func (m *TimedPoint) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TimedPoint.Unmarshal(m, b)
+}
+func (m *TimedPoint) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TimedPoint.Marshal(b, m, deterministic)
+}
+func (m *TimedPoint) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TimedPoint.Merge(m, src)
+}
+func (m *TimedPoint) XXX_Size() int {
+	return xxx_messageInfo_TimedPoint.Size(m)
+}
+func (m *TimedPoint) XXX_DiscardUnknown() {
+	xxx_messageInfo_TimedPoint.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TimedPoint proto.InternalMessageInfo
+
+func (m *TimedPoint) GetPoint() *Point {
+	if m != nil {
+		return m.Point
+	}
+	return nil
+}
+
+func (m *TimedPoint) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+// A RouteGap is a period of a route in which no points were recorded.
+type RouteGap struct {
+	// The time of the point before the gap.
+	Start *timestamp.Timestamp `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	// The time of the point after the gap.
+	End                  *timestamp.Timestamp `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *RouteGap) Reset()         { *m = RouteGap{} }
+func (m *RouteGap) String() string { return proto.CompactTextString(m) }
+func (*RouteGap) ProtoMessage()    {}
+func (*RouteGap) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{9}
+}
+
+func (m *RouteGap) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RouteGap.Unmarshal(m, b)
+}
+func (m *RouteGap) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RouteGap.Marshal(b, m, deterministic)
+}
+func (m *RouteGap) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RouteGap.Merge(m, src)
+}
+func (m *RouteGap) XXX_Size() int {
+	return xxx_messageInfo_RouteGap.Size(m)
+}
+func (m *RouteGap) XXX_DiscardUnknown() {
+	xxx_messageInfo_RouteGap.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RouteGap proto.InternalMessageInfo
+
+func (m *RouteGap) GetStart() *timestamp.Timestamp {
+	if m != nil {
+		return m.Start
+	}
+	return nil
+}
+
+func (m *RouteGap) GetEnd() *timestamp.Timestamp {
+	if m != nil {
+		return m.End
+	}
+	return nil
+}
+
+// A NearestRequest asks for the Features closest to a Point.
+type NearestRequest struct {
+	// The point to measure the distances from.
+	Point *Point `protobuf:"bytes,1,opt,name=point,proto3" json:"point,omitempty"`
+	// The maximum number of features to return.
+	K int32 `protobuf:"varint,2,opt,name=k,proto3" json:"k,omitempty"`
+	// If positive, features further than this distance from the point are
+	// excluded.
+	MaxDistanceMeters    float64  `protobuf:"fixed64,3,opt,name=max_distance_meters,json=maxDistanceMeters,proto3" json:"max_distance_meters,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NearestRequest) Reset()         { *m = NearestRequest{} }
+func (m *NearestRequest) String() string { return proto.CompactTextString(m) }
+func (*NearestRequest) ProtoMessage()    {}
+func (*NearestRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{10}
+}
+
+func (m *NearestRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NearestRequest.Unmarshal(m, b)
+}
+func (m *NearestRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NearestRequest.Marshal(b, m, deterministic)
+}
+func (m *NearestRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NearestRequest.Merge(m, src)
+}
+func (m *NearestRequest) XXX_Size() int {
+	return xxx_messageInfo_NearestRequest.Size(m)
+}
+func (m *NearestRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NearestRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NearestRequest proto.InternalMessageInfo
+
+func (m *NearestRequest) GetPoint() *Point {
+	if m != nil {
+		return m.Point
+	}
+	return nil
+}
+
+func (m *NearestRequest) GetK() int32 {
+	if m != nil {
+		return m.K
+	}
+	return 0
+}
+
+func (m *NearestRequest) GetMaxDistanceMeters() float64 {
+	if m != nil {
+		return m.MaxDistanceMeters
+	}
+	return 0
+}
+
+// A NearbyFeature is a Feature found near a Point.
+type NearbyFeature struct {
+	// The feature.
+	Feature *Feature `protobuf:"bytes,1,opt,name=feature,proto3" json:"feature,omitempty"`
+	// The geodesic distance between the point and the feature in meters.
+	DistanceMeters       float64  `protobuf:"fixed64,2,opt,name=distance_meters,json=distanceMeters,proto3" json:"distance_meters,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NearbyFeature) Reset()         { *m = NearbyFeature{} }
+func (m *NearbyFeature) String() string { return proto.CompactTextString(m) }
+func (*NearbyFeature) ProtoMessage()    {}
+func (*NearbyFeature) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{11}
+}
+
+func (m *NearbyFeature) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NearbyFeature.Unmarshal(m, b)
+}
+func (m *NearbyFeature) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NearbyFeature.Marshal(b, m, deterministic)
+}
+func (m *NearbyFeature) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NearbyFeature.Merge(m, src)
+}
+func (m *NearbyFeature) XXX_Size() int {
+	return xxx_messageInfo_NearbyFeature.Size(m)
+}
+func (m *NearbyFeature) XXX_DiscardUnknown() {
+	xxx_messageInfo_NearbyFeature.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NearbyFeature proto.InternalMessageInfo
+
+func (m *NearbyFeature) GetFeature() *Feature {
+	if m != nil {
+		return m.Feature
+	}
+	return nil
+}
+
+func (m *NearbyFeature) GetDistanceMeters() float64 {
+	if m != nil {
+		return m.DistanceMeters
+	}
+	return 0
+}
+
+// A NearestResponse is received in response to a FindNearest rpc.
+type NearestResponse struct {
+	// The nearby features, ordered by their distance from the point.
+	Features             []*NearbyFeature `protobuf:"bytes,1,rep,name=features,proto3" json:"features,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *NearestResponse) Reset()         { *m = NearestResponse{} }
+func (m *NearestResponse) String() string { return proto.CompactTextString(m) }
+func (*NearestResponse) ProtoMessage()    {}
+func (*NearestResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{12}
+}
+
+func (m *NearestResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NearestResponse.Unmarshal(m, b)
+}
+func (m *NearestResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NearestResponse.Marshal(b, m, deterministic)
+}
+func (m *NearestResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NearestResponse.Merge(m, src)
+}
+func (m *NearestResponse) XXX_Size() int {
+	return xxx_messageInfo_NearestResponse.Size(m)
+}
+func (m *NearestResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_NearestResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NearestResponse proto.InternalMessageInfo
+
+func (m *NearestResponse) GetFeatures() []*NearbyFeature {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+// A ListFeaturesPageRequest asks for a page of Features.
+type ListFeaturesPageRequest struct {
+	// The area to list the features from. If unset, features are listed from
+	// the entire dataset.
+	Rectangle *Rectangle `protobuf:"bytes,1,opt,name=rectangle,proto3" json:"rectangle,omitempty"`
+	// The maximum number of features in the page. If unset, the server's
+	// default page size is used.
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous page. If unset, the first page is
+	// returned. All other fields must be the same as in the request of the
+	// previous page.
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// The ordering of the features.
+	OrderBy ListFeaturesPageRequest_Order `protobuf:"varint,4,opt,name=order_by,json=orderBy,proto3,enum=routeguideproto.ListFeaturesPageRequest_Order" json:"order_by,omitempty"`
+	// The point to measure distances from, when ordering by distance.
+	Reference *Point `protobuf:"bytes,5,opt,name=reference,proto3" json:"reference,omitempty"`
+	// If set, only features whose names start with the prefix are listed.
+	NamePrefix string `protobuf:"bytes,6,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`
+	// If set, only features whose names match the regular expression are
+	// listed. The syntax is the one accepted by Go's regexp package.
+	NameRegex string `protobuf:"bytes,7,opt,name=name_regex,json=nameRegex,proto3" json:"name_regex,omitempty"`
+	// If true, features without names are excluded.
+	ExcludeUnnamed       bool     `protobuf:"varint,8,opt,name=exclude_unnamed,json=excludeUnnamed,proto3" json:"exclude_unnamed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFeaturesPageRequest) Reset()         { *m = ListFeaturesPageRequest{} }
+func (m *ListFeaturesPageRequest) String() string { return proto.CompactTextString(m) }
+func (*ListFeaturesPageRequest) ProtoMessage()    {}
+func (*ListFeaturesPageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{13}
+}
+
+func (m *ListFeaturesPageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFeaturesPageRequest.Unmarshal(m, b)
+}
+func (m *ListFeaturesPageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFeaturesPageRequest.Marshal(b, m, deterministic)
+}
+func (m *ListFeaturesPageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFeaturesPageRequest.Merge(m, src)
+}
+func (m *ListFeaturesPageRequest) XXX_Size() int {
+	return xxx_messageInfo_ListFeaturesPageRequest.Size(m)
+}
+func (m *ListFeaturesPageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFeaturesPageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFeaturesPageRequest proto.InternalMessageInfo
+
+func (m *ListFeaturesPageRequest) GetRectangle() *Rectangle {
+	if m != nil {
+		return m.Rectangle
+	}
+	return nil
+}
+
+func (m *ListFeaturesPageRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListFeaturesPageRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+func (m *ListFeaturesPageRequest) GetOrderBy() ListFeaturesPageRequest_Order {
+	if m != nil {
+		return m.OrderBy
+	}
+	return ListFeaturesPageRequest_LOCATION
+}
+
+func (m *ListFeaturesPageRequest) GetReference() *Point {
+	if m != nil {
+		return m.Reference
+	}
+	return nil
+}
+
+func (m *ListFeaturesPageRequest) GetNamePrefix() string {
+	if m != nil {
+		return m.NamePrefix
+	}
+	return ""
+}
+
+func (m *ListFeaturesPageRequest) GetNameRegex() string {
+	if m != nil {
+		return m.NameRegex
+	}
+	return ""
+}
+
+func (m *ListFeaturesPageRequest) GetExcludeUnnamed() bool {
+	if m != nil {
+		return m.ExcludeUnnamed
+	}
+	return false
+}
+
+// A ListFeaturesPageResponse is received in response to a ListFeaturesPage
+// rpc.
+type ListFeaturesPageResponse struct {
+	// The features in the page.
+	Features []*Feature `protobuf:"bytes,1,rep,name=features,proto3" json:"features,omitempty"`
+	// The token to obtain the next page with. It's empty on the last page.
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFeaturesPageResponse) Reset()         { *m = ListFeaturesPageResponse{} }
+func (m *ListFeaturesPageResponse) String() string { return proto.CompactTextString(m) }
+func (*ListFeaturesPageResponse) ProtoMessage()    {}
+func (*ListFeaturesPageResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{14}
+}
+
+func (m *ListFeaturesPageResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFeaturesPageResponse.Unmarshal(m, b)
+}
+func (m *ListFeaturesPageResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFeaturesPageResponse.Marshal(b, m, deterministic)
+}
+func (m *ListFeaturesPageResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFeaturesPageResponse.Merge(m, src)
+}
+func (m *ListFeaturesPageResponse) XXX_Size() int {
+	return xxx_messageInfo_ListFeaturesPageResponse.Size(m)
+}
+func (m *ListFeaturesPageResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFeaturesPageResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFeaturesPageResponse proto.InternalMessageInfo
+
+func (m *ListFeaturesPageResponse) GetFeatures() []*Feature {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+func (m *ListFeaturesPageResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+// A request to watch the Features within a Rectangle.
+type WatchFeaturesRequest struct {
+	Rectangle *Rectangle `protobuf:"bytes,1,opt,name=rectangle,proto3" json:"rectangle,omitempty"`
+	// The resource version of the last event seen by the client. If zero, the
+	// watch starts with the current Features. Resuming from a version that is
+	// too old to be resumed from fails with an OUT_OF_RANGE error, and the
+	// client should restart the watch from zero. A watch that is interrupted
+	// before its SYNCED event must also be restarted from zero.
+	ResourceVersion      int64    `protobuf:"varint,2,opt,name=resource_version,json=resourceVersion,proto3" json:"resource_version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchFeaturesRequest) Reset()         { *m = WatchFeaturesRequest{} }
+func (m *WatchFeaturesRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchFeaturesRequest) ProtoMessage()    {}
+func (*WatchFeaturesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{15}
+}
+
+func (m *WatchFeaturesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchFeaturesRequest.Unmarshal(m, b)
+}
+func (m *WatchFeaturesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchFeaturesRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchFeaturesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchFeaturesRequest.Merge(m, src)
+}
+func (m *WatchFeaturesRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchFeaturesRequest.Size(m)
+}
+func (m *WatchFeaturesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchFeaturesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchFeaturesRequest proto.InternalMessageInfo
+
+func (m *WatchFeaturesRequest) GetRectangle() *Rectangle {
+	if m != nil {
+		return m.Rectangle
+	}
+	return nil
+}
+
+func (m *WatchFeaturesRequest) GetResourceVersion() int64 {
+	if m != nil {
+		return m.ResourceVersion
+	}
+	return 0
+}
+
+// A change to a Feature.
+type FeatureEvent struct {
+	Type    FeatureEvent_Type `protobuf:"varint,1,opt,name=type,proto3,enum=routeguideproto.FeatureEvent_Type" json:"type,omitempty"`
+	Feature *Feature          `protobuf:"bytes,2,opt,name=feature,proto3" json:"feature,omitempty"`
+	// The revision of the store at which the change was made. Watches can be
+	// resumed from this version.
+	ResourceVersion      int64    `protobuf:"varint,3,opt,name=resource_version,json=resourceVersion,proto3" json:"resource_version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FeatureEvent) Reset()         { *m = FeatureEvent{} }
+func (m *FeatureEvent) String() string { return proto.CompactTextString(m) }
+func (*FeatureEvent) ProtoMessage()    {}
+func (*FeatureEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{16}
+}
+
+func (m *FeatureEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FeatureEvent.Unmarshal(m, b)
+}
+func (m *FeatureEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FeatureEvent.Marshal(b, m, deterministic)
+}
+func (m *FeatureEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FeatureEvent.Merge(m, src)
+}
+func (m *FeatureEvent) XXX_Size() int {
+	return xxx_messageInfo_FeatureEvent.Size(m)
+}
+func (m *FeatureEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_FeatureEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FeatureEvent proto.InternalMessageInfo
+
+func (m *FeatureEvent) GetType() FeatureEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return FeatureEvent_ADDED
+}
+
+func (m *FeatureEvent) GetFeature() *Feature {
+	if m != nil {
+		return m.Feature
+	}
+	return nil
+}
+
+func (m *FeatureEvent) GetResourceVersion() int64 {
+	if m != nil {
+		return m.ResourceVersion
+	}
+	return 0
+}
+
+// A Route recorded by RecordRoute or RecordTimedRoute.
+type Route struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The identity of the client that recorded the route.
+	ClientId string `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	// The points of the route, in the order they were received.
+	Points     []*Point             `protobuf:"bytes,3,rep,name=points,proto3" json:"points,omitempty"`
+	StartedAt  *timestamp.Timestamp `protobuf:"bytes,4,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt *timestamp.Timestamp `protobuf:"bytes,5,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	Summary    *RouteSummary        `protobuf:"bytes,6,opt,name=summary,proto3" json:"summary,omitempty"`
+	// The times the points were recorded at, if the route was recorded by
+	// RecordTimedRoute and every point carries a time.
+	PointTimes           []*timestamp.Timestamp `protobuf:"bytes,7,rep,name=point_times,json=pointTimes,proto3" json:"point_times,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *Route) Reset()         { *m = Route{} }
+func (m *Route) String() string { return proto.CompactTextString(m) }
+func (*Route) ProtoMessage()    {}
+func (*Route) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{17}
+}
+
+func (m *Route) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Route.Unmarshal(m, b)
+}
+func (m *Route) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Route.Marshal(b, m, deterministic)
+}
+func (m *Route) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Route.Merge(m, src)
+}
+func (m *Route) XXX_Size() int {
+	return xxx_messageInfo_Route.Size(m)
+}
+func (m *Route) XXX_DiscardUnknown() {
+	xxx_messageInfo_Route.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Route proto.InternalMessageInfo
+
+func (m *Route) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Route) GetClientId() string {
+	if m != nil {
+		return m.ClientId
+	}
+	return ""
+}
+
+func (m *Route) GetPoints() []*Point {
+	if m != nil {
+		return m.Points
+	}
+	return nil
+}
+
+func (m *Route) GetStartedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.StartedAt
+	}
+	return nil
+}
+
+func (m *Route) GetFinishedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.FinishedAt
+	}
+	return nil
+}
+
+func (m *Route) GetSummary() *RouteSummary {
+	if m != nil {
+		return m.Summary
+	}
+	return nil
+}
+
+func (m *Route) GetPointTimes() []*timestamp.Timestamp {
+	if m != nil {
+		return m.PointTimes
+	}
+	return nil
+}
+
+// A request for the recorded Route with the given ID.
+type RouteRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RouteRequest) Reset()         { *m = RouteRequest{} }
+func (m *RouteRequest) String() string { return proto.CompactTextString(m) }
+func (*RouteRequest) ProtoMessage()    {}
+func (*RouteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{18}
+}
+
+func (m *RouteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RouteRequest.Unmarshal(m, b)
+}
+func (m *RouteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RouteRequest.Marshal(b, m, deterministic)
+}
+func (m *RouteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RouteRequest.Merge(m, src)
+}
+func (m *RouteRequest) XXX_Size() int {
+	return xxx_messageInfo_RouteRequest.Size(m)
+}
+func (m *RouteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RouteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RouteRequest proto.InternalMessageInfo
+
+func (m *RouteRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// A request for the recorded Routes that match all the given filters.
+type ListRoutesRequest struct {
+	// If set, only the Routes recorded by this client are listed.
+	ClientId string `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	// If set, only the Routes started at or after this time are listed.
+	StartedAfter *timestamp.Timestamp `protobuf:"bytes,2,opt,name=started_after,json=startedAfter,proto3" json:"started_after,omitempty"`
+	// If set, only the Routes started before this time are listed.
+	StartedBefore        *timestamp.Timestamp `protobuf:"bytes,3,opt,name=started_before,json=startedBefore,proto3" json:"started_before,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *ListRoutesRequest) Reset()         { *m = ListRoutesRequest{} }
+func (m *ListRoutesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListRoutesRequest) ProtoMessage()    {}
+func (*ListRoutesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{19}
+}
+
+func (m *ListRoutesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRoutesRequest.Unmarshal(m, b)
+}
+func (m *ListRoutesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRoutesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListRoutesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRoutesRequest.Merge(m, src)
+}
+func (m *ListRoutesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListRoutesRequest.Size(m)
+}
+func (m *ListRoutesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRoutesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRoutesRequest proto.InternalMessageInfo
+
+func (m *ListRoutesRequest) GetClientId() string {
+	if m != nil {
+		return m.ClientId
+	}
+	return ""
+}
+
+func (m *ListRoutesRequest) GetStartedAfter() *timestamp.Timestamp {
+	if m != nil {
+		return m.StartedAfter
+	}
+	return nil
+}
+
+func (m *ListRoutesRequest) GetStartedBefore() *timestamp.Timestamp {
+	if m != nil {
+		return m.StartedBefore
+	}
+	return nil
+}
+
+// A recorded Route, exported into a document format.
+type RouteExport struct {
+	// The media type of the document, e.g. application/gpx+xml.
+	ContentType          string   `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Data                 []byte   `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RouteExport) Reset()         { *m = RouteExport{} }
+func (m *RouteExport) String() string { return proto.CompactTextString(m) }
+func (*RouteExport) ProtoMessage()    {}
+func (*RouteExport) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{20}
+}
+
+func (m *RouteExport) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RouteExport.Unmarshal(m, b)
+}
+func (m *RouteExport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RouteExport.Marshal(b, m, deterministic)
+}
+func (m *RouteExport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RouteExport.Merge(m, src)
+}
+func (m *RouteExport) XXX_Size() int {
+	return xxx_messageInfo_RouteExport.Size(m)
+}
+func (m *RouteExport) XXX_DiscardUnknown() {
+	xxx_messageInfo_RouteExport.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RouteExport proto.InternalMessageInfo
+
+func (m *RouteExport) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *RouteExport) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// A Participant is a RouteChat participant at a location.
+type Participant struct {
+	// The identity of the participant.
+	Id       string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Location *Point `protobuf:"bytes,2,opt,name=location,proto3" json:"location,omitempty"`
+	// The time the participant arrived at the location.
+	JoinedAt             *timestamp.Timestamp `protobuf:"bytes,3,opt,name=joined_at,json=joinedAt,proto3" json:"joined_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Participant) Reset()         { *m = Participant{} }
+func (m *Participant) String() string { return proto.CompactTextString(m) }
+func (*Participant) ProtoMessage()    {}
+func (*Participant) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{21}
+}
+
+func (m *Participant) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Participant.Unmarshal(m, b)
+}
+func (m *Participant) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Participant.Marshal(b, m, deterministic)
+}
+func (m *Participant) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Participant.Merge(m, src)
+}
+func (m *Participant) XXX_Size() int {
+	return xxx_messageInfo_Participant.Size(m)
+}
+func (m *Participant) XXX_DiscardUnknown() {
+	xxx_messageInfo_Participant.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Participant proto.InternalMessageInfo
+
+func (m *Participant) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Participant) GetLocation() *Point {
+	if m != nil {
+		return m.Location
+	}
+	return nil
+}
+
+func (m *Participant) GetJoinedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.JoinedAt
+	}
+	return nil
+}
+
+// A ParticipantList is received in response to a ListParticipants rpc. The
+// participants are ordered by the time they arrived at the location.
+type ParticipantList struct {
+	Participants         []*Participant `protobuf:"bytes,1,rep,name=participants,proto3" json:"participants,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *ParticipantList) Reset()         { *m = ParticipantList{} }
+func (m *ParticipantList) String() string { return proto.CompactTextString(m) }
+func (*ParticipantList) ProtoMessage()    {}
+func (*ParticipantList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{22}
+}
+
+func (m *ParticipantList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ParticipantList.Unmarshal(m, b)
+}
+func (m *ParticipantList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ParticipantList.Marshal(b, m, deterministic)
+}
+func (m *ParticipantList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ParticipantList.Merge(m, src)
+}
+func (m *ParticipantList) XXX_Size() int {
+	return xxx_messageInfo_ParticipantList.Size(m)
+}
+func (m *ParticipantList) XXX_DiscardUnknown() {
+	xxx_messageInfo_ParticipantList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ParticipantList proto.InternalMessageInfo
+
+func (m *ParticipantList) GetParticipants() []*Participant {
+	if m != nil {
+		return m.Participants
+	}
+	return nil
+}
+
+// A Geofence is an area whose clients are tracked.
+type Geofence struct {
+	// The ID of the Geofence, assigned by the server.
+	Id   string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// The area of the Geofence. Exactly one of them must be set.
+	//
+	// Types that are valid to be assigned to Area:
+	//	*Geofence_Circle
+	//	*Geofence_Polygon
+	Area isGeofence_Area `protobuf_oneof:"area"`
+	// How long a client must stay within the Geofence before a DWELL event is
+	// sent. If it's unset, no DWELL events are sent.
+	Dwell                *duration.Duration `protobuf:"bytes,5,opt,name=dwell,proto3" json:"dwell,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *Geofence) Reset()         { *m = Geofence{} }
+func (m *Geofence) String() string { return proto.CompactTextString(m) }
+func (*Geofence) ProtoMessage()    {}
+func (*Geofence) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{23}
+}
+
+func (m *Geofence) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Geofence.Unmarshal(m, b)
+}
+func (m *Geofence) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Geofence.Marshal(b, m, deterministic)
+}
+func (m *Geofence) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Geofence.Merge(m, src)
+}
+func (m *Geofence) XXX_Size() int {
+	return xxx_messageInfo_Geofence.Size(m)
+}
+func (m *Geofence) XXX_DiscardUnknown() {
+	xxx_messageInfo_Geofence.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Geofence proto.InternalMessageInfo
+
+func (m *Geofence) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Geofence) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type isGeofence_Area interface {
+	isGeofence_Area()
+}
+
+type Geofence_Circle struct {
+	Circle *Circle `protobuf:"bytes,3,opt,name=circle,proto3,oneof"`
+}
+
+type Geofence_Polygon struct {
+	Polygon *Polygon `protobuf:"bytes,4,opt,name=polygon,proto3,oneof"`
+}
+
+func (*Geofence_Circle) isGeofence_Area() {}
+
+func (*Geofence_Polygon) isGeofence_Area() {}
+
+func (m *Geofence) GetArea() isGeofence_Area {
+	if m != nil {
+		return m.Area
+	}
+	return nil
+}
+
+func (m *Geofence) GetCircle() *Circle {
+	if x, ok := m.GetArea().(*Geofence_Circle); ok {
+		return x.Circle
+	}
+	return nil
+}
+
+func (m *Geofence) GetPolygon() *Polygon {
+	if x, ok := m.GetArea().(*Geofence_Polygon); ok {
+		return x.Polygon
+	}
+	return nil
+}
+
+func (m *Geofence) GetDwell() *duration.Duration {
+	if m != nil {
+		return m.Dwell
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*Geofence) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*Geofence_Circle)(nil),
+		(*Geofence_Polygon)(nil),
+	}
+}
+
+type WatchGeofenceEventsRequest struct {
+	// The IDs of the Geofences to watch. If it's empty, all the Geofences are
+	// watched, including the ones created after the watch started.
+	GeofenceIds          []string `protobuf:"bytes,1,rep,name=geofence_ids,json=geofenceIds,proto3" json:"geofence_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchGeofenceEventsRequest) Reset()         { *m = WatchGeofenceEventsRequest{} }
+func (m *WatchGeofenceEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchGeofenceEventsRequest) ProtoMessage()    {}
+func (*WatchGeofenceEventsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{24}
+}
+
+func (m *WatchGeofenceEventsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchGeofenceEventsRequest.Unmarshal(m, b)
+}
+func (m *WatchGeofenceEventsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchGeofenceEventsRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchGeofenceEventsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchGeofenceEventsRequest.Merge(m, src)
+}
+func (m *WatchGeofenceEventsRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchGeofenceEventsRequest.Size(m)
+}
+func (m *WatchGeofenceEventsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchGeofenceEventsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchGeofenceEventsRequest proto.InternalMessageInfo
+
+func (m *WatchGeofenceEventsRequest) GetGeofenceIds() []string {
+	if m != nil {
+		return m.GeofenceIds
+	}
+	return nil
+}
+
+// A GeofenceEvent is sent when a client enters, exits or dwells in a
+// Geofence.
+type GeofenceEvent struct {
+	Type       GeofenceEvent_Type `protobuf:"varint,1,opt,name=type,proto3,enum=routeguideproto.GeofenceEvent_Type" json:"type,omitempty"`
+	GeofenceId string             `protobuf:"bytes,2,opt,name=geofence_id,json=geofenceId,proto3" json:"geofence_id,omitempty"`
+	// The identity of the client.
+	ClientId string `protobuf:"bytes,3,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	// The point that the client sent. DWELL events, and the EXIT events of
+	// ended streams, carry the last point the client sent within the Geofence.
+	Location *Point `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+	// The time the event occurred at. The events of RecordTimedRoute are timed
+	// by the times the points were recorded at, and their time is unset until
+	// a point with a time is received.
+	Time                 *timestamp.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *GeofenceEvent) Reset()         { *m = GeofenceEvent{} }
+func (m *GeofenceEvent) String() string { return proto.CompactTextString(m) }
+func (*GeofenceEvent) ProtoMessage()    {}
+func (*GeofenceEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7d679f20da65b7b, []int{25}
+}
+
+func (m *GeofenceEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GeofenceEvent.Unmarshal(m, b)
+}
+func (m *GeofenceEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GeofenceEvent.Marshal(b, m, deterministic)
+}
+func (m *GeofenceEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GeofenceEvent.Merge(m, src)
+}
+func (m *GeofenceEvent) XXX_Size() int {
+	return xxx_messageInfo_GeofenceEvent.Size(m)
+}
+func (m *GeofenceEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_GeofenceEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GeofenceEvent proto.InternalMessageInfo
+
+func (m *GeofenceEvent) GetType() GeofenceEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return GeofenceEvent_ENTER
+}
+
+func (m *GeofenceEvent) GetGeofenceId() string {
+	if m != nil {
+		return m.GeofenceId
+	}
+	return ""
+}
+
+func (m *GeofenceEvent) GetClientId() string {
+	if m != nil {
+		return m.ClientId
+	}
+	return ""
+}
+
+func (m *GeofenceEvent) GetLocation() *Point {
+	if m != nil {
+		return m.Location
+	}
+	return nil
+}
+
+func (m *GeofenceEvent) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("routeguideproto.RouteNote_Kind", RouteNote_Kind_name, RouteNote_Kind_value)
+	proto.RegisterEnum("routeguideproto.ListFeaturesPageRequest_Order", ListFeaturesPageRequest_Order_name, ListFeaturesPageRequest_Order_value)
+	proto.RegisterEnum("routeguideproto.FeatureEvent_Type", FeatureEvent_Type_name, FeatureEvent_Type_value)
+	proto.RegisterEnum("routeguideproto.GeofenceEvent_Type", GeofenceEvent_Type_name, GeofenceEvent_Type_value)
+	proto.RegisterType((*Point)(nil), "routeguideproto.Point")
+	proto.RegisterType((*Rectangle)(nil), "routeguideproto.Rectangle")
+	proto.RegisterType((*Circle)(nil), "routeguideproto.Circle")
+	proto.RegisterType((*Polygon)(nil), "routeguideproto.Polygon")
+	proto.RegisterType((*Feature)(nil), "routeguideproto.Feature")
+	proto.RegisterType((*DeleteFeatureRequest)(nil), "routeguideproto.DeleteFeatureRequest")
+	proto.RegisterType((*RouteNote)(nil), "routeguideproto.RouteNote")
+	proto.RegisterType((*RouteSummary)(nil), "routeguideproto.RouteSummary")
+	proto.RegisterType((*TimedPoint)(nil), "routeguideproto.TimedPoint")
+	proto.RegisterType((*RouteGap)(nil), "routeguideproto.RouteGap")
+	proto.RegisterType((*NearestRequest)(nil), "routeguideproto.NearestRequest")
+	proto.RegisterType((*NearbyFeature)(nil), "routeguideproto.NearbyFeature")
+	proto.RegisterType((*NearestResponse)(nil), "routeguideproto.NearestResponse")
+	proto.RegisterType((*ListFeaturesPageRequest)(nil), "routeguideproto.ListFeaturesPageRequest")
+	proto.RegisterType((*ListFeaturesPageResponse)(nil), "routeguideproto.ListFeaturesPageResponse")
+	proto.RegisterType((*WatchFeaturesRequest)(nil), "routeguideproto.WatchFeaturesRequest")
+	proto.RegisterType((*FeatureEvent)(nil), "routeguideproto.FeatureEvent")
+	proto.RegisterType((*Route)(nil), "routeguideproto.Route")
+	proto.RegisterType((*RouteRequest)(nil), "routeguideproto.RouteRequest")
+	proto.RegisterType((*ListRoutesRequest)(nil), "routeguideproto.ListRoutesRequest")
+	proto.RegisterType((*RouteExport)(nil), "routeguideproto.RouteExport")
+	proto.RegisterType((*Participant)(nil), "routeguideproto.Participant")
+	proto.RegisterType((*ParticipantList)(nil), "routeguideproto.ParticipantList")
+	proto.RegisterType((*Geofence)(nil), "routeguideproto.Geofence")
+	proto.RegisterType((*WatchGeofenceEventsRequest)(nil), "routeguideproto.WatchGeofenceEventsRequest")
+	proto.RegisterType((*GeofenceEvent)(nil), "routeguideproto.GeofenceEvent")
+}
+
+func init() { proto.RegisterFile("route_guide.proto", fileDescriptor_b7d679f20da65b7b) }
+
+var fileDescriptor_b7d679f20da65b7b = []byte{
+	// 2023 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x58, 0xdd, 0x72, 0xdb, 0xc6,
+	0x15, 0x26, 0xf8, 0x23, 0x82, 0x87, 0x3f, 0xa2, 0x37, 0x69, 0x02, 0xd3, 0x8e, 0xa5, 0x20, 0x53,
+	0x57, 0x99, 0x24, 0xb4, 0x2b, 0x7b, 0xea, 0xb4, 0x9d, 0x4e, 0x42, 0x91, 0xb0, 0xcd, 0x46, 0xa2,
+	0x34, 0x2b, 0x39, 0x69, 0x2e, 0x3a, 0x1c, 0x88, 0x58, 0x51, 0x6b, 0x91, 0x00, 0x0a, 0x2c, 0x15,
+	0x29, 0xed, 0x45, 0xaf, 0x3b, 0xbd, 0xea, 0x6d, 0x5f, 0xa0, 0x0f, 0xd0, 0x07, 0xe9, 0xf4, 0x05,
+	0xfa, 0x2a, 0x9d, 0xfd, 0x03, 0x49, 0x91, 0x20, 0x99, 0xe4, 0x8e, 0xf8, 0xf6, 0xdb, 0x73, 0xce,
+	0x9e, 0x3d, 0x7f, 0x4b, 0xb8, 0x17, 0x05, 0x13, 0x46, 0xfa, 0xc3, 0x09, 0xf5, 0x48, 0x33, 0x8c,
+	0x02, 0x16, 0xa0, 0x6d, 0x01, 0x09, 0x44, 0x00, 0x8d, 0x47, 0xc3, 0x20, 0x18, 0x8e, 0xc8, 0x13,
+	0xf1, 0x75, 0x3e, 0xb9, 0x78, 0xe2, 0x4d, 0x22, 0x97, 0xd1, 0xc0, 0x97, 0x1b, 0x1a, 0x3b, 0x77,
+	0xd7, 0x19, 0x1d, 0x93, 0x98, 0xb9, 0xe3, 0x50, 0x12, 0xec, 0x16, 0x14, 0x4e, 0x02, 0xea, 0x33,
+	0xd4, 0x00, 0x73, 0xe4, 0x32, 0xca, 0x26, 0x1e, 0xb1, 0x8c, 0x5d, 0x63, 0xaf, 0x80, 0x93, 0x6f,
+	0xf4, 0x10, 0x4a, 0xa3, 0xc0, 0x1f, 0xca, 0xc5, 0xac, 0x58, 0x9c, 0x02, 0xf6, 0x77, 0x50, 0xc2,
+	0x64, 0xc0, 0x5c, 0x7f, 0x38, 0x22, 0xe8, 0x31, 0x64, 0x47, 0x81, 0x10, 0x50, 0xde, 0x7f, 0xaf,
+	0x79, 0xc7, 0xdc, 0xa6, 0x50, 0x85, 0xb3, 0xa3, 0x80, 0xf3, 0x2e, 0xa9, 0x95, 0x5d, 0xcd, 0xbb,
+	0xa4, 0xdc, 0xac, 0x20, 0xa2, 0xc4, 0x67, 0xc4, 0xb3, 0x72, 0xbb, 0xc6, 0x9e, 0x89, 0x93, 0x6f,
+	0xfb, 0x8f, 0xb0, 0xd5, 0xa6, 0xd1, 0x60, 0x44, 0x50, 0x13, 0xb6, 0x06, 0x1c, 0x8b, 0xd6, 0x68,
+	0x56, 0x2c, 0xf4, 0x11, 0x54, 0x23, 0xd7, 0xa3, 0x93, 0xb8, 0x3f, 0x26, 0x8c, 0x44, 0xb1, 0x30,
+	0xc4, 0xc0, 0x15, 0x09, 0x1e, 0x09, 0xcc, 0xfe, 0x1d, 0x14, 0x4f, 0x82, 0xd1, 0xed, 0x30, 0xf0,
+	0xd1, 0x3e, 0x98, 0xd7, 0x24, 0x62, 0x74, 0x40, 0x62, 0xcb, 0xd8, 0xcd, 0xad, 0xd0, 0x90, 0xf0,
+	0xec, 0x2b, 0x28, 0xbe, 0x24, 0x2e, 0x9b, 0x44, 0x04, 0x21, 0xc8, 0xfb, 0xee, 0x58, 0xfa, 0xb5,
+	0x84, 0xc5, 0x6f, 0x2e, 0x72, 0x14, 0x0c, 0xc4, 0x5d, 0xad, 0x71, 0x43, 0xc2, 0x43, 0x16, 0x14,
+	0xaf, 0x49, 0x14, 0xf3, 0x2d, 0xdc, 0x17, 0x39, 0xac, 0x3f, 0x6d, 0x0f, 0xde, 0xed, 0x90, 0x11,
+	0x61, 0x44, 0xa9, 0xc4, 0xe4, 0x4f, 0x13, 0x12, 0xb3, 0x39, 0x2d, 0xc6, 0x0f, 0xd7, 0x92, 0x9d,
+	0xd7, 0xf2, 0xf7, 0x1c, 0x94, 0x30, 0xdf, 0xdd, 0x0b, 0x18, 0xf9, 0xb1, 0xb2, 0xc7, 0x24, 0x8e,
+	0xdd, 0xa1, 0x8c, 0xa3, 0x12, 0xd6, 0x9f, 0xe8, 0x19, 0xe4, 0xaf, 0xa8, 0x2f, 0x2f, 0xb9, 0xb6,
+	0xbf, 0xb3, 0x20, 0x29, 0xd1, 0xdb, 0xfc, 0x8a, 0xfa, 0x1e, 0x16, 0x64, 0xd4, 0x84, 0xbc, 0x1b,
+	0x11, 0xd7, 0xca, 0x0b, 0xf5, 0x8d, 0xc5, 0x4d, 0x3a, 0x2e, 0xb1, 0xe0, 0xa1, 0x1a, 0x64, 0xa9,
+	0x67, 0x15, 0x84, 0xe6, 0x2c, 0xf5, 0xd0, 0x03, 0x28, 0xc5, 0xc4, 0xf7, 0x48, 0xd4, 0xa7, 0x9e,
+	0xb5, 0x25, 0x60, 0x53, 0x02, 0x5d, 0x0f, 0x3d, 0x83, 0x62, 0x4c, 0x7c, 0xd6, 0x77, 0x99, 0x55,
+	0x54, 0xf2, 0x65, 0x36, 0x35, 0x75, 0x36, 0x35, 0xcf, 0x74, 0x36, 0xe1, 0x2d, 0x4e, 0x6d, 0x89,
+	0x34, 0x8a, 0xb9, 0xef, 0xfd, 0x01, 0xb1, 0x4c, 0xe1, 0xbd, 0xe4, 0xdb, 0xee, 0x41, 0x9e, 0xdb,
+	0x8e, 0x4c, 0xc8, 0xf7, 0x8e, 0xcf, 0x9c, 0x7a, 0x06, 0x55, 0xa1, 0x74, 0xfa, 0xe6, 0xe0, 0xb4,
+	0x8d, 0xbb, 0x07, 0x4e, 0xdd, 0x40, 0xdb, 0x50, 0x7e, 0xd3, 0x9b, 0x02, 0x59, 0x54, 0x84, 0x5c,
+	0xab, 0xfd, 0x55, 0x3d, 0xc7, 0xb7, 0xfc, 0xfe, 0xb8, 0xdb, 0xab, 0xe7, 0x51, 0x09, 0x0a, 0x87,
+	0x4e, 0xeb, 0x6b, 0xa7, 0x5e, 0xb0, 0xff, 0x99, 0x87, 0x8a, 0x70, 0xcb, 0xe9, 0x64, 0x3c, 0x76,
+	0xa3, 0x5b, 0xb4, 0x03, 0xe5, 0x90, 0x3b, 0xbc, 0x3f, 0x08, 0x26, 0x3e, 0x53, 0x69, 0x0c, 0x02,
+	0x6a, 0x73, 0x84, 0xc7, 0xfd, 0x85, 0x0c, 0x10, 0x45, 0x91, 0xc9, 0x5c, 0x51, 0xa0, 0x24, 0x35,
+	0xc0, 0xf4, 0x68, 0xcc, 0x5c, 0x7e, 0x84, 0x9c, 0xac, 0x04, 0xfa, 0x1b, 0x7d, 0x08, 0x15, 0x32,
+	0x72, 0xc3, 0x98, 0x78, 0x7d, 0x5e, 0x49, 0x84, 0xe3, 0x0b, 0xb8, 0xac, 0x30, 0xee, 0x0e, 0xf4,
+	0x09, 0xdc, 0x8b, 0xc9, 0x70, 0xcc, 0x3d, 0xa7, 0xb7, 0xc5, 0x56, 0x61, 0x37, 0xb7, 0x67, 0xe0,
+	0xba, 0x5a, 0xe8, 0x68, 0x1c, 0xfd, 0x02, 0xb6, 0xa9, 0x4f, 0x19, 0x75, 0x47, 0xfd, 0x73, 0xe2,
+	0x46, 0xd4, 0x1f, 0x8a, 0x6b, 0x30, 0x70, 0x4d, 0xc1, 0x07, 0x12, 0x15, 0x96, 0x53, 0x7f, 0x86,
+	0x56, 0x94, 0x19, 0x2b, 0x40, 0x4d, 0x6a, 0xc1, 0xb6, 0x3a, 0x49, 0xdc, 0x0f, 0xdd, 0x38, 0x26,
+	0x9e, 0x65, 0x8a, 0x6c, 0xb5, 0x16, 0x22, 0x43, 0xe7, 0x49, 0x4d, 0x6f, 0x38, 0x11, 0x7c, 0x74,
+	0x1f, 0x4c, 0x59, 0x76, 0xa9, 0x67, 0x95, 0x64, 0x84, 0x8a, 0xef, 0xae, 0xc7, 0x4d, 0x70, 0xaf,
+	0x49, 0xe4, 0x0e, 0x49, 0x3f, 0x0e, 0x09, 0xf1, 0x2c, 0x90, 0x26, 0x28, 0xf0, 0x94, 0x63, 0x3c,
+	0xa2, 0xc6, 0xee, 0x8d, 0x22, 0x94, 0x05, 0xc1, 0x1c, 0xbb, 0x37, 0x72, 0x71, 0x07, 0xca, 0xe3,
+	0xe0, 0x9a, 0xfa, 0x43, 0xe9, 0xbc, 0x8a, 0xbc, 0x1f, 0x09, 0x09, 0xdf, 0x3d, 0x80, 0x12, 0xf5,
+	0x46, 0x44, 0x2e, 0x57, 0xa5, 0xef, 0x39, 0x20, 0x16, 0x3f, 0x83, 0xfc, 0xd0, 0x0d, 0x63, 0xab,
+	0x26, 0x8e, 0x74, 0x7f, 0x79, 0x86, 0xbc, 0x72, 0x43, 0x2c, 0x68, 0xf6, 0x5b, 0x00, 0xbe, 0xcd,
+	0x93, 0xe5, 0xfd, 0x53, 0x28, 0x88, 0x38, 0x58, 0x93, 0xa9, 0x92, 0xc4, 0xf3, 0x4a, 0x98, 0x90,
+	0x5d, 0x1b, 0xf7, 0x82, 0x67, 0xbf, 0x05, 0x53, 0x6b, 0x47, 0x4f, 0xa1, 0x10, 0x33, 0x37, 0xd2,
+	0x9a, 0x56, 0x6d, 0x96, 0x44, 0xf4, 0x29, 0xe4, 0x88, 0xef, 0x6d, 0xa0, 0x8c, 0xd3, 0xec, 0xbf,
+	0x40, 0xad, 0x47, 0xdc, 0x88, 0xc4, 0x4c, 0x17, 0xb9, 0x1f, 0x76, 0xb6, 0x0a, 0x18, 0x57, 0x2a,
+	0xee, 0x8d, 0x2b, 0xd4, 0x84, 0x77, 0xf8, 0x7d, 0xe9, 0x48, 0xd5, 0xfd, 0x20, 0x27, 0x6e, 0xee,
+	0xde, 0xd8, 0xbd, 0xd1, 0xb1, 0xaa, 0x9a, 0xc2, 0x08, 0xaa, 0x5c, 0xfb, 0xf9, 0xad, 0xae, 0xed,
+	0xfb, 0x50, 0x54, 0x21, 0xa4, 0xd4, 0xa7, 0xc7, 0x9a, 0x26, 0xf2, 0xa8, 0xbf, 0xab, 0x50, 0x36,
+	0xa0, 0x9a, 0x37, 0xaf, 0xed, 0x08, 0xb6, 0x93, 0xb3, 0xc6, 0x61, 0xe0, 0xc7, 0x04, 0xfd, 0x06,
+	0x4c, 0x1d, 0xb2, 0xaa, 0x15, 0x3d, 0x5a, 0x50, 0x38, 0x67, 0x21, 0x4e, 0xf8, 0xf6, 0xbf, 0x72,
+	0xf0, 0xfe, 0x21, 0x8d, 0xd9, 0xcb, 0x24, 0xe6, 0x87, 0x49, 0xa7, 0xf8, 0x1c, 0x4a, 0x91, 0xae,
+	0x96, 0xc9, 0xd5, 0xa5, 0xd7, 0xd3, 0x29, 0x99, 0x07, 0x6d, 0x28, 0x92, 0x82, 0x7e, 0xaf, 0xa7,
+	0x03, 0x93, 0x03, 0xa7, 0xf4, 0x7b, 0x82, 0x3e, 0x00, 0x10, 0x8b, 0x2c, 0xb8, 0x22, 0xb2, 0x6b,
+	0x95, 0xb0, 0xa0, 0x9f, 0x71, 0x00, 0x75, 0x79, 0x7b, 0xe7, 0xf5, 0xf7, 0xfc, 0x56, 0xd4, 0x92,
+	0xda, 0x7e, 0x73, 0x41, 0x69, 0x8a, 0xc5, 0xcd, 0x63, 0xbe, 0x11, 0x17, 0xc5, 0xfe, 0x83, 0x5b,
+	0xf4, 0x9c, 0x1f, 0xe0, 0x82, 0x44, 0xa2, 0xf4, 0x16, 0x56, 0x46, 0xc2, 0x94, 0xc8, 0x53, 0x92,
+	0xb7, 0xe3, 0x7e, 0x18, 0x91, 0x0b, 0x7a, 0xa3, 0x7a, 0x00, 0x70, 0xe8, 0x44, 0x20, 0xfc, 0x00,
+	0x82, 0x10, 0x91, 0x21, 0xb9, 0x11, 0x55, 0xa7, 0x84, 0x4b, 0x1c, 0xc1, 0x1c, 0xe0, 0x57, 0x49,
+	0x6e, 0x06, 0xa3, 0x89, 0x47, 0xfa, 0x13, 0x9f, 0xc3, 0x9e, 0x28, 0xfb, 0x26, 0xae, 0x29, 0xf8,
+	0x8d, 0x44, 0xed, 0xcf, 0xa0, 0x20, 0x0c, 0x46, 0x15, 0x30, 0x0f, 0x8f, 0xdb, 0xad, 0xb3, 0xee,
+	0x71, 0xaf, 0x9e, 0x11, 0xbd, 0xa0, 0x75, 0xc4, 0x8b, 0x7f, 0x05, 0xcc, 0x4e, 0xf7, 0xf4, 0xac,
+	0xd5, 0x6b, 0x3b, 0xf5, 0xac, 0x7d, 0x03, 0xd6, 0xe2, 0xb9, 0x55, 0x08, 0x3c, 0x5f, 0x08, 0x81,
+	0xf4, 0x98, 0x4b, 0x98, 0xe8, 0x31, 0x6c, 0xfb, 0xe4, 0x86, 0xf5, 0x67, 0xae, 0x43, 0xb6, 0xe0,
+	0x2a, 0x87, 0x4f, 0xf4, 0x95, 0xd8, 0x7f, 0x86, 0x77, 0xbf, 0x71, 0xd9, 0xe0, 0x52, 0xab, 0xfe,
+	0xe9, 0x01, 0xf2, 0x31, 0xd4, 0x23, 0x12, 0x07, 0x93, 0x68, 0x40, 0xfa, 0xf3, 0x93, 0xc5, 0xb6,
+	0xc6, 0xbf, 0x96, 0xb0, 0xfd, 0x3f, 0x03, 0x2a, 0x4a, 0xb1, 0x73, 0x4d, 0x7c, 0x86, 0x7e, 0x05,
+	0x79, 0x76, 0x1b, 0x4a, 0x85, 0xb5, 0x7d, 0x3b, 0xed, 0x9c, 0x82, 0xdc, 0x3c, 0xbb, 0x0d, 0x09,
+	0x16, 0xfc, 0xd9, 0xb4, 0xcc, 0x6e, 0x9a, 0x96, 0xcb, 0xec, 0xcc, 0x2d, 0xb7, 0xf3, 0x73, 0xc8,
+	0x73, 0x65, 0xbc, 0x1b, 0xb7, 0x3a, 0x1d, 0xa7, 0x53, 0xcf, 0xf0, 0xfb, 0x3b, 0x3a, 0xee, 0x74,
+	0x5f, 0x76, 0x9d, 0x4e, 0xdd, 0x40, 0x65, 0x28, 0x76, 0x9c, 0x43, 0xe7, 0xcc, 0xe9, 0xd4, 0xb3,
+	0x08, 0x60, 0xeb, 0xf4, 0xdb, 0x5e, 0xdb, 0xe9, 0xd4, 0x73, 0xf6, 0x7f, 0xb3, 0x50, 0x10, 0xb5,
+	0x52, 0x0d, 0x23, 0xc6, 0xec, 0x30, 0x32, 0x18, 0x51, 0xde, 0x37, 0xa9, 0xa7, 0xae, 0xc6, 0x94,
+	0x40, 0x97, 0x4f, 0x3a, 0x5b, 0xa2, 0x7c, 0xf1, 0xd2, 0xb4, 0x6a, 0xfe, 0x54, 0x2c, 0xf4, 0x6b,
+	0x00, 0x51, 0x5c, 0x89, 0xc7, 0xe7, 0x97, 0xfc, 0xda, 0xd2, 0x5a, 0x52, 0xec, 0x16, 0x43, 0xbf,
+	0x85, 0xf2, 0x05, 0xf5, 0x69, 0x7c, 0x29, 0xf7, 0x16, 0xd6, 0xee, 0x05, 0x4d, 0x6f, 0x31, 0xf4,
+	0x02, 0x8a, 0xb1, 0x9c, 0x46, 0x44, 0x2e, 0x95, 0xf7, 0x3f, 0x58, 0xde, 0xa7, 0xd4, 0xc8, 0x82,
+	0x35, 0x9b, 0x6b, 0x95, 0xb3, 0x8b, 0x78, 0xa1, 0x58, 0xc5, 0xdd, 0xdc, 0x3a, 0xad, 0x82, 0x2e,
+	0xbe, 0xed, 0x47, 0x6a, 0x10, 0xd2, 0xb1, 0x7a, 0xc7, 0xb5, 0xf6, 0xbf, 0x0d, 0xb8, 0xc7, 0xd3,
+	0x49, 0x90, 0x92, 0x88, 0x9e, 0x73, 0xb8, 0x71, 0xc7, 0xe1, 0x5f, 0x40, 0x35, 0x71, 0xe0, 0x05,
+	0x23, 0xd1, 0x06, 0xed, 0xa9, 0xa2, 0x7d, 0xc8, 0xf9, 0xa8, 0x05, 0x35, 0x2d, 0xe0, 0x9c, 0x5c,
+	0x04, 0x91, 0x1c, 0xa6, 0x56, 0x4b, 0xd0, 0x2a, 0x0f, 0xc4, 0x06, 0xbb, 0x03, 0x65, 0x61, 0xb1,
+	0x73, 0x13, 0x06, 0x11, 0xe3, 0xc3, 0xd7, 0x20, 0xf0, 0x19, 0x37, 0x38, 0xc9, 0x89, 0x12, 0x2e,
+	0x2b, 0x4c, 0xc4, 0x23, 0x82, 0xbc, 0xe7, 0x32, 0x57, 0x18, 0x5b, 0xc1, 0xe2, 0xb7, 0xfd, 0x37,
+	0x03, 0xca, 0x27, 0x2e, 0x7f, 0x95, 0xd0, 0xd0, 0xf5, 0x17, 0x9c, 0xf3, 0xa3, 0x5e, 0x22, 0x2f,
+	0xa0, 0xf4, 0x36, 0xa0, 0xbe, 0x8c, 0x90, 0xf5, 0xe7, 0x32, 0x25, 0xb9, 0xc5, 0xec, 0x53, 0xd8,
+	0x9e, 0xb1, 0x85, 0xdf, 0x09, 0xfa, 0x12, 0x2a, 0xe1, 0x14, 0xd2, 0x25, 0xed, 0xe1, 0xa2, 0x0d,
+	0x53, 0x12, 0x9e, 0xdb, 0x61, 0xff, 0xc7, 0x00, 0xf3, 0x15, 0x09, 0x2e, 0x44, 0x45, 0xbf, 0x7b,
+	0x3c, 0xfd, 0xf8, 0xca, 0xce, 0x3c, 0xbe, 0x7e, 0x09, 0x5b, 0x03, 0xf1, 0x72, 0x54, 0xb6, 0xbf,
+	0xbf, 0xa0, 0x4c, 0x3e, 0x2c, 0x5f, 0x67, 0xb0, 0x22, 0xa2, 0xe7, 0x50, 0x0c, 0xe5, 0x6b, 0xd0,
+	0xca, 0xa7, 0x14, 0x14, 0xf5, 0x5a, 0x7c, 0x9d, 0xc1, 0x9a, 0x8a, 0x9e, 0x40, 0xc1, 0xfb, 0x8e,
+	0x8c, 0x46, 0x2a, 0x8b, 0xee, 0x2f, 0xf8, 0xa8, 0xa3, 0xde, 0xeb, 0x58, 0xf2, 0x0e, 0xb6, 0xe4,
+	0x8b, 0xc6, 0xfe, 0x02, 0x1a, 0xa2, 0x0a, 0xeb, 0x63, 0x89, 0x02, 0x97, 0x44, 0xee, 0x87, 0x50,
+	0x19, 0xaa, 0x85, 0x3e, 0xf5, 0xa4, 0xcb, 0x4a, 0xb8, 0xac, 0xb1, 0xae, 0x17, 0xdb, 0xff, 0xc8,
+	0x42, 0x75, 0x6e, 0x33, 0x7a, 0x31, 0x57, 0x4a, 0x3f, 0x5a, 0x30, 0x7f, 0x8e, 0x3d, 0x5b, 0x4b,
+	0x77, 0xa0, 0x3c, 0xa3, 0x4d, 0x39, 0x12, 0xa6, 0xca, 0xe6, 0x13, 0x29, 0x77, 0x27, 0x91, 0x66,
+	0xc3, 0x2b, 0xbf, 0x61, 0x78, 0xe9, 0xf9, 0xb3, 0xb0, 0xe1, 0xfc, 0xf9, 0x78, 0x5a, 0x8e, 0x9d,
+	0xde, 0x99, 0x83, 0x65, 0x63, 0x75, 0xfe, 0xd0, 0x3d, 0xab, 0x1b, 0x1c, 0xec, 0x7c, 0xe3, 0x1c,
+	0x1e, 0xd6, 0xb3, 0xfb, 0x7f, 0xad, 0x00, 0xc8, 0x41, 0x95, 0xeb, 0x46, 0x5f, 0x02, 0xbc, 0x22,
+	0xba, 0xc7, 0xa2, 0x14, 0xb3, 0x1a, 0xa9, 0x9d, 0xc3, 0xce, 0xa0, 0xd7, 0x50, 0x99, 0x6d, 0xd3,
+	0x68, 0x45, 0x47, 0x5c, 0x25, 0xe7, 0xa9, 0x81, 0x5e, 0x43, 0x19, 0x93, 0x41, 0x10, 0x79, 0xb2,
+	0x39, 0xa4, 0x19, 0xb3, 0xba, 0x9c, 0xda, 0x99, 0x3d, 0x03, 0x61, 0xa8, 0x4b, 0x49, 0x62, 0xfc,
+	0x97, 0xe2, 0x1e, 0x2c, 0x6c, 0x9b, 0xbe, 0x0d, 0x36, 0x91, 0xd9, 0x55, 0x0f, 0xff, 0xf6, 0xa5,
+	0xcb, 0x96, 0x1d, 0x52, 0x3f, 0xce, 0x1b, 0x2b, 0xd6, 0xb8, 0xa0, 0xa7, 0x06, 0x72, 0xa0, 0xda,
+	0x8e, 0x88, 0x9b, 0xfc, 0x55, 0x81, 0x52, 0xfd, 0xb2, 0xd2, 0xf3, 0x0e, 0x54, 0xdf, 0x84, 0xde,
+	0x4f, 0x16, 0x83, 0xa1, 0x3a, 0xf7, 0xc7, 0x09, 0xfa, 0xf9, 0x02, 0x79, 0xd9, 0x1f, 0x2b, 0x6b,
+	0x64, 0x96, 0x5f, 0x52, 0xdf, 0x53, 0x93, 0x3b, 0xda, 0x59, 0x3a, 0x9f, 0x4f, 0xdf, 0x2f, 0x8d,
+	0xdd, 0x74, 0x82, 0x9c, 0xf8, 0xec, 0x0c, 0x3a, 0x82, 0x77, 0x67, 0x03, 0xad, 0xeb, 0x63, 0xf1,
+	0x57, 0x15, 0x4a, 0xab, 0x5c, 0x6b, 0xa2, 0xed, 0x18, 0x7e, 0x36, 0x2f, 0x4e, 0xff, 0xd3, 0x95,
+	0x5a, 0xd5, 0xd6, 0x08, 0xa4, 0x50, 0xbf, 0x3b, 0xaf, 0xa2, 0xbd, 0x4d, 0x47, 0xf9, 0xc6, 0xc7,
+	0x1b, 0x30, 0x13, 0x57, 0x7c, 0x0b, 0xd5, 0xb9, 0x01, 0x75, 0xc9, 0x95, 0x2d, 0x1b, 0x60, 0x97,
+	0x84, 0xf9, 0xec, 0xf0, 0x28, 0x4e, 0xd1, 0xe6, 0x7d, 0x44, 0x4e, 0x09, 0x28, 0x25, 0x2b, 0xb4,
+	0xb4, 0xf7, 0x96, 0x2f, 0xdb, 0x19, 0x74, 0x08, 0x30, 0x9d, 0x35, 0x90, 0xbd, 0xf4, 0x68, 0x73,
+	0x83, 0x48, 0xba, 0xac, 0xa7, 0x06, 0x3a, 0x84, 0xb2, 0x6c, 0xff, 0x1b, 0x59, 0xf5, 0x70, 0xf9,
+	0xb2, 0x94, 0x60, 0x67, 0x50, 0x4f, 0x5e, 0xd3, 0x4c, 0x2b, 0x8d, 0x53, 0x4b, 0xcd, 0xee, 0xaa,
+	0x0e, 0xcc, 0xa5, 0x88, 0xfa, 0x57, 0x93, 0xc9, 0x9c, 0xb4, 0xdf, 0xfb, 0xa9, 0x7d, 0xa5, 0x91,
+	0xbe, 0x64, 0x67, 0xd0, 0x05, 0xbc, 0xb3, 0xa4, 0xe1, 0xa1, 0x4f, 0x96, 0xdf, 0xed, 0xd2, 0xb6,
+	0xd8, 0x78, 0xb4, 0xba, 0xa7, 0x71, 0x7f, 0x9e, 0x6f, 0x89, 0x85, 0x67, 0xff, 0x1f, 0x00, 0xdd,
+	0xb8, 0xc5, 0xc3, 0x5e, 0x17, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// RouteGuideClient is the client API for RouteGuide service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type RouteGuideClient interface {
+	// Obtains the feature at a given position
+	GetFeature(ctx context.Context, in *Point, opts ...grpc.CallOption) (*Feature, error)
+	// Obtains the Features available within the given Rectangle. Results are
+	// streamed rather than returned at once (e.g. in a response message with a
+	// repeated field), as the rectangle may cover a large area and contain a
+	// huge number of features.
+	ListFeatures(ctx context.Context, in *Rectangle, opts ...grpc.CallOption) (RouteGuide_ListFeaturesClient, error)
+	// Accepts a stream of Points on a route being traversed, returning a
+	// RouteSummary when traversal is completed.
+	RecordRoute(ctx context.Context, opts ...grpc.CallOption) (RouteGuide_RecordRouteClient, error)
+	// Accepts a stream of TimedPoints on a route that was traversed, returning
+	// a RouteSummary when the stream is completed. Unlike RecordRoute, the
+	// summary is measured with the times the points were recorded at, so that
+	// tracks can be uploaded after they were recorded.
+	RecordTimedRoute(ctx context.Context, opts ...grpc.CallOption) (RouteGuide_RecordTimedRouteClient, error)
+	// Accepts a stream of RouteNotes sent while a route is being traversed,
+	// while receiving other RouteNotes (e.g. from other users). Sending a note
+	// subscribes the stream to the note's location. Streams can also subscribe
+	// to other locations and areas with SUBSCRIBE notes. The history of a
+	// location or area is sent once when the stream subscribes to it, followed
+	// by the notes sent to it by all the participants as they arrive. Every
+	// note sent by the stream is acknowledged with an ACK note, once it's
+	// accepted by the server.
+	RouteChat(ctx context.Context, opts ...grpc.CallOption) (RouteGuide_RouteChatClient, error)
+	// Creates a new Feature at an unoccupied location. The created Feature is
+	// returned with its version.
+	CreateFeature(ctx context.Context, in *Feature, opts ...grpc.CallOption) (*Feature, error)
+	// Updates the Feature at the given location. The version must match the
+	// version of the saved Feature, otherwise the update is rejected with a
+	// FAILED_PRECONDITION error. The updated Feature is returned with its new
+	// version.
+	UpdateFeature(ctx context.Context, in *Feature, opts ...grpc.CallOption) (*Feature, error)
+	// Deletes the Feature at the given location. The version must match the
+	// version of the saved Feature, otherwise the deletion is rejected with a
+	// FAILED_PRECONDITION error. The deleted Feature is returned.
+	DeleteFeature(ctx context.Context, in *DeleteFeatureRequest, opts ...grpc.CallOption) (*Feature, error)
+	// Obtains the k Features closest to the given Point, ordered by their
+	// geodesic distance from the Point. Unlike GetFeature, the Point doesn't
+	// have to match the location of a Feature exactly.
+	FindNearest(ctx context.Context, in *NearestRequest, opts ...grpc.CallOption) (*NearestResponse, error)
+	// Obtains the Features within the given Circle, measured by geodesic
+	// distance. Results are streamed rather than returned at once.
+	ListFeaturesInRadius(ctx context.Context, in *Circle, opts ...grpc.CallOption) (RouteGuide_ListFeaturesInRadiusClient, error)
+	// Obtains the Features within the given Polygon. Results are streamed
+	// rather than returned at once.
+	ListFeaturesInPolygon(ctx context.Context, in *Polygon, opts ...grpc.CallOption) (RouteGuide_ListFeaturesInPolygonClient, error)
+	// Obtains a page of the Features within the given Rectangle, ordered and
+	// filtered as requested. Subsequent pages are obtained by repeating the
+	// request with the page token of the previous response.
+	ListFeaturesPage(ctx context.Context, in *ListFeaturesPageRequest, opts ...grpc.CallOption) (*ListFeaturesPageResponse, error)
+	// Watches the Features within the given Rectangle. The current Features are
+	// sent first as ADDED events, followed by a SYNCED event. Subsequent changes
+	// are streamed as ADDED, MODIFIED and DELETED events. A watch that is
+	// resumed from a resource version skips the current Features, and streams
+	// the changes after that version.
+	WatchFeatures(ctx context.Context, in *WatchFeaturesRequest, opts ...grpc.CallOption) (RouteGuide_WatchFeaturesClient, error)
+	// Obtains the recorded Route with the given ID.
+	GetRoute(ctx context.Context, in *RouteRequest, opts ...grpc.CallOption) (*Route, error)
+	// Obtains the recorded Routes that match the request, in the order they
+	// were started. The Routes are streamed without their points.
+	ListRoutes(ctx context.Context, in *ListRoutesRequest, opts ...grpc.CallOption) (RouteGuide_ListRoutesClient, error)
+	// Exports the recorded Route with the given ID as a GPX 1.1 document.
+	ExportRoute(ctx context.Context, in *RouteRequest, opts ...grpc.CallOption) (*RouteExport, error)
+	// Obtains the RouteChat participants currently at the given Point. A
+	// participant is at the location of the last note it sent.
+	ListParticipants(ctx context.Context, in *Point, opts ...grpc.CallOption) (*ParticipantList, error)
+	// Registers a Geofence around a circle or a polygon. The created Geofence
+	// is returned with its ID. The points sent by the clients through
+	// RecordRoute and RouteChat are evaluated against all the Geofences.
+	CreateGeofence(ctx context.Context, in *Geofence, opts ...grpc.CallOption) (*Geofence, error)
+	// Streams the events of the Geofences with the given IDs, or of all the
+	// Geofences if no ID is given, as the clients enter, exit and dwell in
+	// them.
+	WatchGeofenceEvents(ctx context.Context, in *WatchGeofenceEventsRequest, opts ...grpc.CallOption) (RouteGuide_WatchGeofenceEventsClient, error)
+}
+
+type routeGuideClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewRouteGuideClient(cc *grpc.ClientConn) RouteGuideClient {
+	return &routeGuideClient{cc}
+}
+
+func (c *routeGuideClient) GetFeature(ctx context.Context, in *Point, opts ...grpc.CallOption) (*Feature, error) {
+	out := new(Feature)
+	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/GetFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routeGuideClient) ListFeatures(ctx context.Context, in *Rectangle, opts ...grpc.CallOption) (RouteGuide_ListFeaturesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[0], "/routeguideproto.RouteGuide/ListFeatures", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routeGuideListFeaturesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RouteGuide_ListFeaturesClient interface {
+	Recv() (*Feature, error)
+	grpc.ClientStream
+}
+
+type routeGuideListFeaturesClient struct {
+	grpc.ClientStream
+}
+
+func (x *routeGuideListFeaturesClient) Recv() (*Feature, error) {
+	m := new(Feature)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *routeGuideClient) RecordRoute(ctx context.Context, opts ...grpc.CallOption) (RouteGuide_RecordRouteClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[1], "/routeguideproto.RouteGuide/RecordRoute", opts...)
+	if err != nil {
 		return nil, err
 	}
 	x := &routeGuideRecordRouteClient{stream}
@@ -444,31 +2195,306 @@ func (x *routeGuideRecordRouteClient) CloseAndRecv() (*RouteSummary, error) {
 	return m, nil
 }
 
+func (c *routeGuideClient) RecordTimedRoute(ctx context.Context, opts ...grpc.CallOption) (RouteGuide_RecordTimedRouteClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[2], "/routeguideproto.RouteGuide/RecordTimedRoute", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routeGuideRecordTimedRouteClient{stream}
+	return x, nil
+}
+
+type RouteGuide_RecordTimedRouteClient interface {
+	Send(*TimedPoint) error
+	CloseAndRecv() (*RouteSummary, error)
+	grpc.ClientStream
+}
+
+type routeGuideRecordTimedRouteClient struct {
+	grpc.ClientStream
+}
+
+func (x *routeGuideRecordTimedRouteClient) Send(m *TimedPoint) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *routeGuideRecordTimedRouteClient) CloseAndRecv() (*RouteSummary, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(RouteSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *routeGuideClient) RouteChat(ctx context.Context, opts ...grpc.CallOption) (RouteGuide_RouteChatClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[2], "/routeguideproto.RouteGuide/RouteChat", opts...)
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[3], "/routeguideproto.RouteGuide/RouteChat", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routeGuideRouteChatClient{stream}
+	return x, nil
+}
+
+type RouteGuide_RouteChatClient interface {
+	Send(*RouteNote) error
+	Recv() (*RouteNote, error)
+	grpc.ClientStream
+}
+
+type routeGuideRouteChatClient struct {
+	grpc.ClientStream
+}
+
+func (x *routeGuideRouteChatClient) Send(m *RouteNote) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *routeGuideRouteChatClient) Recv() (*RouteNote, error) {
+	m := new(RouteNote)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *routeGuideClient) CreateFeature(ctx context.Context, in *Feature, opts ...grpc.CallOption) (*Feature, error) {
+	out := new(Feature)
+	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/CreateFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routeGuideClient) UpdateFeature(ctx context.Context, in *Feature, opts ...grpc.CallOption) (*Feature, error) {
+	out := new(Feature)
+	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/UpdateFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routeGuideClient) DeleteFeature(ctx context.Context, in *DeleteFeatureRequest, opts ...grpc.CallOption) (*Feature, error) {
+	out := new(Feature)
+	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/DeleteFeature", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routeGuideClient) FindNearest(ctx context.Context, in *NearestRequest, opts ...grpc.CallOption) (*NearestResponse, error) {
+	out := new(NearestResponse)
+	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/FindNearest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routeGuideClient) ListFeaturesInRadius(ctx context.Context, in *Circle, opts ...grpc.CallOption) (RouteGuide_ListFeaturesInRadiusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[4], "/routeguideproto.RouteGuide/ListFeaturesInRadius", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routeGuideListFeaturesInRadiusClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RouteGuide_ListFeaturesInRadiusClient interface {
+	Recv() (*Feature, error)
+	grpc.ClientStream
+}
+
+type routeGuideListFeaturesInRadiusClient struct {
+	grpc.ClientStream
+}
+
+func (x *routeGuideListFeaturesInRadiusClient) Recv() (*Feature, error) {
+	m := new(Feature)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *routeGuideClient) ListFeaturesInPolygon(ctx context.Context, in *Polygon, opts ...grpc.CallOption) (RouteGuide_ListFeaturesInPolygonClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[5], "/routeguideproto.RouteGuide/ListFeaturesInPolygon", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routeGuideListFeaturesInPolygonClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RouteGuide_ListFeaturesInPolygonClient interface {
+	Recv() (*Feature, error)
+	grpc.ClientStream
+}
+
+type routeGuideListFeaturesInPolygonClient struct {
+	grpc.ClientStream
+}
+
+func (x *routeGuideListFeaturesInPolygonClient) Recv() (*Feature, error) {
+	m := new(Feature)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *routeGuideClient) ListFeaturesPage(ctx context.Context, in *ListFeaturesPageRequest, opts ...grpc.CallOption) (*ListFeaturesPageResponse, error) {
+	out := new(ListFeaturesPageResponse)
+	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/ListFeaturesPage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routeGuideClient) WatchFeatures(ctx context.Context, in *WatchFeaturesRequest, opts ...grpc.CallOption) (RouteGuide_WatchFeaturesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[6], "/routeguideproto.RouteGuide/WatchFeatures", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routeGuideWatchFeaturesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RouteGuide_WatchFeaturesClient interface {
+	Recv() (*FeatureEvent, error)
+	grpc.ClientStream
+}
+
+type routeGuideWatchFeaturesClient struct {
+	grpc.ClientStream
+}
+
+func (x *routeGuideWatchFeaturesClient) Recv() (*FeatureEvent, error) {
+	m := new(FeatureEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *routeGuideClient) GetRoute(ctx context.Context, in *RouteRequest, opts ...grpc.CallOption) (*Route, error) {
+	out := new(Route)
+	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/GetRoute", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routeGuideClient) ListRoutes(ctx context.Context, in *ListRoutesRequest, opts ...grpc.CallOption) (RouteGuide_ListRoutesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[7], "/routeguideproto.RouteGuide/ListRoutes", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routeGuideListRoutesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RouteGuide_ListRoutesClient interface {
+	Recv() (*Route, error)
+	grpc.ClientStream
+}
+
+type routeGuideListRoutesClient struct {
+	grpc.ClientStream
+}
+
+func (x *routeGuideListRoutesClient) Recv() (*Route, error) {
+	m := new(Route)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *routeGuideClient) ExportRoute(ctx context.Context, in *RouteRequest, opts ...grpc.CallOption) (*RouteExport, error) {
+	out := new(RouteExport)
+	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/ExportRoute", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routeGuideClient) ListParticipants(ctx context.Context, in *Point, opts ...grpc.CallOption) (*ParticipantList, error) {
+	out := new(ParticipantList)
+	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/ListParticipants", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routeGuideClient) CreateGeofence(ctx context.Context, in *Geofence, opts ...grpc.CallOption) (*Geofence, error) {
+	out := new(Geofence)
+	err := c.cc.Invoke(ctx, "/routeguideproto.RouteGuide/CreateGeofence", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routeGuideClient) WatchGeofenceEvents(ctx context.Context, in *WatchGeofenceEventsRequest, opts ...grpc.CallOption) (RouteGuide_WatchGeofenceEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_serviceDesc.Streams[8], "/routeguideproto.RouteGuide/WatchGeofenceEvents", opts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &routeGuideRouteChatClient{stream}
+	x := &routeGuideWatchGeofenceEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
 	return x, nil
 }
 
-type RouteGuide_RouteChatClient interface {
-	Send(*RouteNote) error
-	Recv() (*RouteNote, error)
+type RouteGuide_WatchGeofenceEventsClient interface {
+	Recv() (*GeofenceEvent, error)
 	grpc.ClientStream
 }
 
-type routeGuideRouteChatClient struct {
+type routeGuideWatchGeofenceEventsClient struct {
 	grpc.ClientStream
 }
 
-func (x *routeGuideRouteChatClient) Send(m *RouteNote) error {
-	return x.ClientStream.SendMsg(m)
-}
-
-func (x *routeGuideRouteChatClient) Recv() (*RouteNote, error) {
-	m := new(RouteNote)
+func (x *routeGuideWatchGeofenceEventsClient) Recv() (*GeofenceEvent, error) {
+	m := new(GeofenceEvent)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
@@ -487,9 +2513,70 @@ type RouteGuideServer interface {
 	// Accepts a stream of Points on a route being traversed, returning a
 	// RouteSummary when traversal is completed.
 	RecordRoute(RouteGuide_RecordRouteServer) error
+	// Accepts a stream of TimedPoints on a route that was traversed, returning
+	// a RouteSummary when the stream is completed. Unlike RecordRoute, the
+	// summary is measured with the times the points were recorded at, so that
+	// tracks can be uploaded after they were recorded.
+	RecordTimedRoute(RouteGuide_RecordTimedRouteServer) error
 	// Accepts a stream of RouteNotes sent while a route is being traversed,
-	// while receiving other RouteNotes (e.g. from other users).
+	// while receiving other RouteNotes (e.g. from other users). Sending a note
+	// subscribes the stream to the note's location. Streams can also subscribe
+	// to other locations and areas with SUBSCRIBE notes. The history of a
+	// location or area is sent once when the stream subscribes to it, followed
+	// by the notes sent to it by all the participants as they arrive. Every
+	// note sent by the stream is acknowledged with an ACK note, once it's
+	// accepted by the server.
 	RouteChat(RouteGuide_RouteChatServer) error
+	// Creates a new Feature at an unoccupied location. The created Feature is
+	// returned with its version.
+	CreateFeature(context.Context, *Feature) (*Feature, error)
+	// Updates the Feature at the given location. The version must match the
+	// version of the saved Feature, otherwise the update is rejected with a
+	// FAILED_PRECONDITION error. The updated Feature is returned with its new
+	// version.
+	UpdateFeature(context.Context, *Feature) (*Feature, error)
+	// Deletes the Feature at the given location. The version must match the
+	// version of the saved Feature, otherwise the deletion is rejected with a
+	// FAILED_PRECONDITION error. The deleted Feature is returned.
+	DeleteFeature(context.Context, *DeleteFeatureRequest) (*Feature, error)
+	// Obtains the k Features closest to the given Point, ordered by their
+	// geodesic distance from the Point. Unlike GetFeature, the Point doesn't
+	// have to match the location of a Feature exactly.
+	FindNearest(context.Context, *NearestRequest) (*NearestResponse, error)
+	// Obtains the Features within the given Circle, measured by geodesic
+	// distance. Results are streamed rather than returned at once.
+	ListFeaturesInRadius(*Circle, RouteGuide_ListFeaturesInRadiusServer) error
+	// Obtains the Features within the given Polygon. Results are streamed
+	// rather than returned at once.
+	ListFeaturesInPolygon(*Polygon, RouteGuide_ListFeaturesInPolygonServer) error
+	// Obtains a page of the Features within the given Rectangle, ordered and
+	// filtered as requested. Subsequent pages are obtained by repeating the
+	// request with the page token of the previous response.
+	ListFeaturesPage(context.Context, *ListFeaturesPageRequest) (*ListFeaturesPageResponse, error)
+	// Watches the Features within the given Rectangle. The current Features are
+	// sent first as ADDED events, followed by a SYNCED event. Subsequent changes
+	// are streamed as ADDED, MODIFIED and DELETED events. A watch that is
+	// resumed from a resource version skips the current Features, and streams
+	// the changes after that version.
+	WatchFeatures(*WatchFeaturesRequest, RouteGuide_WatchFeaturesServer) error
+	// Obtains the recorded Route with the given ID.
+	GetRoute(context.Context, *RouteRequest) (*Route, error)
+	// Obtains the recorded Routes that match the request, in the order they
+	// were started. The Routes are streamed without their points.
+	ListRoutes(*ListRoutesRequest, RouteGuide_ListRoutesServer) error
+	// Exports the recorded Route with the given ID as a GPX 1.1 document.
+	ExportRoute(context.Context, *RouteRequest) (*RouteExport, error)
+	// Obtains the RouteChat participants currently at the given Point. A
+	// participant is at the location of the last note it sent.
+	ListParticipants(context.Context, *Point) (*ParticipantList, error)
+	// Registers a Geofence around a circle or a polygon. The created Geofence
+	// is returned with its ID. The points sent by the clients through
+	// RecordRoute and RouteChat are evaluated against all the Geofences.
+	CreateGeofence(context.Context, *Geofence) (*Geofence, error)
+	// Streams the events of the Geofences with the given IDs, or of all the
+	// Geofences if no ID is given, as the clients enter, exit and dwell in
+	// them.
+	WatchGeofenceEvents(*WatchGeofenceEventsRequest, RouteGuide_WatchGeofenceEventsServer) error
 }
 
 func RegisterRouteGuideServer(s *grpc.Server, srv RouteGuideServer) {
@@ -561,6 +2648,32 @@ func (x *routeGuideRecordRouteServer) Recv() (*Point, error) {
 	return m, nil
 }
 
+func _RouteGuide_RecordTimedRoute_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RouteGuideServer).RecordTimedRoute(&routeGuideRecordTimedRouteServer{stream})
+}
+
+type RouteGuide_RecordTimedRouteServer interface {
+	SendAndClose(*RouteSummary) error
+	Recv() (*TimedPoint, error)
+	grpc.ServerStream
+}
+
+type routeGuideRecordTimedRouteServer struct {
+	grpc.ServerStream
+}
+
+func (x *routeGuideRecordTimedRouteServer) SendAndClose(m *RouteSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *routeGuideRecordTimedRouteServer) Recv() (*TimedPoint, error) {
+	m := new(TimedPoint)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _RouteGuide_RouteChat_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(RouteGuideServer).RouteChat(&routeGuideRouteChatServer{stream})
 }
@@ -587,6 +2700,273 @@ func (x *routeGuideRouteChatServer) Recv() (*RouteNote, error) {
 	return m, nil
 }
 
+func _RouteGuide_CreateFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Feature)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouteGuideServer).CreateFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routeguideproto.RouteGuide/CreateFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouteGuideServer).CreateFeature(ctx, req.(*Feature))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RouteGuide_UpdateFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Feature)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouteGuideServer).UpdateFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routeguideproto.RouteGuide/UpdateFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouteGuideServer).UpdateFeature(ctx, req.(*Feature))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RouteGuide_DeleteFeature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteFeatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouteGuideServer).DeleteFeature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routeguideproto.RouteGuide/DeleteFeature",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouteGuideServer).DeleteFeature(ctx, req.(*DeleteFeatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RouteGuide_FindNearest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NearestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouteGuideServer).FindNearest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routeguideproto.RouteGuide/FindNearest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouteGuideServer).FindNearest(ctx, req.(*NearestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RouteGuide_ListFeaturesInRadius_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Circle)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RouteGuideServer).ListFeaturesInRadius(m, &routeGuideListFeaturesInRadiusServer{stream})
+}
+
+type RouteGuide_ListFeaturesInRadiusServer interface {
+	Send(*Feature) error
+	grpc.ServerStream
+}
+
+type routeGuideListFeaturesInRadiusServer struct {
+	grpc.ServerStream
+}
+
+func (x *routeGuideListFeaturesInRadiusServer) Send(m *Feature) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _RouteGuide_ListFeaturesInPolygon_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Polygon)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RouteGuideServer).ListFeaturesInPolygon(m, &routeGuideListFeaturesInPolygonServer{stream})
+}
+
+type RouteGuide_ListFeaturesInPolygonServer interface {
+	Send(*Feature) error
+	grpc.ServerStream
+}
+
+type routeGuideListFeaturesInPolygonServer struct {
+	grpc.ServerStream
+}
+
+func (x *routeGuideListFeaturesInPolygonServer) Send(m *Feature) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _RouteGuide_ListFeaturesPage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFeaturesPageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouteGuideServer).ListFeaturesPage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routeguideproto.RouteGuide/ListFeaturesPage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouteGuideServer).ListFeaturesPage(ctx, req.(*ListFeaturesPageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RouteGuide_WatchFeatures_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchFeaturesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RouteGuideServer).WatchFeatures(m, &routeGuideWatchFeaturesServer{stream})
+}
+
+type RouteGuide_WatchFeaturesServer interface {
+	Send(*FeatureEvent) error
+	grpc.ServerStream
+}
+
+type routeGuideWatchFeaturesServer struct {
+	grpc.ServerStream
+}
+
+func (x *routeGuideWatchFeaturesServer) Send(m *FeatureEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _RouteGuide_GetRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RouteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouteGuideServer).GetRoute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routeguideproto.RouteGuide/GetRoute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouteGuideServer).GetRoute(ctx, req.(*RouteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RouteGuide_ListRoutes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRoutesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RouteGuideServer).ListRoutes(m, &routeGuideListRoutesServer{stream})
+}
+
+type RouteGuide_ListRoutesServer interface {
+	Send(*Route) error
+	grpc.ServerStream
+}
+
+type routeGuideListRoutesServer struct {
+	grpc.ServerStream
+}
+
+func (x *routeGuideListRoutesServer) Send(m *Route) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _RouteGuide_ExportRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RouteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouteGuideServer).ExportRoute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routeguideproto.RouteGuide/ExportRoute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouteGuideServer).ExportRoute(ctx, req.(*RouteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RouteGuide_ListParticipants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Point)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouteGuideServer).ListParticipants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routeguideproto.RouteGuide/ListParticipants",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouteGuideServer).ListParticipants(ctx, req.(*Point))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RouteGuide_CreateGeofence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Geofence)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouteGuideServer).CreateGeofence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routeguideproto.RouteGuide/CreateGeofence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouteGuideServer).CreateGeofence(ctx, req.(*Geofence))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RouteGuide_WatchGeofenceEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchGeofenceEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RouteGuideServer).WatchGeofenceEvents(m, &routeGuideWatchGeofenceEventsServer{stream})
+}
+
+type RouteGuide_WatchGeofenceEventsServer interface {
+	Send(*GeofenceEvent) error
+	grpc.ServerStream
+}
+
+type routeGuideWatchGeofenceEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *routeGuideWatchGeofenceEventsServer) Send(m *GeofenceEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _RouteGuide_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "routeguideproto.RouteGuide",
 	HandlerType: (*RouteGuideServer)(nil),
@@ -595,6 +2975,42 @@ var _RouteGuide_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetFeature",
 			Handler:    _RouteGuide_GetFeature_Handler,
 		},
+		{
+			MethodName: "CreateFeature",
+			Handler:    _RouteGuide_CreateFeature_Handler,
+		},
+		{
+			MethodName: "UpdateFeature",
+			Handler:    _RouteGuide_UpdateFeature_Handler,
+		},
+		{
+			MethodName: "DeleteFeature",
+			Handler:    _RouteGuide_DeleteFeature_Handler,
+		},
+		{
+			MethodName: "FindNearest",
+			Handler:    _RouteGuide_FindNearest_Handler,
+		},
+		{
+			MethodName: "ListFeaturesPage",
+			Handler:    _RouteGuide_ListFeaturesPage_Handler,
+		},
+		{
+			MethodName: "GetRoute",
+			Handler:    _RouteGuide_GetRoute_Handler,
+		},
+		{
+			MethodName: "ExportRoute",
+			Handler:    _RouteGuide_ExportRoute_Handler,
+		},
+		{
+			MethodName: "ListParticipants",
+			Handler:    _RouteGuide_ListParticipants_Handler,
+		},
+		{
+			MethodName: "CreateGeofence",
+			Handler:    _RouteGuide_CreateGeofence_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -607,12 +3023,42 @@ var _RouteGuide_serviceDesc = grpc.ServiceDesc{
 			Handler:       _RouteGuide_RecordRoute_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "RecordTimedRoute",
+			Handler:       _RouteGuide_RecordTimedRoute_Handler,
+			ClientStreams: true,
+		},
 		{
 			StreamName:    "RouteChat",
 			Handler:       _RouteGuide_RouteChat_Handler,
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "ListFeaturesInRadius",
+			Handler:       _RouteGuide_ListFeaturesInRadius_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListFeaturesInPolygon",
+			Handler:       _RouteGuide_ListFeaturesInPolygon_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchFeatures",
+			Handler:       _RouteGuide_WatchFeatures_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListRoutes",
+			Handler:       _RouteGuide_ListRoutes_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchGeofenceEvents",
+			Handler:       _RouteGuide_WatchGeofenceEvents_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "route_guide.proto",
 }
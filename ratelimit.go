@@ -0,0 +1,240 @@
+package routeguide
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// rateLimitSweepInterval is the interval at which the buckets that refilled
+// are dropped, so that idle callers don't hold on to memory.
+const rateLimitSweepInterval = time.Minute
+
+// RateLimit is the number of calls per second that a caller can make to a
+// method, and the number of calls it can make at once. A zero rate disables
+// the limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// tokenBucket holds the tokens of a caller. Every call takes a token, and the
+// tokens are refilled at the rate of the limit, up to its burst.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take takes a token from the bucket, returning zero if one was taken, or the
+// time until the next token is refilled.
+func (b *tokenBucket) take(limit RateLimit, now time.Time) time.Duration {
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+}
+
+// full returns true if the bucket refilled completely by the given time.
+func (b *tokenBucket) full(limit RateLimit, now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= float64(limit.Burst)
+}
+
+type bucketKey struct {
+	method string
+	caller string
+}
+
+// RateLimiter limits the rate of the calls of every caller to every method
+// with token buckets. The callers are identified by the subjects of their
+// bearer tokens, or else by their client certificates, or else by their
+// addresses.
+type RateLimiter struct {
+	limit   RateLimit
+	methods map[string]RateLimit
+
+	mutex     sync.Mutex
+	buckets   map[bucketKey]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter returns a rate limiter that applies the limits of the
+// methods, by their full names, and the default limit to the other methods.
+func NewRateLimiter(limit RateLimit, methods map[string]RateLimit) (*RateLimiter, error) {
+	if err := limit.validate(); err != nil {
+		return nil, err
+	}
+	for method, l := range methods {
+		if err := l.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %s", method, err)
+		}
+	}
+
+	return &RateLimiter{
+		limit:     limit,
+		methods:   methods,
+		buckets:   make(map[bucketKey]*tokenBucket),
+		lastSweep: time.Now(),
+	}, nil
+}
+
+func (l RateLimit) validate() error {
+	if l.Rate < 0 {
+		return fmt.Errorf("rate must not be negative")
+	}
+	if l.Rate > 0 && l.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1")
+	}
+	return nil
+}
+
+// Allow takes a token from the bucket of the caller for the method, returning
+// zero if the call is allowed, or the time after which it can be retried.
+func (r *RateLimiter) Allow(caller, method string) time.Duration {
+	limit, exists := r.methods[method]
+	if !exists {
+		limit = r.limit
+	}
+	if limit.Rate == 0 {
+		return 0
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastSweep) > rateLimitSweepInterval {
+		r.sweep(now)
+	}
+
+	key := bucketKey{method: method, caller: caller}
+	bucket, exists := r.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		r.buckets[key] = bucket
+	}
+	return bucket.take(limit, now)
+}
+
+// sweep drops the buckets that refilled, since they behave like new buckets.
+// The caller must hold the mutex.
+func (r *RateLimiter) sweep(now time.Time) {
+	for key, bucket := range r.buckets {
+		limit, exists := r.methods[key.method]
+		if !exists {
+			limit = r.limit
+		}
+
+		if bucket.full(limit, now) {
+			delete(r.buckets, key)
+		}
+	}
+	r.lastSweep = now
+}
+
+// UnaryInterceptor rejects the unary requests of the callers that exceeded
+// their limits, except for the exempt methods.
+func (r *RateLimiter) UnaryInterceptor(exempt ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !contains(exempt, info.FullMethod) {
+			if err := r.limitCall(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor rejects the streams of the callers that exceeded their
+// limits, except for the exempt methods. A stream counts as one call.
+func (r *RateLimiter) StreamInterceptor(exempt ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !contains(exempt, info.FullMethod) {
+			if err := r.limitCall(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// limitCall returns a RESOURCE_EXHAUSTED error with the delay after which the
+// call can be retried, if the caller exceeded its limit.
+func (r *RateLimiter) limitCall(ctx context.Context, method string) error {
+	caller := rateLimitCaller(ctx)
+	delay := r.Allow(caller, method)
+	if delay == 0 {
+		return nil
+	}
+
+	log.Printf("[ratelimit] (limited) %s: %s, retry in %s", method, caller, delay)
+	s, err := status.New(codes.ResourceExhausted, fmt.Sprintf("rate limit of %s exceeded", method)).
+		WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(delay)})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return s.Err()
+}
+
+// rateLimitCaller returns the identity of the caller that its calls are
+// counted against, prefixed with its kind like the identities of the policy.
+// The unverified identity header is ignored, so that a caller can't dodge its
+// limits by changing it.
+func rateLimitCaller(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok && claims.Subject != "" {
+		return identitySubject + ":" + claims.Subject
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return unknownClientID
+	}
+
+	if identity := PeerIdentity(p); identity != "" {
+		return identitySAN + ":" + identity
+	}
+
+	// the port differs between the connections of a caller
+	address := p.Addr.String()
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	return "addr:" + address
+}
+
+// RetryDelay returns the delay after which the call that failed with the
+// error can be retried, if the server asked the client to back off.
+func RetryDelay(err error) (time.Duration, bool) {
+	s, ok := status.FromError(err)
+	if !ok || s.Code() != codes.ResourceExhausted {
+		return 0, false
+	}
+
+	for _, detail := range s.Details() {
+		info, ok := detail.(*errdetails.RetryInfo)
+		if !ok {
+			continue
+		}
+
+		delay, err := ptypes.Duration(info.RetryDelay)
+		if err != nil {
+			return 0, false
+		}
+		return delay, true
+	}
+	return 0, false
+}
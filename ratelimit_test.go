@@ -0,0 +1,150 @@
+package routeguide
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	var (
+		limit = RateLimit{Rate: 2, Burst: 3}
+		start = time.Unix(1600000000, 0)
+	)
+
+	tests := []struct {
+		name     string
+		bucket   *tokenBucket
+		now      time.Time
+		expected time.Duration
+	}{
+		{
+			name:   "full",
+			bucket: &tokenBucket{tokens: 3, last: start},
+			now:    start,
+		},
+		{
+			name:     "empty",
+			bucket:   &tokenBucket{tokens: 0, last: start},
+			now:      start,
+			expected: 500 * time.Millisecond,
+		},
+		{
+			name:     "partly refilled",
+			bucket:   &tokenBucket{tokens: 0, last: start},
+			now:      start.Add(250 * time.Millisecond),
+			expected: 250 * time.Millisecond,
+		},
+		{
+			name:   "refilled",
+			bucket: &tokenBucket{tokens: 0, last: start},
+			now:    start.Add(500 * time.Millisecond),
+		},
+		{
+			name:   "refilled up to the burst",
+			bucket: &tokenBucket{tokens: 0, last: start},
+			now:    start.Add(time.Hour),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := test.bucket.take(limit, test.now); actual != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTokenBucketBurst(t *testing.T) {
+	var (
+		limit  = RateLimit{Rate: 1, Burst: 3}
+		now    = time.Unix(1600000000, 0)
+		bucket = &tokenBucket{tokens: 0, last: now}
+	)
+
+	// a long idle time refills the bucket up to its burst only
+	now = now.Add(time.Hour)
+	for i := 0; i < limit.Burst; i++ {
+		if delay := bucket.take(limit, now); delay != 0 {
+			t.Fatalf("call %d: expected no delay, got %s", i, delay)
+		}
+	}
+
+	if delay := bucket.take(limit, now); delay != time.Second {
+		t.Errorf("expected a delay of 1s after the burst, got %s", delay)
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimit{Rate: 1, Burst: 2}, map[string]RateLimit{
+		"/svc/Slow": {Rate: 0.01, Burst: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	limiter.buckets = map[bucketKey]*tokenBucket{
+		{method: "/svc/Fast", caller: "refilled"}:  {tokens: 0, last: now.Add(-2 * time.Second)},
+		{method: "/svc/Fast", caller: "refilling"}: {tokens: 0, last: now.Add(-time.Second)},
+		{method: "/svc/Slow", caller: "refilling"}: {tokens: 0, last: now.Add(-10 * time.Second)},
+	}
+	limiter.sweep(now)
+
+	if _, exists := limiter.buckets[bucketKey{method: "/svc/Fast", caller: "refilled"}]; exists {
+		t.Error("expected the refilled bucket to be dropped")
+	}
+	if _, exists := limiter.buckets[bucketKey{method: "/svc/Fast", caller: "refilling"}]; !exists {
+		t.Error("expected the refilling bucket to be kept")
+	}
+	if _, exists := limiter.buckets[bucketKey{method: "/svc/Slow", caller: "refilling"}]; !exists {
+		t.Error("expected the bucket of the method limit to be kept")
+	}
+	if !limiter.lastSweep.Equal(now) {
+		t.Errorf("expected the last sweep at %s, got %s", now, limiter.lastSweep)
+	}
+}
+
+func TestRateLimiterRetryDelay(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimit{Rate: 0.5, Burst: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		interceptor = limiter.UnaryInterceptor()
+		info        = &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}
+		handler     = func(ctx context.Context, req interface{}) (interface{}, error) { return req, nil }
+	)
+	if _, err := interceptor(context.Background(), nil, info, handler); err != nil {
+		t.Fatalf("expected the first call to be allowed, got %v", err)
+	}
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected a %s error, got %v", codes.ResourceExhausted, err)
+	}
+
+	delay, ok := RetryDelay(err)
+	if !ok {
+		t.Fatal("expected the error to carry a retry delay")
+	}
+	if delay <= time.Second || delay > 2*time.Second {
+		t.Errorf("expected a retry delay of about 2s, got %s", delay)
+	}
+
+	for _, err := range []error{
+		nil,
+		status.Error(codes.ResourceExhausted, "no details"),
+		status.Error(codes.Unavailable, "unavailable"),
+	} {
+		if _, ok := RetryDelay(err); ok {
+			t.Errorf("%v: expected no retry delay", err)
+		}
+	}
+}
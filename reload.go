@@ -0,0 +1,147 @@
+package routeguide
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// FeatureReloader reloads the dataset files of a feature store whenever they
+// change on disk, or whenever a reload is requested.
+type FeatureReloader struct {
+	store    FeatureStore
+	paths    []string
+	onReload func(error)
+	watcher  *fileWatcher
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewFeatureReloader returns a reloader that swaps the features of the store
+// with the features in the dataset files at the given paths. The files are
+// polled for changes at the given interval. A zero interval disables polling.
+// The onReload callback is invoked with the outcome of every reload.
+func NewFeatureReloader(store FeatureStore, paths []string, interval time.Duration, onReload func(error)) *FeatureReloader {
+	r := &FeatureReloader{
+		store:    store,
+		paths:    paths,
+		onReload: onReload,
+	}
+	r.watcher = newFileWatcher("reload", paths, interval, r.reload)
+
+	return r
+}
+
+// Run reloads the dataset whenever a signal is received on the trigger
+// channel, or whenever the dataset files change. It returns when the stop
+// channel is closed.
+func (r *FeatureReloader) Run(trigger <-chan os.Signal, stop <-chan struct{}) {
+	r.watcher.Run(trigger, stop)
+}
+
+func (r *FeatureReloader) reload() error {
+	err := r.Reload()
+	if r.onReload != nil {
+		r.onReload(err)
+	}
+	return err
+}
+
+// Reload reads the dataset files strictly with LoadDatasets, and swaps them
+// into the store. If the files are rejected, the store is left untouched.
+func (r *FeatureReloader) Reload() error {
+	features, err := LoadDatasets(r.paths, true)
+	if err != nil {
+		return err
+	}
+
+	if err := r.store.Replace(features); err != nil {
+		return err
+	}
+
+	log.Printf("[reload] loaded %d features", len(features))
+	return nil
+}
+
+// fileWatcher calls its reload function whenever a signal is received on the
+// trigger channel, or whenever any of its files changes on disk. The files
+// are polled for changes at an interval.
+type fileWatcher struct {
+	name     string
+	files    []string
+	interval time.Duration
+	reload   func() error
+	stamps   map[string]fileStamp
+}
+
+// newFileWatcher returns a watcher of the files, which records their current
+// state so that only the later changes trigger a reload. The name prefixes
+// its log lines. A zero interval disables polling.
+func newFileWatcher(name string, files []string, interval time.Duration, reload func() error) *fileWatcher {
+	w := &fileWatcher{
+		name:     name,
+		files:    files,
+		interval: interval,
+		reload:   reload,
+		stamps:   make(map[string]fileStamp),
+	}
+	filesChanged(w.files, w.stamps)
+
+	return w
+}
+
+// Run reloads the files until the stop channel is closed.
+func (w *fileWatcher) Run(trigger <-chan os.Signal, stop <-chan struct{}) {
+	var poll <-chan time.Time
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case sig := <-trigger:
+			log.Printf("[%s] received %s", w.name, sig)
+			filesChanged(w.files, w.stamps)
+			w.callReload()
+		case <-poll:
+			if filesChanged(w.files, w.stamps) {
+				log.Printf("[%s] %s changed", w.name, strings.Join(w.files, ", "))
+				w.callReload()
+			}
+		}
+	}
+}
+
+func (w *fileWatcher) callReload() {
+	if err := w.reload(); err != nil {
+		log.Printf("[%s] (error) %s", w.name, err)
+	}
+}
+
+// filesChanged records the current modification times and sizes of the files
+// in the stamps, returning true if any of them changed since they were last
+// recorded.
+func filesChanged(paths []string, stamps map[string]fileStamp) bool {
+	changed := false
+	for _, path := range paths {
+		var stamp fileStamp
+		if info, err := os.Stat(path); err == nil {
+			stamp = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+
+		if previous, exists := stamps[path]; !exists || previous != stamp {
+			changed = true
+		}
+		stamps[path] = stamp
+	}
+
+	return changed
+}
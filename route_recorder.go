@@ -0,0 +1,171 @@
+package routeguide
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+const (
+	// DefaultIdleSpeed is the speed in meters per second below which a
+	// segment of a timed route is idle.
+	DefaultIdleSpeed = 0.5
+
+	// DefaultGapThreshold is the time between two points of a timed route
+	// beyond which the segment between them is a gap.
+	DefaultGapThreshold = time.Minute
+)
+
+// routeRecorder measures the summary of a route, one point at a time.
+type routeRecorder struct {
+	server    *routeGuideServer
+	summary   *pb.RouteSummary
+	points    []*pb.Point
+	times     []*timestamp.Timestamp
+	passed    map[pointKey]bool
+	distance  float64
+	bearings  bool
+	lastPoint *pb.Point
+
+	// the times of the first and the last timed points, and the totals of
+	// the moving segments
+	firstTime, lastTime time.Time
+	movingDistance      float64
+	movingTime          time.Duration
+	idleTime            time.Duration
+}
+
+func (r *routeGuideServer) newRouteRecorder() *routeRecorder {
+	return &routeRecorder{
+		server:  r,
+		summary: &pb.RouteSummary{},
+		points:  []*pb.Point{},
+		passed:  make(map[pointKey]bool),
+	}
+}
+
+// add adds the point recorded at the given time to the route. The time is nil
+// if it's unknown.
+func (rr *routeRecorder) add(point *pb.Point, recordedAt *timestamp.Timestamp) error {
+	var at time.Time
+	if recordedAt != nil {
+		t, err := ptypes.Timestamp(recordedAt)
+		if err != nil {
+			return err
+		}
+		if !rr.lastTime.IsZero() && t.Before(rr.lastTime) {
+			return fmt.Errorf("point %d was recorded before the previous point", len(rr.points))
+		}
+		at = t
+	}
+
+	rr.summary.PointCount++
+	rr.points = append(rr.points, point)
+	rr.times = append(rr.times, recordedAt)
+
+	feature, err := rr.server.matchFeature(point)
+	if err != nil {
+		return err
+	}
+	if feature != nil && !rr.passed[keyOf(feature.Location)] {
+		rr.passed[keyOf(feature.Location)] = true
+		rr.summary.FeaturesPassed = append(rr.summary.FeaturesPassed, feature)
+		rr.summary.FeatureCount++
+	}
+
+	var segment float64
+	if rr.lastPoint != nil {
+		var initialBearing, finalBearing float64
+		segment, initialBearing, finalBearing = rr.server.geodesic.Inverse(rr.lastPoint, point)
+		rr.summary.SegmentDistances = append(rr.summary.SegmentDistances, segment)
+		rr.distance += segment
+
+		// the bearing of a zero-length segment is undefined
+		if segment > 0 {
+			if !rr.bearings {
+				rr.summary.InitialBearing = initialBearing
+				rr.bearings = true
+			}
+			rr.summary.FinalBearing = finalBearing
+		}
+	}
+
+	// the times and speeds are only measured between consecutive timed
+	// points
+	if !at.IsZero() && rr.lastPoint != nil && rr.times[len(rr.times)-2] != nil {
+		rr.measure(segment, rr.times[len(rr.times)-2], recordedAt, at.Sub(rr.lastTime))
+	}
+
+	rr.lastPoint = point
+	if !at.IsZero() {
+		if rr.firstTime.IsZero() {
+			rr.firstTime = at
+		}
+		rr.lastTime = at
+	}
+	return nil
+}
+
+// measure classifies the segment recorded between the given times as moving,
+// idle or a gap.
+func (rr *routeRecorder) measure(segment float64, start, end *timestamp.Timestamp, elapsed time.Duration) {
+	if elapsed > rr.server.gapThreshold {
+		rr.summary.Gaps = append(rr.summary.Gaps, &pb.RouteGap{Start: start, End: end})
+		return
+	}
+
+	if elapsed <= 0 {
+		return
+	}
+
+	speed := segment / elapsed.Seconds()
+	if speed < rr.server.idleSpeed {
+		rr.idleTime += elapsed
+		return
+	}
+
+	rr.movingTime += elapsed
+	rr.movingDistance += segment
+	if speed > rr.summary.MaxSpeed {
+		rr.summary.MaxSpeed = speed
+	}
+}
+
+// finish completes the summary of the route, and saves the route. The route
+// is timed by the times of its points if it has any, and otherwise by the
+// given start and end times.
+func (rr *routeRecorder) finish(ctx context.Context, startTime, endTime time.Time) (*pb.RouteSummary, error) {
+	if !rr.firstTime.IsZero() {
+		startTime, endTime = rr.firstTime, rr.lastTime
+	}
+
+	rr.summary.Distance = int32(math.Round(rr.distance))
+	rr.summary.ElapsedTime = int32(endTime.Sub(startTime).Seconds())
+	rr.summary.MovingTime = int32(rr.movingTime.Seconds())
+	rr.summary.IdleTime = int32(rr.idleTime.Seconds())
+	if rr.movingTime > 0 {
+		rr.summary.AverageSpeed = rr.movingDistance / rr.movingTime.Seconds()
+	}
+
+	// the times are only kept if every point carries one
+	times := rr.times
+	for _, t := range times {
+		if t == nil {
+			times = nil
+			break
+		}
+	}
+
+	route, err := rr.server.saveRoute(ctx, rr.points, times, rr.summary, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	rr.summary.RouteId = route.Id
+
+	return rr.summary, nil
+}
@@ -0,0 +1,251 @@
+package routeguide
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+func TestRouteRecorderSummary(t *testing.T) {
+	var (
+		start = time.Unix(1600000000, 0)
+
+		// the points are 0.001 degrees of latitude apart, i.e. about 111m
+		points = []*pb.Point{
+			testPoint(t, 0, 0),
+			testPoint(t, 0.001, 0),
+			testPoint(t, 0.002, 0),
+			testPoint(t, 0.003, 0),
+		}
+		step = haversine(points[0], points[1])
+	)
+
+	type timedPoint struct {
+		point   *pb.Point
+		at      time.Duration
+		untimed bool
+	}
+	type gap struct {
+		start, end time.Duration
+	}
+
+	tests := []struct {
+		name      string
+		idleSpeed float64
+		points    []timedPoint
+		expected  *pb.RouteSummary
+		gaps      []gap
+		timed     bool
+	}{
+		{
+			name: "moving, idle and gap",
+			points: []timedPoint{
+				{point: points[0]},
+				{point: points[1], at: 10 * time.Second},
+				{point: points[1], at: 40 * time.Second},
+				{point: points[2], at: 50 * time.Second},
+				{point: points[3], at: 5 * time.Minute},
+			},
+			expected: &pb.RouteSummary{
+				PointCount:   5,
+				Distance:     int32(math.Round(3 * step)),
+				ElapsedTime:  300,
+				MovingTime:   20,
+				IdleTime:     30,
+				AverageSpeed: 2 * step / 20,
+				MaxSpeed:     step / 10,
+			},
+			gaps:  []gap{{start: 50 * time.Second, end: 5 * time.Minute}},
+			timed: true,
+		},
+		{
+			name: "maximum speed",
+			points: []timedPoint{
+				{point: points[0]},
+				{point: points[1], at: 20 * time.Second},
+				{point: points[2], at: 25 * time.Second},
+				{point: points[3], at: 45 * time.Second},
+			},
+			expected: &pb.RouteSummary{
+				PointCount:   4,
+				Distance:     int32(math.Round(3 * step)),
+				ElapsedTime:  45,
+				MovingTime:   45,
+				AverageSpeed: 3 * step / 45,
+				MaxSpeed:     step / 5,
+			},
+			timed: true,
+		},
+		{
+			name:      "below the idle speed",
+			idleSpeed: 20,
+			points: []timedPoint{
+				{point: points[0]},
+				{point: points[1], at: 10 * time.Second},
+				{point: points[2], at: 15 * time.Second},
+			},
+			expected: &pb.RouteSummary{
+				PointCount:   3,
+				Distance:     int32(math.Round(2 * step)),
+				ElapsedTime:  15,
+				MovingTime:   5,
+				IdleTime:     10,
+				AverageSpeed: step / 5,
+				MaxSpeed:     step / 5,
+			},
+			timed: true,
+		},
+		{
+			name: "same timestamps",
+			points: []timedPoint{
+				{point: points[0]},
+				{point: points[1], at: 10 * time.Second},
+				{point: points[2], at: 10 * time.Second},
+			},
+			expected: &pb.RouteSummary{
+				PointCount:   3,
+				Distance:     int32(math.Round(2 * step)),
+				ElapsedTime:  10,
+				MovingTime:   10,
+				AverageSpeed: step / 10,
+				MaxSpeed:     step / 10,
+			},
+			timed: true,
+		},
+		{
+			name: "unset times",
+			points: []timedPoint{
+				{point: points[0]},
+				{point: points[1], untimed: true},
+				{point: points[2], at: 20 * time.Second},
+				{point: points[3], at: 30 * time.Second},
+			},
+			expected: &pb.RouteSummary{
+				PointCount:   4,
+				Distance:     int32(math.Round(3 * step)),
+				ElapsedTime:  30,
+				MovingTime:   10,
+				AverageSpeed: step / 10,
+				MaxSpeed:     step / 10,
+			},
+		},
+		{
+			name: "untimed",
+			points: []timedPoint{
+				{point: points[0], untimed: true},
+				{point: points[1], untimed: true},
+			},
+			expected: &pb.RouteSummary{
+				PointCount:  2,
+				Distance:    int32(math.Round(step)),
+				ElapsedTime: 3600,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := []ServerOption{}
+			if test.idleSpeed > 0 {
+				opts = append(opts, WithIdleSpeed(test.idleSpeed))
+			}
+			server, err := NewServer("test", NewMemoryStore(nil), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := server.(*routeGuideServer)
+
+			recorder := r.newRouteRecorder()
+			for _, p := range test.points {
+				var recordedAt *timestamp.Timestamp
+				if !p.untimed {
+					recordedAt, _ = ptypes.TimestampProto(start.Add(p.at))
+				}
+				if err := recorder.add(p.point, recordedAt); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// the route is timed by the given times only if none of its
+			// points has a time
+			summary, err := recorder.finish(context.Background(), start.Add(-time.Hour), start)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := &pb.RouteSummary{
+				PointCount:   summary.PointCount,
+				Distance:     summary.Distance,
+				ElapsedTime:  summary.ElapsedTime,
+				MovingTime:   summary.MovingTime,
+				IdleTime:     summary.IdleTime,
+				AverageSpeed: summary.AverageSpeed,
+				MaxSpeed:     summary.MaxSpeed,
+			}
+			if math.Abs(actual.AverageSpeed-test.expected.AverageSpeed) < 1e-9 {
+				actual.AverageSpeed = test.expected.AverageSpeed
+			}
+			if math.Abs(actual.MaxSpeed-test.expected.MaxSpeed) < 1e-9 {
+				actual.MaxSpeed = test.expected.MaxSpeed
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected the summary\n%+v\ngot\n%+v", test.expected, actual)
+			}
+
+			gaps := []gap{}
+			for _, g := range summary.Gaps {
+				gapStart, _ := ptypes.Timestamp(g.Start)
+				gapEnd, _ := ptypes.Timestamp(g.End)
+				gaps = append(gaps, gap{start: gapStart.Sub(start), end: gapEnd.Sub(start)})
+			}
+			if len(test.gaps) == 0 {
+				test.gaps = []gap{}
+			}
+			if !reflect.DeepEqual(gaps, test.gaps) {
+				t.Errorf("expected the gaps %v, got %v", test.gaps, gaps)
+			}
+
+			// the times of the points are only saved if every point has one
+			route, err := r.routes.Get(summary.RouteId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if timed := len(route.PointTimes) == len(test.points); timed != test.timed {
+				t.Errorf("expected the saved route to be timed %t, got %d times", test.timed, len(route.PointTimes))
+			}
+		})
+	}
+}
+
+func TestRouteRecorderDecreasingTimes(t *testing.T) {
+	server, err := NewServer("test", NewMemoryStore(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		recorder = server.(*routeGuideServer).newRouteRecorder()
+		start    = time.Unix(1600000000, 0)
+		first, _ = ptypes.TimestampProto(start)
+		later, _ = ptypes.TimestampProto(start.Add(time.Minute))
+	)
+
+	if err := recorder.add(testPoint(t, 0, 0), later); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.add(testPoint(t, 0, 0.001), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	err = recorder.add(testPoint(t, 0, 0.002), first)
+	if err == nil || !strings.Contains(err.Error(), "point 2 was recorded before the previous point") {
+		t.Errorf("expected an error of the decreasing time, got %v", err)
+	}
+}
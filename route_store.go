@@ -0,0 +1,167 @@
+package routeguide
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// RouteStore knows how to save and look up the routes recorded by the route
+// guide server. Implementations must be safe for concurrent use.
+type RouteStore interface {
+	// Save saves the route, overwriting any saved route with the same ID.
+	Save(route *pb.Route) error
+
+	// Get returns the route with the given ID. If there is no such route, a
+	// nil route is returned.
+	Get(id string) (*pb.Route, error)
+
+	// List calls fn for every route that matches the filter, in the order the
+	// routes were started. The iteration stops when fn returns false.
+	List(filter RouteFilter, fn func(*pb.Route) bool) error
+}
+
+// RouteFilter selects the routes recorded by a client within a time range.
+// Zero fields don't filter the routes.
+type RouteFilter struct {
+	ClientID string
+
+	// StartedAfter is the inclusive lower bound of the start times.
+	StartedAfter time.Time
+
+	// StartedBefore is the exclusive upper bound of the start times.
+	StartedBefore time.Time
+}
+
+// match returns true if the route passes the filter.
+func (f RouteFilter) match(route *pb.Route) bool {
+	if f.ClientID != "" && route.ClientId != f.ClientID {
+		return false
+	}
+
+	started, err := ptypes.Timestamp(route.StartedAt)
+	if err != nil {
+		return false
+	}
+
+	if !f.StartedAfter.IsZero() && started.Before(f.StartedAfter) {
+		return false
+	}
+
+	return f.StartedBefore.IsZero() || started.Before(f.StartedBefore)
+}
+
+// newRouteID returns a unique ID for a route started at the given time. IDs
+// sort in the order of their start times.
+func newRouteID(started time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+
+	return routeIDPrefix(started) + hex.EncodeToString(suffix), nil
+}
+
+// The range of the start times that the IDs tell apart.
+var (
+	minRouteTime = time.Unix(0, math.MinInt64)
+	maxRouteTime = time.Unix(0, math.MaxInt64)
+)
+
+// routeIDPrefix returns the prefix of the IDs of the routes started at the
+// given time. The sign bit of the time is flipped, so that the times before
+// 1970 sort before the later ones. The times beyond the range of UnixNano,
+// between the years 1677 and 2262, share the prefix of its bounds.
+func routeIDPrefix(started time.Time) string {
+	nanos := started.UnixNano()
+	switch {
+	case started.Before(minRouteTime):
+		nanos = math.MinInt64
+	case started.After(maxRouteTime):
+		nanos = math.MaxInt64
+	}
+
+	prefix := make([]byte, 8)
+	binary.BigEndian.PutUint64(prefix, uint64(nanos)^1<<63)
+	return hex.EncodeToString(prefix)
+}
+
+// NewMemoryRouteStore returns a route store that keeps the routes in memory.
+// The routes are lost when the server stops.
+func NewMemoryRouteStore() RouteStore {
+	return &memoryRouteStore{}
+}
+
+type memoryRouteStore struct {
+	// routes are ordered by their IDs
+	routes []*pb.Route
+	mutex  sync.RWMutex
+}
+
+func (m *memoryRouteStore) Save(route *pb.Route) error {
+	saved := proto.Clone(route).(*pb.Route)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	i := m.search(route.Id)
+	if i < len(m.routes) && m.routes[i].Id == route.Id {
+		m.routes[i] = saved
+		return nil
+	}
+
+	m.routes = append(m.routes, nil)
+	copy(m.routes[i+1:], m.routes[i:])
+	m.routes[i] = saved
+	return nil
+}
+
+func (m *memoryRouteStore) Get(id string) (*pb.Route, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if i := m.search(id); i < len(m.routes) && m.routes[i].Id == id {
+		return m.routes[i], nil
+	}
+	return nil, nil
+}
+
+func (m *memoryRouteStore) List(filter RouteFilter, fn func(*pb.Route) bool) error {
+	// iterate over a snapshot so that fn doesn't hold up the lock
+	m.mutex.RLock()
+	first := 0
+	if !filter.StartedAfter.IsZero() {
+		first = m.search(routeIDPrefix(filter.StartedAfter))
+	}
+	snapshot := make([]*pb.Route, len(m.routes)-first)
+	copy(snapshot, m.routes[first:])
+	m.mutex.RUnlock()
+
+	for _, route := range snapshot {
+		if !filter.match(route) {
+			continue
+		}
+
+		if !fn(route) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// search returns the index of the first route whose ID isn't less than the
+// given ID.
+func (m *memoryRouteStore) search(id string) int {
+	return sort.Search(len(m.routes), func(i int) bool {
+		return m.routes[i].Id >= id
+	})
+}
@@ -0,0 +1,120 @@
+package routeguide
+
+import (
+	"bytes"
+
+	"github.com/gogo/protobuf/proto"
+	pb "github.com/ihcsim/routeguide/proto"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketRoutes = []byte("routes")
+
+// NewBoltRouteStore returns a route store that persists routes in the given
+// bolt database. The database can be shared with a BoltStore. Routes are keyed
+// by their IDs, so that they are ordered by their start times.
+func NewBoltRouteStore(db *bolt.DB) (*BoltRouteStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketRoutes)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltRouteStore{db: db}, nil
+}
+
+// BoltRouteStore is a RouteStore backed by an embedded bolt key/value
+// database.
+type BoltRouteStore struct {
+	db *bolt.DB
+}
+
+// Save saves the route, overwriting any saved route with the same ID.
+func (b *BoltRouteStore) Save(route *pb.Route) error {
+	value, err := proto.Marshal(route)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketRoutes).Put([]byte(route.Id), value)
+	})
+}
+
+// Get returns the route with the given ID.
+func (b *BoltRouteStore) Get(id string) (*pb.Route, error) {
+	var route *pb.Route
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucketRoutes).Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+
+		route = &pb.Route{}
+		return proto.Unmarshal(value, route)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return route, nil
+}
+
+// List calls fn for every route that matches the filter. Since keys are
+// ordered by the start times, only the keys within the filter's time range are
+// visited.
+func (b *BoltRouteStore) List(filter RouteFilter, fn func(*pb.Route) bool) error {
+	var first, last []byte
+	if !filter.StartedAfter.IsZero() {
+		first = []byte(routeIDPrefix(filter.StartedAfter))
+	}
+	if !filter.StartedBefore.IsZero() {
+		last = []byte(routeIDPrefix(filter.StartedBefore))
+	}
+
+	routes := []*pb.Route{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucketRoutes).Cursor()
+
+		var key, value []byte
+		if first == nil {
+			key, value = cursor.First()
+		} else {
+			key, value = cursor.Seek(first)
+		}
+
+		for ; key != nil; key, value = cursor.Next() {
+			// the routes with the same prefix as the upper bound are
+			// left to the filter, since the times beyond the range of the
+			// prefixes share theirs
+			if last != nil && string(key) >= string(last) && !bytes.HasPrefix(key, last) {
+				break
+			}
+
+			route := &pb.Route{}
+			if err := proto.Unmarshal(value, route); err != nil {
+				return err
+			}
+
+			if filter.match(route) {
+				routes = append(routes, route)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the callbacks are invoked outside of the transaction so that slow
+	// consumers don't keep it open
+	for _, route := range routes {
+		if !fn(route) {
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package routeguide
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	pb "github.com/ihcsim/routeguide/proto"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestRouteIDPrefixOrder(t *testing.T) {
+	times := []time.Time{
+		time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1677, 9, 21, 0, 12, 43, 145224193, time.UTC),
+		time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1969, 12, 31, 23, 59, 59, 999999999, time.UTC),
+		time.Unix(0, 0),
+		time.Unix(0, 1),
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2262, 4, 11, 23, 47, 16, 854775807, time.UTC),
+	}
+
+	for i := 1; i < len(times); i++ {
+		if previous, prefix := routeIDPrefix(times[i-1]), routeIDPrefix(times[i]); previous >= prefix {
+			t.Errorf("expected the prefix of %s to sort before the prefix of %s, got %s and %s", times[i-1], times[i], previous, prefix)
+		}
+	}
+
+	// the times beyond the range of the prefixes share the prefixes of its
+	// bounds
+	if prefix := routeIDPrefix(time.Date(1500, 1, 1, 0, 0, 0, 0, time.UTC)); prefix != routeIDPrefix(times[0]) {
+		t.Errorf("expected the prefix of 1500 to be clamped, got %s", prefix)
+	}
+	if prefix := routeIDPrefix(time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC)); prefix != routeIDPrefix(maxRouteTime) {
+		t.Errorf("expected the prefix of 2300 to be clamped, got %s", prefix)
+	}
+}
+
+func TestRouteStoreListOrder(t *testing.T) {
+	years := []int{2300, 1970, 1500, 2000, 1969, 1960}
+
+	newStores := map[string]func(t *testing.T) RouteStore{
+		"memory": func(t *testing.T) RouteStore {
+			return NewMemoryRouteStore()
+		},
+		"bolt": func(t *testing.T) RouteStore {
+			db, err := bolt.Open(filepath.Join(t.TempDir(), "routes.db"), 0600, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+
+			store, err := NewBoltRouteStore(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filter   RouteFilter
+		expected []int
+	}{
+		{
+			name:     "all",
+			expected: []int{1500, 1960, 1969, 1970, 2000, 2300},
+		},
+		{
+			name:     "started after 1965",
+			filter:   RouteFilter{StartedAfter: yearStart(1965)},
+			expected: []int{1969, 1970, 2000, 2300},
+		},
+		{
+			name:     "started before 1970",
+			filter:   RouteFilter{StartedBefore: yearStart(1970)},
+			expected: []int{1500, 1960, 1969},
+		},
+		{
+			name:     "started between 1400 and 1600",
+			filter:   RouteFilter{StartedAfter: yearStart(1400), StartedBefore: yearStart(1600)},
+			expected: []int{1500},
+		},
+		{
+			name:     "started after 2250",
+			filter:   RouteFilter{StartedAfter: yearStart(2250)},
+			expected: []int{2300},
+		},
+	}
+
+	for name, newStore := range newStores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			for _, year := range years {
+				id, err := newRouteID(yearStart(year))
+				if err != nil {
+					t.Fatal(err)
+				}
+				startedAt, err := ptypes.TimestampProto(yearStart(year))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := store.Save(&pb.Route{Id: id, StartedAt: startedAt}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					actual := []int{}
+					err := store.List(test.filter, func(route *pb.Route) bool {
+						started, err := ptypes.Timestamp(route.StartedAt)
+						if err != nil {
+							t.Fatal(err)
+						}
+						actual = append(actual, started.Year())
+						return true
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if !reflect.DeepEqual(actual, test.expected) {
+						t.Errorf("expected the routes of %v, got %v", test.expected, actual)
+					}
+				})
+			}
+		})
+	}
+}
+
+func yearStart(year int) time.Time {
+	return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+}
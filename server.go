@@ -2,45 +2,144 @@ package routeguide
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"io"
 	"log"
 	"math"
-	"strings"
-	"sync"
 	"time"
 
-	"github.com/gogo/protobuf/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
 	pb "github.com/ihcsim/routeguide/proto"
 )
 
-const metadataServerKey = "server"
+const (
+	metadataServerKey = "server"
+
+	// MetadataClientIDKey is the metadata header that identifies the client.
+	MetadataClientIDKey = "client-id"
+
+	unknownClientID = "unknown"
+
+	// maxNearest is the maximum number of features returned by FindNearest.
+	maxNearest = 1000
+)
+
+// ServerOption configures the route guide server.
+type ServerOption func(*routeGuideServer)
+
+// WithGeodesic sets the geodesic used to measure the distances of recorded
+// routes. The default is Haversine.
+func WithGeodesic(geodesic Geodesic) ServerOption {
+	return func(r *routeGuideServer) {
+		r.geodesic = geodesic
+	}
+}
+
+// WithSnapTolerance sets the distance in meters within which a point of a
+// recorded route is considered to pass a named feature. The default is 0,
+// where a point must match the location of the feature exactly.
+func WithSnapTolerance(meters float64) ServerOption {
+	return func(r *routeGuideServer) {
+		r.snapTolerance = meters
+	}
+}
+
+// WithRetention sets the retention policy of the RouteChat history. The
+// default is DefaultRetentionPolicy.
+func WithRetention(policy RetentionPolicy) ServerOption {
+	return func(r *routeGuideServer) {
+		r.retention = policy
+	}
+}
+
+// WithNoteLog sets the log that the RouteChat notes are persisted to. The
+// history is restored from the log when the server is created. By default,
+// the notes are only kept in memory.
+func WithNoteLog(noteLog *NoteLog) ServerOption {
+	return func(r *routeGuideServer) {
+		r.noteLog = noteLog
+	}
+}
+
+// WithIdleSpeed sets the speed in meters per second below which a segment of
+// a timed route is idle. The default is DefaultIdleSpeed.
+func WithIdleSpeed(speed float64) ServerOption {
+	return func(r *routeGuideServer) {
+		r.idleSpeed = speed
+	}
+}
+
+// WithGapThreshold sets the time between two points of a timed route beyond
+// which the segment between them is a gap. The default is
+// DefaultGapThreshold.
+func WithGapThreshold(threshold time.Duration) ServerOption {
+	return func(r *routeGuideServer) {
+		r.gapThreshold = threshold
+	}
+}
+
+// WithRouteStore sets the store of the recorded routes. The default is an
+// in-memory store.
+func WithRouteStore(store RouteStore) ServerOption {
+	return func(r *routeGuideServer) {
+		r.routes = store
+	}
+}
+
+// NewServer returns a new route guide server that exposes 19 GRPC APIs. The
+// features are served from the given store. If store is nil, an in-memory
+// store of the embedded dataset is used.
+func NewServer(hostname string, store FeatureStore, opts ...ServerOption) (pb.RouteGuideServer, error) {
+	if store == nil {
+		features, err := DefaultFeatures()
+		if err != nil {
+			return nil, err
+		}
+		store = NewMemoryStore(features)
+	}
 
-// NewServer returns a new route guide server that exposes 4 GRPC APIs.
-func NewServer(hostname string) (pb.RouteGuideServer, error) {
 	r := &routeGuideServer{
-		savedFeatures: []*pb.Feature{},
-		routeNotes:    make(map[string][]*pb.RouteNote),
-		hostname:      hostname,
+		store:        store,
+		routes:       NewMemoryRouteStore(),
+		geofences:    newGeofences(),
+		hostname:     hostname,
+		geodesic:     Haversine,
+		idleSpeed:    DefaultIdleSpeed,
+		gapThreshold: DefaultGapThreshold,
+		retention:    DefaultRetentionPolicy,
 	}
 
-	err := json.Unmarshal(featuresData, &r.savedFeatures)
+	for _, opt := range opts {
+		opt(r)
+	}
+	notes, err := newNoteBroker(r.retention, r.noteLog)
 	if err != nil {
 		return nil, err
 	}
+	r.notes = notes
 
-	return r, err
+	return r, nil
 }
 
 type routeGuideServer struct {
-	savedFeatures []*pb.Feature
-	routeNotes    map[string][]*pb.RouteNote
-	mutex         sync.Mutex
-	hostname      string
+	store     FeatureStore
+	routes    RouteStore
+	notes     *noteBroker
+	geofences *geofences
+	hostname  string
+	geodesic  Geodesic
+
+	snapTolerance float64
+	idleSpeed     float64
+	gapThreshold  time.Duration
+	retention     RetentionPolicy
+	noteLog       *NoteLog
 }
 
 // GetFeature obtains the feature at a given position.
@@ -49,11 +148,14 @@ func (r *routeGuideServer) GetFeature(ctx context.Context, point *pb.Point) (*pb
 	grpc.SetHeader(ctx, md)
 
 	log.Printf("[GetFeature] (req) %+v\n", point)
-	for _, feature := range r.savedFeatures {
-		if proto.Equal(feature.Location, point) {
-			log.Printf("[GetFeature] (resp) %+v\n", feature)
-			return feature, nil
-		}
+	feature, err := r.store.Get(point)
+	if err != nil {
+		return nil, err
+	}
+
+	if feature != nil {
+		log.Printf("[GetFeature] (resp) %+v\n", feature)
+		return feature, nil
 	}
 
 	return &pb.Feature{}, nil
@@ -67,29 +169,129 @@ func (r *routeGuideServer) ListFeatures(rectangle *pb.Rectangle, stream pb.Route
 	stream.SetHeader(md)
 
 	log.Printf("[ListFeatures] (req) %+v\n", rectangle)
-	for _, feature := range r.savedFeatures {
-		if inRange(feature, rectangle) {
-			log.Printf("[ListFeatures] (resp) %+v\n", feature)
-			if err := stream.Send(feature); err != nil {
-				return err
-			}
+	if rectangle == nil {
+		return status.Error(codes.InvalidArgument, "rectangle is required")
+	}
+	return r.sendFeatures("ListFeatures", rectangle, nil, stream)
+}
+
+// ListFeaturesInRadius obtains the features within the given circle, measured
+// by geodesic distance.
+func (r *routeGuideServer) ListFeaturesInRadius(circle *pb.Circle, stream pb.RouteGuide_ListFeaturesInRadiusServer) error {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	stream.SetHeader(md)
+
+	log.Printf("[ListFeaturesInRadius] (req) %+v\n", circle)
+	if err := validPoint(circle.GetCenter()); err != nil {
+		return err
+	}
+
+	if circle.RadiusMeters <= 0 {
+		return status.Error(codes.InvalidArgument, "radius must be positive")
+	}
+
+	within := func(feature *pb.Feature) bool {
+		return haversine(circle.Center, feature.Location) <= circle.RadiusMeters
+	}
+	return r.sendFeatures("ListFeaturesInRadius", circleBounds(circle.Center, circle.RadiusMeters), within, stream)
+}
+
+// ListFeaturesInPolygon obtains the features within the given polygon.
+func (r *routeGuideServer) ListFeaturesInPolygon(p *pb.Polygon, stream pb.RouteGuide_ListFeaturesInPolygonServer) error {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	stream.SetHeader(md)
+
+	log.Printf("[ListFeaturesInPolygon] (req) %+v\n", p)
+	polygon, err := newPolygon(p.GetVertices())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	within := func(feature *pb.Feature) bool {
+		return polygon.contains(feature.Location)
+	}
+	return r.sendFeatures("ListFeaturesInPolygon", polygon.bounds(), within, stream)
+}
+
+// ListFeaturesPage obtains a page of the features within the given rectangle,
+// ordered and filtered as requested.
+func (r *routeGuideServer) ListFeaturesPage(ctx context.Context, req *pb.ListFeaturesPageRequest) (*pb.ListFeaturesPageResponse, error) {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	grpc.SetHeader(ctx, md)
+
+	log.Printf("[ListFeaturesPage] (req) %+v\n", req)
+	pager, err := newFeaturePager(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	candidates := []*pb.Feature{}
+	collect := func(feature *pb.Feature) bool {
+		if pager.accept(feature) {
+			candidates = append(candidates, feature)
 		}
+		return true
 	}
 
-	return nil
+	if req.Rectangle != nil {
+		err = r.store.Query(req.Rectangle, collect)
+	} else {
+		err = r.store.Range(collect)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	features, token, err := pager.page(candidates)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &pb.ListFeaturesPageResponse{
+		Features:      features,
+		NextPageToken: token,
+	}
+	log.Printf("[ListFeaturesPage] (resp) %d features, next page token: %q\n", len(features), token)
+	return resp, nil
+}
+
+type featureSender interface {
+	Send(*pb.Feature) error
+}
+
+// sendFeatures streams the features within the rectangle that are accepted by
+// the filter. A nil filter accepts all the features.
+func (r *routeGuideServer) sendFeatures(api string, rectangle *pb.Rectangle, filter func(*pb.Feature) bool, stream featureSender) error {
+	var sendErr error
+	err := r.store.Query(rectangle, func(feature *pb.Feature) bool {
+		if filter != nil && !filter(feature) {
+			return true
+		}
+
+		log.Printf("[%s] (resp) %+v\n", api, feature)
+		sendErr = stream.Send(feature)
+		return sendErr == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendErr
 }
 
 // RecordRoute accepts a stream of points on a route being traversed, returning a
-// route summary when traversal is completed.
+// route summary when traversal is completed. The route is saved, so that it
+// can be retrieved with GetRoute.
 func (r *routeGuideServer) RecordRoute(stream pb.RouteGuide_RecordRouteServer) error {
 	md := metadata.Pairs(metadataServerKey, r.hostname)
 	stream.SetHeader(md)
 
 	var (
-		summary   = &pb.RouteSummary{}
+		session   = r.geofences.session(clientIdentity(stream.Context()), false)
+		recorder  = r.newRouteRecorder()
 		startTime = time.Now()
-		lastPoint *pb.Point
 	)
+	defer r.geofences.leave(session)
 
 	for {
 		point, err := stream.Recv()
@@ -100,22 +302,72 @@ func (r *routeGuideServer) RecordRoute(stream pb.RouteGuide_RecordRouteServer) e
 			return err
 		}
 		log.Printf("[RecordRoute] (req) %+v\n", point)
-		summary.PointCount++
+		r.geofences.observe(session, point, time.Now())
+
+		if err := recorder.add(point, nil); err != nil {
+			return err
+		}
+	}
+
+	summary, err := recorder.finish(stream.Context(), startTime, time.Now())
+	if err != nil {
+		return err
+	}
 
-		if _, err := r.GetFeature(context.Background(), point); err != nil {
+	log.Printf("[RecordRoute] (resp) %+v\n", summary)
+	if err := stream.SendAndClose(summary); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RecordTimedRoute accepts a stream of timed points on a route that was
+// traversed, returning a route summary measured with the times of the points.
+// The route is saved, so that it can be retrieved with GetRoute.
+func (r *routeGuideServer) RecordTimedRoute(stream pb.RouteGuide_RecordTimedRouteServer) error {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	stream.SetHeader(md)
+
+	var (
+		session   = r.geofences.session(clientIdentity(stream.Context()), true)
+		recorder  = r.newRouteRecorder()
+		startTime = time.Now()
+	)
+	defer r.geofences.leave(session)
+
+	for {
+		timed, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
 			return err
 		}
-		summary.FeatureCount++
+		log.Printf("[RecordTimedRoute] (req) %+v\n", timed)
 
-		if lastPoint != nil {
-			summary.Distance += dist(point, lastPoint)
+		if timed.Point == nil {
+			return status.Error(codes.InvalidArgument, "point is required")
+		}
+		if err := recorder.add(timed.Point, timed.Time); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
 		}
 
-		lastPoint = point
+		// the geofences are evaluated at the time the point was recorded
+		// at, which the recorder validated
+		var recordedAt time.Time
+		if timed.Time != nil {
+			recordedAt, _ = ptypes.Timestamp(timed.Time)
+		}
+		r.geofences.observe(session, timed.Point, recordedAt)
 	}
 
-	summary.ElapsedTime = int32(time.Now().Sub(startTime).Seconds())
-	log.Printf("[RecordRoute] (resp) %+v\n", summary)
+	summary, err := recorder.finish(stream.Context(), startTime, time.Now())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[RecordTimedRoute] (resp) %+v\n", summary)
 	if err := stream.SendAndClose(summary); err != nil {
 		return err
 	}
@@ -123,12 +375,121 @@ func (r *routeGuideServer) RecordRoute(stream pb.RouteGuide_RecordRouteServer) e
 	return nil
 }
 
+// saveRoute saves the route recorded by the client.
+func (r *routeGuideServer) saveRoute(ctx context.Context, points []*pb.Point, times []*timestamp.Timestamp, summary *pb.RouteSummary, startTime, endTime time.Time) (*pb.Route, error) {
+	id, err := newRouteID(startTime)
+	if err != nil {
+		return nil, err
+	}
+
+	startedAt, err := ptypes.TimestampProto(startTime)
+	if err != nil {
+		return nil, err
+	}
+
+	finishedAt, err := ptypes.TimestampProto(endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	route := &pb.Route{
+		Id:         id,
+		ClientId:   clientIdentity(ctx),
+		Points:     points,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		Summary:    summary,
+		PointTimes: times,
+	}
+	if err := r.routes.Save(route); err != nil {
+		return nil, err
+	}
+
+	return route, nil
+}
+
+// matchFeature returns the named feature closest to the point, within the
+// snapping tolerance. If there is no such feature, nil is returned.
+func (r *routeGuideServer) matchFeature(point *pb.Point) (*pb.Feature, error) {
+	if r.snapTolerance <= 0 {
+		feature, err := r.store.Get(point)
+		if err != nil || feature == nil || feature.Name == "" {
+			return nil, err
+		}
+		return feature, nil
+	}
+
+	var (
+		match    *pb.Feature
+		distance = r.snapTolerance
+	)
+	err := r.store.Query(circleBounds(point, r.snapTolerance), func(feature *pb.Feature) bool {
+		if feature.Name == "" {
+			return true
+		}
+
+		if d := haversine(point, feature.Location); d <= distance {
+			match, distance = feature, d
+		}
+		return true
+	})
+
+	return match, err
+}
+
 // RouteChat accepts a stream of route notes sent while a route is being traversed,
-// while receiving other route notes.
+// while receiving other route notes. The stream receives the notes of the
+// locations and areas it's subscribed to, including its own notes.
 func (r *routeGuideServer) RouteChat(stream pb.RouteGuide_RouteChatServer) error {
 	md := metadata.Pairs(metadataServerKey, r.hostname)
 	stream.SetHeader(md)
 
+	subscriber := r.notes.subscriber()
+	defer r.notes.remove(subscriber)
+
+	// the notes are received in their own goroutine, so that this goroutine
+	// is the only writer of the stream
+	received := make(chan error, 1)
+	go func() {
+		received <- r.receiveNotes(stream, subscriber)
+	}()
+
+	send := func() error {
+		for _, note := range subscriber.drain() {
+			log.Printf("[RouteChat] (resp) %+v\n", note)
+			if err := stream.Send(note); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for {
+		select {
+		case <-subscriber.wake:
+			if err := send(); err != nil {
+				return err
+			}
+		case <-subscriber.dropped:
+			return status.Error(codes.Aborted, ErrSubscriberOverflow.Error())
+		case err := <-received:
+			if err != nil {
+				return err
+			}
+
+			// the client is done sending, so flush the queued notes
+			return send()
+		}
+	}
+}
+
+// receiveNotes handles the notes received from the stream until the client
+// closes it, returning nil if it was closed normally.
+func (r *routeGuideServer) receiveNotes(stream pb.RouteGuide_RouteChatServer, subscriber *noteSubscriber) error {
+	sender := clientIdentity(stream.Context())
+	session := r.geofences.session(sender, false)
+	defer r.geofences.leave(session)
+
 	for {
 		note, err := stream.Recv()
 		if err != nil {
@@ -139,40 +500,453 @@ func (r *routeGuideServer) RouteChat(stream pb.RouteGuide_RouteChatServer) error
 		}
 		log.Printf("[RouteChat] (req) %+v\n", note)
 
-		key := fmt.Sprintf("(%d,%d)", note.Location.GetLatitude(), note.Location.GetLongitude())
+		switch note.Kind {
+		case pb.RouteNote_SUBSCRIBE, pb.RouteNote_UNSUBSCRIBE:
+			if err := r.subscription(subscriber, note); err != nil {
+				return err
+			}
+
+		case pb.RouteNote_NOTE:
+			if err := validPoint(note.GetLocation()); err != nil {
+				return err
+			}
 
-		// clone the server's route notes to not block other clients
-		r.mutex.Lock()
-		note.Message = strings.Replace(note.Message, "ack=0", "ack=1", -1)
-		r.routeNotes[key] = append(r.routeNotes[key], note)
-		clone := make([]*pb.RouteNote, len(r.routeNotes[key]))
-		copy(clone, r.routeNotes[key])
-		r.mutex.Unlock()
+			// the identity and the order of the note are only assigned by
+			// the server
+			note.SenderId = sender
+			note.SentAt, note.Sequence = nil, 0
 
-		for _, note := range clone {
-			log.Printf("[RouteChat] (resp) %+v\n", note)
-			if err := stream.Send(note); err != nil {
+			r.notes.subscribe(subscriber, note.Location)
+			r.notes.move(subscriber, sender, note.Location)
+			r.geofences.observe(session, note.Location, time.Now())
+			accepted, err := r.notes.publish(note)
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to save note: %s", err)
+			}
+			subscriber.acknowledge(accepted)
+
+		default:
+			return status.Errorf(codes.InvalidArgument, "unsupported note kind %s", note.Kind)
+		}
+	}
+}
+
+// CreateFeature saves a new feature at an unoccupied location.
+func (r *routeGuideServer) CreateFeature(ctx context.Context, feature *pb.Feature) (*pb.Feature, error) {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	grpc.SetHeader(ctx, md)
+
+	log.Printf("[CreateFeature] (req) %+v\n", feature)
+	if err := validPoint(feature.GetLocation()); err != nil {
+		return nil, err
+	}
+
+	created, err := r.store.Create(feature)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	log.Printf("[CreateFeature] (resp) %+v\n", created)
+	return created, nil
+}
+
+// UpdateFeature replaces the feature at the location of the given feature, if
+// its version matches the saved feature's version.
+func (r *routeGuideServer) UpdateFeature(ctx context.Context, feature *pb.Feature) (*pb.Feature, error) {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	grpc.SetHeader(ctx, md)
+
+	log.Printf("[UpdateFeature] (req) %+v\n", feature)
+	if err := validPoint(feature.GetLocation()); err != nil {
+		return nil, err
+	}
+
+	updated, err := r.store.Update(feature)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	log.Printf("[UpdateFeature] (resp) %+v\n", updated)
+	return updated, nil
+}
+
+// DeleteFeature removes the feature at the given location, if the version
+// matches the saved feature's version.
+func (r *routeGuideServer) DeleteFeature(ctx context.Context, req *pb.DeleteFeatureRequest) (*pb.Feature, error) {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	grpc.SetHeader(ctx, md)
+
+	log.Printf("[DeleteFeature] (req) %+v\n", req)
+	if err := validPoint(req.GetLocation()); err != nil {
+		return nil, err
+	}
+
+	deleted, err := r.store.Delete(req.Location, req.Version)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	log.Printf("[DeleteFeature] (resp) %+v\n", deleted)
+	return deleted, nil
+}
+
+// FindNearest obtains the k features closest to the given point, ordered by
+// their geodesic distance.
+func (r *routeGuideServer) FindNearest(ctx context.Context, req *pb.NearestRequest) (*pb.NearestResponse, error) {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	grpc.SetHeader(ctx, md)
+
+	log.Printf("[FindNearest] (req) %+v\n", req)
+	if err := validPoint(req.GetPoint()); err != nil {
+		return nil, err
+	}
+
+	if req.K <= 0 || req.K > maxNearest {
+		return nil, status.Errorf(codes.InvalidArgument, "k must be between 1 and %d", maxNearest)
+	}
+
+	if req.MaxDistanceMeters < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max distance must not be negative")
+	}
+
+	features, err := r.store.Nearest(req.Point, int(req.K), req.MaxDistanceMeters)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.NearestResponse{Features: features}
+	log.Printf("[FindNearest] (resp) %+v\n", resp)
+	return resp, nil
+}
+
+// WatchFeatures streams the current features within the given rectangle,
+// followed by their changes. A watch that is resumed from a resource version
+// only streams the changes after that version.
+func (r *routeGuideServer) WatchFeatures(req *pb.WatchFeaturesRequest, stream pb.RouteGuide_WatchFeaturesServer) error {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	stream.SetHeader(md)
+
+	log.Printf("[WatchFeatures] (req) %+v\n", req)
+	if req.ResourceVersion < 0 {
+		return status.Error(codes.InvalidArgument, "resource version must not be negative")
+	}
+	if req.Rectangle == nil {
+		return status.Error(codes.InvalidArgument, "rectangle is required")
+	}
+
+	watcher, err := r.store.Watch(req.ResourceVersion)
+	if err != nil {
+		return storeError(err)
+	}
+	defer watcher.Stop()
+
+	send := func(event *pb.FeatureEvent) error {
+		log.Printf("[WatchFeatures] (resp) %+v\n", event)
+		return stream.Send(event)
+	}
+
+	if req.ResourceVersion == 0 {
+		for _, feature := range watcher.Snapshot() {
+			if !inRange(feature.Location, req.Rectangle) {
+				continue
+			}
+
+			if err := send(featureEvent(pb.FeatureEvent_ADDED, feature, watcher.Revision())); err != nil {
+				return err
+			}
+		}
+
+		if err := send(&pb.FeatureEvent{Type: pb.FeatureEvent_SYNCED, ResourceVersion: watcher.Revision()}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case event, ok := <-watcher.Events():
+			if !ok {
+				// the client can resume from the last event it received
+				return status.Error(codes.Aborted, watcher.Err().Error())
+			}
+
+			if !inRange(event.Feature.GetLocation(), req.Rectangle) {
+				continue
+			}
+
+			if err := send(event); err != nil {
 				return err
 			}
 		}
 	}
 }
 
-func inRange(feature *pb.Feature, rectangle *pb.Rectangle) bool {
-	var (
-		top    = math.Max(float64(rectangle.Lo.GetLatitude()), float64(rectangle.Hi.GetLatitude()))
-		bottom = math.Min(float64(rectangle.Lo.GetLatitude()), float64(rectangle.Hi.GetLatitude()))
-		right  = math.Max(float64(rectangle.Lo.GetLongitude()), float64(rectangle.Hi.GetLongitude()))
-		left   = math.Min(float64(rectangle.Lo.GetLongitude()), float64(rectangle.Hi.GetLongitude()))
-	)
+// CreateGeofence registers a new geofence.
+func (r *routeGuideServer) CreateGeofence(ctx context.Context, fence *pb.Geofence) (*pb.Geofence, error) {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	grpc.SetHeader(ctx, md)
 
-	return float64(feature.Location.GetLatitude()) <= top && float64(feature.Location.GetLatitude()) >= bottom && float64(feature.Location.GetLongitude()) <= right && float64(feature.Location.GetLongitude()) >= left
+	log.Printf("[CreateGeofence] (req) %+v\n", fence)
+	created, err := r.geofences.create(fence)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	log.Printf("[CreateGeofence] (resp) %+v\n", created)
+	return created, nil
+}
+
+// WatchGeofenceEvents streams the events of the requested geofences, as the
+// clients enter, exit and dwell in them.
+func (r *routeGuideServer) WatchGeofenceEvents(req *pb.WatchGeofenceEventsRequest, stream pb.RouteGuide_WatchGeofenceEventsServer) error {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	stream.SetHeader(md)
+
+	log.Printf("[WatchGeofenceEvents] (req) %+v\n", req)
+	watcher, err := r.geofences.watch(req.GeofenceIds)
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	defer r.geofences.stop(watcher)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case event, ok := <-watcher.events:
+			if !ok {
+				return status.Error(codes.Aborted, r.geofences.stop(watcher).Error())
+			}
+
+			log.Printf("[WatchGeofenceEvents] (resp) %+v\n", event)
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
 }
 
-func dist(a *pb.Point, b *pb.Point) int32 {
+// GetRoute obtains the recorded route with the given ID.
+func (r *routeGuideServer) GetRoute(ctx context.Context, req *pb.RouteRequest) (*pb.Route, error) {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	grpc.SetHeader(ctx, md)
+
+	log.Printf("[GetRoute] (req) %+v\n", req)
+	route, err := r.getRoute(req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[GetRoute] (resp) %+v\n", route)
+	return route, nil
+}
+
+// ListRoutes obtains the recorded routes that match the request, in the order
+// they were started. The routes are streamed without their points.
+func (r *routeGuideServer) ListRoutes(req *pb.ListRoutesRequest, stream pb.RouteGuide_ListRoutesServer) error {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	stream.SetHeader(md)
+
+	log.Printf("[ListRoutes] (req) %+v\n", req)
+	filter := RouteFilter{ClientID: req.ClientId}
+	if req.StartedAfter != nil {
+		after, err := ptypes.Timestamp(req.StartedAfter)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "started after: %s", err)
+		}
+		filter.StartedAfter = after
+	}
+
+	if req.StartedBefore != nil {
+		before, err := ptypes.Timestamp(req.StartedBefore)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "started before: %s", err)
+		}
+		filter.StartedBefore = before
+	}
+
+	var sendErr error
+	err := r.routes.List(filter, func(route *pb.Route) bool {
+		listed := &pb.Route{
+			Id:         route.Id,
+			ClientId:   route.ClientId,
+			StartedAt:  route.StartedAt,
+			FinishedAt: route.FinishedAt,
+			Summary:    route.Summary,
+		}
+
+		log.Printf("[ListRoutes] (resp) %+v\n", listed)
+		sendErr = stream.Send(listed)
+		return sendErr == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendErr
+}
+
+// ExportRoute exports the recorded route with the given ID as a GPX document.
+func (r *routeGuideServer) ExportRoute(ctx context.Context, req *pb.RouteRequest) (*pb.RouteExport, error) {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	grpc.SetHeader(ctx, md)
+
+	log.Printf("[ExportRoute] (req) %+v\n", req)
+	route, err := r.getRoute(req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ExportGPX(route)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[ExportRoute] (resp) %d bytes of %s\n", len(data), GPXContentType)
+	return &pb.RouteExport{ContentType: GPXContentType, Data: data}, nil
+}
+
+// ListParticipants obtains the RouteChat participants at the given point.
+func (r *routeGuideServer) ListParticipants(ctx context.Context, point *pb.Point) (*pb.ParticipantList, error) {
+	md := metadata.Pairs(metadataServerKey, r.hostname)
+	grpc.SetHeader(ctx, md)
+
+	log.Printf("[ListParticipants] (req) %+v\n", point)
+	if err := validPoint(point); err != nil {
+		return nil, err
+	}
+
+	list := &pb.ParticipantList{Participants: r.notes.participants(point)}
+	log.Printf("[ListParticipants] (resp) %+v\n", list)
+	return list, nil
+}
+
+func (r *routeGuideServer) getRoute(id string) (*pb.Route, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "route ID is required")
+	}
+
+	route, err := r.routes.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if route == nil {
+		return nil, status.Errorf(codes.NotFound, "route %s not found", id)
+	}
+
+	return route, nil
+}
+
+// clientIdentity returns the identity of the client. The verified identities
+// are preferred: the subject of its bearer token, then the identity in its
+// client certificate. Otherwise, the client-id metadata header is used, and
+// if it's missing, the client's address.
+func clientIdentity(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok && claims.Subject != "" {
+		return claims.Subject
+	}
+
+	p, hasPeer := peer.FromContext(ctx)
+	if identity := PeerIdentity(p); identity != "" {
+		return identity
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(MetadataClientIDKey); len(ids) > 0 && ids[0] != "" {
+			return ids[0]
+		}
+	}
+
+	if hasPeer {
+		return p.Addr.String()
+	}
+
+	return unknownClientID
+}
+
+// subscription applies the SUBSCRIBE or UNSUBSCRIBE note to the subscriber.
+func (r *routeGuideServer) subscription(subscriber *noteSubscriber, note *pb.RouteNote) error {
+	subscribe := note.Kind == pb.RouteNote_SUBSCRIBE
+
+	if note.Area != nil {
+		if err := validArea(note.Area); err != nil {
+			return err
+		}
+
+		if subscribe {
+			r.notes.subscribeArea(subscriber, note.Area)
+		} else {
+			r.notes.unsubscribeArea(subscriber, note.Area)
+		}
+		return nil
+	}
+
+	if err := validPoint(note.GetLocation()); err != nil {
+		return err
+	}
+
+	if subscribe {
+		r.notes.subscribe(subscriber, note.Location)
+	} else {
+		r.notes.unsubscribe(subscriber, note.Location)
+	}
+	return nil
+}
+
+// validArea ensures that the corners of the rectangle are valid points.
+func validArea(area *pb.Rectangle) error {
+	if err := validPoint(area.GetLo()); err != nil {
+		return err
+	}
+	return validPoint(area.GetHi())
+}
+
+// validPoint ensures that the point is within the valid E7 latitude and
+// longitude ranges.
+func validPoint(point *pb.Point) error {
+	if err := checkPoint(point); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return nil
+}
+
+// storeError converts the errors of the feature store to GRPC errors.
+func storeError(err error) error {
+	switch err {
+	case ErrFeatureExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case ErrFeatureNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case ErrVersionConflict:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case ErrRevisionCompacted:
+		return status.Error(codes.OutOfRange, err.Error())
+	}
+
+	return err
+}
+
+func inRange(point *pb.Point, rectangle *pb.Rectangle) bool {
 	var (
-		operand1 = math.Pow(float64(a.GetLatitude())-float64(b.GetLatitude()), 2)
-		operand2 = math.Pow(float64(a.GetLongitude())-float64(b.GetLongitude()), 2)
+		top    = math.Max(float64(rectangle.GetLo().GetLatitude()), float64(rectangle.GetHi().GetLatitude()))
+		bottom = math.Min(float64(rectangle.GetLo().GetLatitude()), float64(rectangle.GetHi().GetLatitude()))
+		right  = math.Max(float64(rectangle.GetLo().GetLongitude()), float64(rectangle.GetHi().GetLongitude()))
+		left   = math.Min(float64(rectangle.GetLo().GetLongitude()), float64(rectangle.GetHi().GetLongitude()))
+		lat    = float64(point.GetLatitude())
+		lng    = float64(point.GetLongitude())
 	)
-	return int32(math.Sqrt(operand1 - operand2))
+
+	if lat > top || lat < bottom {
+		return false
+	}
+
+	// an oriented rectangle whose west edge is east of its east edge wraps
+	// around the antimeridian
+	if west, east := rectangle.GetLo().GetLongitude(), rectangle.GetHi().GetLongitude(); rectangle.GetOriented() && west > east {
+		return lng >= float64(west) || lng <= float64(east)
+	}
+
+	return lng <= right && lng >= left
 }
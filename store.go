@@ -0,0 +1,310 @@
+package routeguide
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+var (
+	// ErrFeatureExists is returned when a feature is created at a location
+	// that is already occupied.
+	ErrFeatureExists = errors.New("feature already exists")
+
+	// ErrFeatureNotFound is returned when there is no feature at the location
+	// to be modified.
+	ErrFeatureNotFound = errors.New("feature not found")
+
+	// ErrVersionConflict is returned when the version of a modification
+	// doesn't match the version of the saved feature.
+	ErrVersionConflict = errors.New("feature version conflict")
+)
+
+// FeatureStore knows how to look up and modify the features served by the
+// route guide server. Implementations must be safe for concurrent use.
+//
+// Every modification advances the store's revision, and the modified feature
+// is versioned with that revision. Features handed out by the store must not
+// be mutated.
+type FeatureStore interface {
+	// Get returns the feature at the given point. If there is no feature at
+	// the point, a nil feature is returned.
+	Get(point *pb.Point) (*pb.Feature, error)
+
+	// Query calls fn for every feature within the given rectangle. The
+	// iteration stops when fn returns false.
+	Query(rectangle *pb.Rectangle, fn func(*pb.Feature) bool) error
+
+	// Range calls fn for every feature in the store. The iteration stops when
+	// fn returns false.
+	Range(fn func(*pb.Feature) bool) error
+
+	// Count returns the number of features in the store.
+	Count() (int, error)
+
+	// Nearest returns up to k features closest to the given point, ordered by
+	// their geodesic distance. If maxDistance is positive, features further
+	// than maxDistance meters are excluded.
+	Nearest(point *pb.Point, k int, maxDistance float64) ([]*pb.NearbyFeature, error)
+
+	// Create saves a new feature, returning it with its version. It fails
+	// with ErrFeatureExists if the location is already occupied.
+	Create(feature *pb.Feature) (*pb.Feature, error)
+
+	// Update replaces the feature at the location of the given feature,
+	// returning it with its new version. It fails with ErrFeatureNotFound if
+	// the location is unoccupied, and with ErrVersionConflict if the versions
+	// don't match.
+	Update(feature *pb.Feature) (*pb.Feature, error)
+
+	// Delete removes the feature at the given point, returning the deleted
+	// feature. It fails with ErrFeatureNotFound if the location is unoccupied,
+	// and with ErrVersionConflict if the versions don't match.
+	Delete(point *pb.Point, version int64) (*pb.Feature, error)
+
+	// Replace atomically swaps the entire dataset with the given features.
+	// Features that are unchanged keep their versions, and every other
+	// change advances the revision. Queries that are in progress complete
+	// against the previous dataset.
+	Replace(features []*pb.Feature) error
+
+	// Watch subscribes to the changes made after the given revision. If the
+	// revision is zero, the watcher starts with a snapshot of the current
+	// features. It fails with ErrRevisionCompacted if the changes after the
+	// revision are no longer retained.
+	Watch(since int64) (*Watcher, error)
+}
+
+// DefaultFeatures returns the features of the embedded dataset.
+func DefaultFeatures() ([]*pb.Feature, error) {
+	features := []*pb.Feature{}
+	if err := json.Unmarshal(featuresData, &features); err != nil {
+		return nil, err
+	}
+
+	return features, nil
+}
+
+// NewMemoryStore returns a feature store that keeps the given features in
+// memory. The features are indexed by a spatial grid so that lookups don't
+// have to scan the entire dataset.
+func NewMemoryStore(features []*pb.Feature) FeatureStore {
+	m := &memoryStore{index: newGridIndex(defaultCellSize)}
+	m.load(features)
+	m.feed = newChangeFeed(m.revision)
+	return m
+}
+
+type memoryStore struct {
+	features  []*pb.Feature
+	positions map[pointKey]int
+	index     *gridIndex
+	revision  int64
+	feed      *changeFeed
+	mutex     sync.RWMutex
+}
+
+func (m *memoryStore) Get(point *pb.Point) (*pb.Feature, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.index.get(point), nil
+}
+
+func (m *memoryStore) Query(rectangle *pb.Rectangle, fn func(*pb.Feature) bool) error {
+	m.mutex.RLock()
+	features := m.index.query(rectangle)
+	m.mutex.RUnlock()
+
+	for _, feature := range features {
+		if !fn(feature) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryStore) Range(fn func(*pb.Feature) bool) error {
+	// iterate over a snapshot so that fn doesn't hold up the lock
+	m.mutex.RLock()
+	snapshot := make([]*pb.Feature, len(m.features))
+	copy(snapshot, m.features)
+	m.mutex.RUnlock()
+
+	for _, feature := range snapshot {
+		if !fn(feature) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryStore) Nearest(point *pb.Point, k int, maxDistance float64) ([]*pb.NearbyFeature, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.index.nearest(point, k, maxDistance), nil
+}
+
+func (m *memoryStore) Count() (int, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return len(m.features), nil
+}
+
+func (m *memoryStore) Create(feature *pb.Feature) (*pb.Feature, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := keyOf(feature.Location)
+	if _, exists := m.positions[key]; exists {
+		return nil, ErrFeatureExists
+	}
+
+	m.revision++
+	created := withVersion(feature, m.revision)
+	m.positions[key] = len(m.features)
+	m.features = append(m.features, created)
+	m.index.insert(created)
+	m.feed.publish([]*pb.FeatureEvent{featureEvent(pb.FeatureEvent_ADDED, created, m.revision)})
+
+	return created, nil
+}
+
+func (m *memoryStore) Update(feature *pb.Feature) (*pb.Feature, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := keyOf(feature.Location)
+	i, exists := m.positions[key]
+	if !exists {
+		return nil, ErrFeatureNotFound
+	}
+	if m.features[i].Version != feature.Version {
+		return nil, ErrVersionConflict
+	}
+
+	m.revision++
+	updated := withVersion(feature, m.revision)
+	m.features[i] = updated
+	m.index.insert(updated)
+	m.feed.publish([]*pb.FeatureEvent{featureEvent(pb.FeatureEvent_MODIFIED, updated, m.revision)})
+
+	return updated, nil
+}
+
+func (m *memoryStore) Delete(point *pb.Point, version int64) (*pb.Feature, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := keyOf(point)
+	i, exists := m.positions[key]
+	if !exists {
+		return nil, ErrFeatureNotFound
+	}
+
+	deleted := m.features[i]
+	if deleted.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	// move the last feature into the vacated position
+	last := len(m.features) - 1
+	m.features[i] = m.features[last]
+	m.positions[keyOf(m.features[i].Location)] = i
+	m.features[last] = nil
+	m.features = m.features[:last]
+	delete(m.positions, key)
+
+	m.revision++
+	m.index.remove(point)
+	m.feed.publish([]*pb.FeatureEvent{featureEvent(pb.FeatureEvent_DELETED, deleted, m.revision)})
+
+	return deleted, nil
+}
+
+func (m *memoryStore) Replace(features []*pb.Feature) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.feed.publish(m.load(features))
+	return nil
+}
+
+func (m *memoryStore) Watch(since int64) (*Watcher, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var snapshot []*pb.Feature
+	if since == 0 {
+		snapshot = make([]*pb.Feature, len(m.features))
+		copy(snapshot, m.features)
+	}
+
+	return m.feed.watch(since, m.revision, snapshot)
+}
+
+// load rebuilds the store with the given features, returning the changes to
+// the previous features. Features that are already in the store with the same
+// name keep their versions. Every other change advances the revision, so that
+// watches can be resumed from any change.
+func (m *memoryStore) load(features []*pb.Feature) []*pb.FeatureEvent {
+	var (
+		positions = make(map[pointKey]int)
+		index     = newGridIndex(defaultCellSize)
+		loaded    = []*pb.Feature{}
+		events    = []*pb.FeatureEvent{}
+	)
+
+	// later features replace earlier features at the same location
+	for _, feature := range features {
+		key := keyOf(feature.Location)
+		if i, exists := positions[key]; exists {
+			loaded[i] = feature
+		} else {
+			positions[key] = len(loaded)
+			loaded = append(loaded, feature)
+		}
+	}
+
+	for i, feature := range loaded {
+		existing := m.index.get(feature.Location)
+		if existing != nil && existing.Name == feature.Name {
+			loaded[i] = existing
+		} else {
+			m.revision++
+			loaded[i] = withVersion(feature, m.revision)
+
+			eventType := pb.FeatureEvent_ADDED
+			if existing != nil {
+				eventType = pb.FeatureEvent_MODIFIED
+			}
+			events = append(events, featureEvent(eventType, loaded[i], m.revision))
+		}
+		index.insert(loaded[i])
+	}
+
+	for _, feature := range m.features {
+		if _, exists := positions[keyOf(feature.Location)]; !exists {
+			m.revision++
+			events = append(events, featureEvent(pb.FeatureEvent_DELETED, feature, m.revision))
+		}
+	}
+
+	m.features, m.positions, m.index = loaded, positions, index
+	return events
+}
+
+// withVersion returns a copy of the feature with the given version, so that
+// the caller's feature isn't shared with the store.
+func withVersion(feature *pb.Feature, version int64) *pb.Feature {
+	clone := proto.Clone(feature).(*pb.Feature)
+	clone.Version = version
+	return clone
+}
@@ -0,0 +1,102 @@
+package routeguide
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+var benchmarkSizes = []int{100, 10000, 200000}
+
+// randFeatures returns n features scattered around the embedded dataset's
+// area, roughly 5 degrees square.
+func randFeatures(r *rand.Rand, n int) []*pb.Feature {
+	features := make([]*pb.Feature, n)
+	for i := range features {
+		features[i] = &pb.Feature{
+			Name:     fmt.Sprintf("feature #%d", i),
+			Location: randNearbyPoint(r),
+		}
+	}
+	return features
+}
+
+func randNearbyPoint(r *rand.Rand) *pb.Point {
+	return &pb.Point{
+		Latitude:  380000000 + r.Int31n(50000000),
+		Longitude: -770000000 + r.Int31n(50000000),
+	}
+}
+
+// randQuery returns a rectangle of about 0.2 degrees square.
+func randQuery(r *rand.Rand) *pb.Rectangle {
+	lo := randNearbyPoint(r)
+	return &pb.Rectangle{
+		Lo: lo,
+		Hi: &pb.Point{Latitude: lo.Latitude + 2000000, Longitude: lo.Longitude + 2000000},
+	}
+}
+
+func BenchmarkGetFeature(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		var (
+			r        = rand.New(rand.NewSource(1))
+			features = randFeatures(r, size)
+			store    = NewMemoryStore(features)
+		)
+
+		b.Run(fmt.Sprintf("scan/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				point := features[(i*7919)%size].Location
+				for _, feature := range features {
+					if proto.Equal(feature.Location, point) {
+						break
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("index/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := store.Get(features[(i*7919)%size].Location); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkListFeatures(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		var (
+			r        = rand.New(rand.NewSource(1))
+			features = randFeatures(r, size)
+			store    = NewMemoryStore(features)
+			queries  = make([]*pb.Rectangle, 100)
+		)
+		for i := range queries {
+			queries[i] = randQuery(r)
+		}
+
+		b.Run(fmt.Sprintf("scan/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rectangle := queries[i%len(queries)]
+				for _, feature := range features {
+					inRange(feature.Location, rectangle)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("index/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				err := store.Query(queries[i%len(queries)], func(*pb.Feature) bool { return true })
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,469 @@
+package routeguide
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	pb "github.com/ihcsim/routeguide/proto"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketFeatures = []byte("features")
+	bucketMeta     = []byte("meta")
+	keyRevision    = []byte("revision")
+)
+
+// NewBoltStore returns a feature store that persists features in the given
+// bolt database. Features are keyed by their location, ordered by latitude
+// and then longitude.
+func NewBoltStore(db *bolt.DB) (*BoltStore, error) {
+	var revision int64
+	err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketFeatures); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(bucketMeta); err != nil {
+			return err
+		}
+
+		revision = currentRevision(tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltStore{db: db, feed: newChangeFeed(revision)}, nil
+}
+
+// BoltStore is a FeatureStore backed by an embedded bolt key/value database.
+// The recent changes retained for watches are kept in memory, so watches
+// can't be resumed across restarts.
+type BoltStore struct {
+	db   *bolt.DB
+	feed *changeFeed
+
+	// mutex serializes the writers, so that their changes are published in
+	// the order they are committed in
+	mutex sync.Mutex
+}
+
+// Put saves the given features, overwriting any existing features at the same
+// locations. Every feature is versioned with a new revision.
+func (b *BoltStore) Put(features ...*pb.Feature) error {
+	return b.update(func(tx *bolt.Tx) ([]*pb.FeatureEvent, error) {
+		events := []*pb.FeatureEvent{}
+		for _, feature := range features {
+			existing, err := getFeature(tx, feature.Location)
+			if err != nil {
+				return nil, err
+			}
+
+			revision, err := nextRevision(tx)
+			if err != nil {
+				return nil, err
+			}
+
+			put := withVersion(feature, revision)
+			if err := putFeature(tx, put); err != nil {
+				return nil, err
+			}
+
+			eventType := pb.FeatureEvent_ADDED
+			if existing != nil {
+				eventType = pb.FeatureEvent_MODIFIED
+			}
+			events = append(events, featureEvent(eventType, put, revision))
+		}
+		return events, nil
+	})
+}
+
+// Get returns the feature at the given point.
+func (b *BoltStore) Get(point *pb.Point) (*pb.Feature, error) {
+	var feature *pb.Feature
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		feature, err = getFeature(tx, point)
+		return err
+	})
+
+	return feature, err
+}
+
+// Query calls fn for every feature within the given rectangle. Since keys are
+// ordered by latitude, only the keys within the rectangle's latitude band are
+// visited.
+func (b *BoltStore) Query(rectangle *pb.Rectangle, fn func(*pb.Feature) bool) error {
+	var (
+		bottom = rectangle.GetLo().GetLatitude()
+		top    = rectangle.GetHi().GetLatitude()
+	)
+	if bottom > top {
+		bottom, top = top, bottom
+	}
+
+	var (
+		first = featureKey(&pb.Point{Latitude: bottom, Longitude: minE7})
+		last  = featureKey(&pb.Point{Latitude: top, Longitude: maxE7})
+	)
+
+	return b.scan(first, last, func(feature *pb.Feature) bool {
+		if !inRange(feature.Location, rectangle) {
+			return true
+		}
+		return fn(feature)
+	})
+}
+
+// Range calls fn for every feature in the store.
+func (b *BoltStore) Range(fn func(*pb.Feature) bool) error {
+	return b.scan(nil, nil, fn)
+}
+
+// Nearest returns up to k features closest to the given point. Since the keys
+// aren't spatially indexed, all the features are visited.
+func (b *BoltStore) Nearest(point *pb.Point, k int, maxDistance float64) ([]*pb.NearbyFeature, error) {
+	set := newNearestSet(point, k, maxDistance)
+	err := b.scan(nil, nil, func(feature *pb.Feature) bool {
+		set.offer(feature)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return set.sorted(), nil
+}
+
+// Count returns the number of features in the store.
+func (b *BoltStore) Count() (int, error) {
+	var count int
+	err := b.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(bucketFeatures).Stats().KeyN
+		return nil
+	})
+
+	return count, err
+}
+
+// Create saves a new feature, returning it with its version.
+func (b *BoltStore) Create(feature *pb.Feature) (*pb.Feature, error) {
+	var created *pb.Feature
+	err := b.update(func(tx *bolt.Tx) ([]*pb.FeatureEvent, error) {
+		existing, err := getFeature(tx, feature.Location)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, ErrFeatureExists
+		}
+
+		revision, err := nextRevision(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		created = withVersion(feature, revision)
+		return []*pb.FeatureEvent{featureEvent(pb.FeatureEvent_ADDED, created, revision)}, putFeature(tx, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// Update replaces the feature at the location of the given feature, returning
+// it with its new version.
+func (b *BoltStore) Update(feature *pb.Feature) (*pb.Feature, error) {
+	var updated *pb.Feature
+	err := b.update(func(tx *bolt.Tx) ([]*pb.FeatureEvent, error) {
+		existing, err := getFeature(tx, feature.Location)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, ErrFeatureNotFound
+		}
+		if existing.Version != feature.Version {
+			return nil, ErrVersionConflict
+		}
+
+		revision, err := nextRevision(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		updated = withVersion(feature, revision)
+		return []*pb.FeatureEvent{featureEvent(pb.FeatureEvent_MODIFIED, updated, revision)}, putFeature(tx, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// Delete removes the feature at the given point, returning the deleted
+// feature.
+func (b *BoltStore) Delete(point *pb.Point, version int64) (*pb.Feature, error) {
+	var deleted *pb.Feature
+	err := b.update(func(tx *bolt.Tx) ([]*pb.FeatureEvent, error) {
+		var err error
+		deleted, err = getFeature(tx, point)
+		if err != nil {
+			return nil, err
+		}
+		if deleted == nil {
+			return nil, ErrFeatureNotFound
+		}
+		if deleted.Version != version {
+			return nil, ErrVersionConflict
+		}
+
+		revision, err := nextRevision(tx)
+		if err != nil {
+			return nil, err
+		}
+		return []*pb.FeatureEvent{featureEvent(pb.FeatureEvent_DELETED, deleted, revision)}, tx.Bucket(bucketFeatures).Delete(featureKey(point))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// Replace atomically swaps all the features in the store with the given
+// features. Features that are unchanged keep their versions, and every other
+// change advances the revision.
+func (b *BoltStore) Replace(features []*pb.Feature) error {
+	return b.update(func(tx *bolt.Tx) ([]*pb.FeatureEvent, error) {
+		// later features replace earlier features at the same location
+		var (
+			keys     = []string{}
+			replaced = make(map[string]*pb.Feature)
+		)
+		for _, feature := range features {
+			key := string(featureKey(feature.Location))
+			if _, exists := replaced[key]; !exists {
+				keys = append(keys, key)
+			}
+			replaced[key] = feature
+		}
+
+		events := []*pb.FeatureEvent{}
+		for _, key := range keys {
+			feature := replaced[key]
+			existing, err := getFeature(tx, feature.Location)
+			if err != nil {
+				return nil, err
+			}
+
+			if existing != nil && existing.Name == feature.Name {
+				replaced[key] = existing
+				continue
+			}
+
+			revision, err := nextRevision(tx)
+			if err != nil {
+				return nil, err
+			}
+			replaced[key] = withVersion(feature, revision)
+
+			eventType := pb.FeatureEvent_ADDED
+			if existing != nil {
+				eventType = pb.FeatureEvent_MODIFIED
+			}
+			events = append(events, featureEvent(eventType, replaced[key], revision))
+		}
+
+		previous, err := decodeFeatures(tx, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, feature := range previous {
+			if _, exists := replaced[string(featureKey(feature.Location))]; exists {
+				continue
+			}
+
+			revision, err := nextRevision(tx)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, featureEvent(pb.FeatureEvent_DELETED, feature, revision))
+		}
+
+		if err := tx.DeleteBucket(bucketFeatures); err != nil {
+			return nil, err
+		}
+		if _, err := tx.CreateBucket(bucketFeatures); err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			if err := putFeature(tx, replaced[key]); err != nil {
+				return nil, err
+			}
+		}
+		return events, nil
+	})
+}
+
+// Watch subscribes to the changes made after the given revision.
+func (b *BoltStore) Watch(since int64) (*Watcher, error) {
+	// block the writers, so that no changes are published before the watcher
+	// is subscribed
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var (
+		revision int64
+		snapshot []*pb.Feature
+	)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		revision = currentRevision(tx)
+		if since != 0 {
+			return nil
+		}
+
+		var err error
+		snapshot, err = decodeFeatures(tx, nil, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b.feed.watch(since, revision, snapshot)
+}
+
+// update runs fn in a read-write transaction, and publishes the changes
+// returned by fn once the transaction is committed.
+func (b *BoltStore) update(fn func(tx *bolt.Tx) ([]*pb.FeatureEvent, error)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var events []*pb.FeatureEvent
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		events, err = fn(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	b.feed.publish(events)
+	return nil
+}
+
+// scan decodes the features whose keys fall within [first, last] in a single
+// read transaction. A nil first or last key leaves that end unbounded.
+func (b *BoltStore) scan(first, last []byte, fn func(*pb.Feature) bool) error {
+	var features []*pb.Feature
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		features, err = decodeFeatures(tx, first, last)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// the callbacks are invoked outside of the transaction so that slow
+	// consumers don't keep it open
+	for _, feature := range features {
+		if !fn(feature) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// decodeFeatures decodes the features whose keys fall within [first, last].
+// A nil first or last key leaves that end unbounded.
+func decodeFeatures(tx *bolt.Tx, first, last []byte) ([]*pb.Feature, error) {
+	var (
+		features = []*pb.Feature{}
+		cursor   = tx.Bucket(bucketFeatures).Cursor()
+		key      []byte
+		value    []byte
+	)
+	if first == nil {
+		key, value = cursor.First()
+	} else {
+		key, value = cursor.Seek(first)
+	}
+
+	for ; key != nil; key, value = cursor.Next() {
+		if last != nil && string(key) > string(last) {
+			break
+		}
+
+		feature := &pb.Feature{}
+		if err := proto.Unmarshal(value, feature); err != nil {
+			return nil, err
+		}
+		features = append(features, feature)
+	}
+
+	return features, nil
+}
+
+func getFeature(tx *bolt.Tx, point *pb.Point) (*pb.Feature, error) {
+	value := tx.Bucket(bucketFeatures).Get(featureKey(point))
+	if value == nil {
+		return nil, nil
+	}
+
+	feature := &pb.Feature{}
+	if err := proto.Unmarshal(value, feature); err != nil {
+		return nil, err
+	}
+	return feature, nil
+}
+
+func putFeature(tx *bolt.Tx, feature *pb.Feature) error {
+	value, err := proto.Marshal(feature)
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket(bucketFeatures).Put(featureKey(feature.Location), value)
+}
+
+// currentRevision returns the revision persisted in the meta bucket.
+func currentRevision(tx *bolt.Tx) int64 {
+	if value := tx.Bucket(bucketMeta).Get(keyRevision); value != nil {
+		return int64(binary.BigEndian.Uint64(value))
+	}
+	return 0
+}
+
+// nextRevision advances the revision persisted in the meta bucket.
+func nextRevision(tx *bolt.Tx) (int64, error) {
+	revision := currentRevision(tx) + 1
+
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(revision))
+	return revision, tx.Bucket(bucketMeta).Put(keyRevision, value)
+}
+
+const (
+	minE7 = -1800000000
+	maxE7 = 1800000000
+)
+
+// featureKey encodes a point as 8 bytes that sort in the same order as its
+// latitude and longitude.
+func featureKey(point *pb.Point) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint32(key[:4], uint32(point.GetLatitude())^1<<31)
+	binary.BigEndian.PutUint32(key[4:], uint32(point.GetLongitude())^1<<31)
+	return key
+}
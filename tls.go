@@ -0,0 +1,229 @@
+package routeguide
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// DefaultCertReloadInterval is the interval at which the certificate files
+// are polled for changes.
+const DefaultCertReloadInterval = 10 * time.Second
+
+var (
+	// errServerNameRequired is returned when the server certificate can't be
+	// verified, because the name of the server is unknown.
+	errServerNameRequired = errors.New("the server name is required to verify its certificate")
+
+	// errNoCertificate is returned when a server has no certificate to
+	// present.
+	errNoCertificate = errors.New("the server has no certificate")
+)
+
+// CertReloader serves a certificate and a CA bundle that are reloaded whenever
+// their files change on disk, so that they can be rotated without a restart.
+// The connections that are already established keep the certificates they
+// were negotiated with.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	watcher  *fileWatcher
+
+	mutex sync.RWMutex
+	cert  *tls.Certificate
+	pool  *x509.CertPool
+}
+
+// NewCertReloader loads the PEM-encoded certificate and key, and the bundle
+// of the CAs that the peer certificates are verified with. The certificate
+// files and the CA file are optional. The files are polled for changes at the
+// given interval. A zero interval disables polling.
+func NewCertReloader(certFile, keyFile, caFile string, interval time.Duration) (*CertReloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("the certificate and the key files must be given together")
+	}
+
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+
+	files := []string{}
+	for _, path := range []string{certFile, keyFile, caFile} {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	r.watcher = newFileWatcher("tls", files, interval, r.Reload)
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Run reloads the certificates whenever a signal is received on the trigger
+// channel, or whenever their files change. It returns when the stop channel
+// is closed.
+func (r *CertReloader) Run(trigger <-chan os.Signal, stop <-chan struct{}) {
+	r.watcher.Run(trigger, stop)
+}
+
+// Reload reads the certificate files. If any of them can't be read, for
+// example because it's halfway through a rotation, the previous certificates
+// are kept.
+func (r *CertReloader) Reload() error {
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		loaded, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("%s: %s", r.certFile, err)
+		}
+
+		loaded.Leaf, err = x509.ParseCertificate(loaded.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("%s: %s", r.certFile, err)
+		}
+		cert = &loaded
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := ioutil.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("%s: %s", r.caFile, err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: no certificates found", r.caFile)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.cert, r.pool = cert, pool
+	if cert != nil {
+		log.Printf("[tls] loaded certificate of %s (expires %s)", certIdentity(cert.Leaf), cert.Leaf.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ServerConfig returns the TLS config of a server that presents the current
+// certificate, and verifies the client certificates against the current CA
+// bundle, depending on the client authentication type.
+func (r *CertReloader) ServerConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mutex.RLock()
+			defer r.mutex.RUnlock()
+
+			if r.cert == nil {
+				return nil, errNoCertificate
+			}
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientCAs:    r.pool,
+				ClientAuth:   clientAuth,
+				NextProtos:   []string{"h2"},
+			}, nil
+		},
+	}
+}
+
+// ClientConfig returns the TLS config of a client that verifies the server
+// certificate against the current CA bundle, and presents the current
+// certificate if the server asks for one. Without a CA bundle, the server
+// certificate is verified against the system roots. If the server name is
+// empty, the host of the target address is used.
+func (r *CertReloader) ClientConfig(serverName string) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			r.mutex.RLock()
+			defer r.mutex.RUnlock()
+
+			if r.cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return r.cert, nil
+		},
+	}
+
+	if r.caFile != "" {
+		// the server certificate is verified by VerifyConnection instead, so
+		// that it's verified against the reloaded CA bundle
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = r.verifyServer
+	}
+	return config
+}
+
+// verifyServer verifies the server certificate of the connection against the
+// current CA bundle.
+func (r *CertReloader) verifyServer(state tls.ConnectionState) error {
+	if state.ServerName == "" {
+		return errServerNameRequired
+	}
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("the server presented no certificate")
+	}
+
+	r.mutex.RLock()
+	pool := r.pool
+	r.mutex.RUnlock()
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Roots:         pool,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// PeerIdentity returns the identity in the verified certificate of the peer,
+// or an empty string if the peer didn't present one. The identity is the
+// first URI SAN, or else the first DNS SAN, or else the common name of the
+// certificate.
+func PeerIdentity(p *peer.Peer) string {
+	if p == nil {
+		return ""
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return ""
+	}
+	return certIdentity(info.State.PeerCertificates[0])
+}
+
+func certIdentity(cert *x509.Certificate) string {
+	switch {
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	default:
+		return cert.Subject.CommonName
+	}
+}
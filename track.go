@@ -0,0 +1,205 @@
+package routeguide
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+// TrackPoint is a point of a recorded track, and the time it was recorded at.
+// The time is zero if the track file doesn't record it.
+type TrackPoint struct {
+	Point *pb.Point
+	Time  time.Time
+}
+
+// LoadTrack reads the points of the track file at the given path. Files with
+// the .gpx extension are read as GPX documents, whose routes and tracks are
+// concatenated. Files with the .kml extension are read as KML documents, whose
+// LineStrings and gx:Tracks are concatenated.
+func LoadTrack(path string) ([]TrackPoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var track []TrackPoint
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".gpx":
+		track, err = parseGPX(data)
+	case ".kml":
+		track, err = parseKML(data)
+	default:
+		return nil, fmt.Errorf("unsupported track file extension %q", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(track) == 0 {
+		return nil, fmt.Errorf("no track points found in %s", path)
+	}
+
+	return track, nil
+}
+
+func parseGPX(data []byte) ([]TrackPoint, error) {
+	doc := gpxDocument{}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	points := []gpxPoint{}
+	for _, route := range doc.Routes {
+		points = append(points, route.Points...)
+	}
+	for _, track := range doc.Tracks {
+		for _, segment := range track.Segments {
+			points = append(points, segment.Points...)
+		}
+	}
+
+	track := make([]TrackPoint, len(points))
+	for i, p := range points {
+		point := &pb.Point{Latitude: int32(p.Lat), Longitude: int32(p.Lon)}
+		if err := checkPoint(point); err != nil {
+			return nil, fmt.Errorf("point %d: %s", i, err)
+		}
+		track[i].Point = point
+
+		if p.Time != "" {
+			t, err := time.Parse(time.RFC3339, strings.TrimSpace(p.Time))
+			if err != nil {
+				return nil, fmt.Errorf("point %d: invalid time: %s", i, err)
+			}
+			track[i].Time = t
+		}
+	}
+
+	return track, nil
+}
+
+// parseKML reads the coordinates of the LineStrings, and the coordinates and
+// times of the gx:Tracks, in the order they appear in the document.
+func parseKML(data []byte) ([]TrackPoint, error) {
+	var (
+		track   = []TrackPoint{}
+		decoder = xml.NewDecoder(bytes.NewReader(data))
+		parents = []string{}
+
+		// the times and coordinates of the current gx:Track
+		whens  = []time.Time{}
+		coords = []*pb.Point{}
+	)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			parent := ""
+			if len(parents) > 0 {
+				parent = parents[len(parents)-1]
+			}
+
+			switch {
+			case element.Name.Local == "coordinates" && parent == "LineString":
+				var text string
+				if err := decoder.DecodeElement(&text, &element); err != nil {
+					return nil, err
+				}
+
+				for _, tuple := range strings.Fields(text) {
+					point, err := parseKMLCoord(strings.Split(tuple, ","))
+					if err != nil {
+						return nil, fmt.Errorf("line %d: %s", lineAt(data, decoder.InputOffset()), err)
+					}
+					track = append(track, TrackPoint{Point: point})
+				}
+				continue
+
+			case element.Name.Local == "when" && parent == "Track":
+				var text string
+				if err := decoder.DecodeElement(&text, &element); err != nil {
+					return nil, err
+				}
+
+				when, err := time.Parse(time.RFC3339, strings.TrimSpace(text))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid time: %s", lineAt(data, decoder.InputOffset()), err)
+				}
+				whens = append(whens, when)
+				continue
+
+			case element.Name.Local == "coord" && parent == "Track":
+				var text string
+				if err := decoder.DecodeElement(&text, &element); err != nil {
+					return nil, err
+				}
+
+				point, err := parseKMLCoord(strings.Fields(text))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s", lineAt(data, decoder.InputOffset()), err)
+				}
+				coords = append(coords, point)
+				continue
+			}
+
+			parents = append(parents, element.Name.Local)
+
+		case xml.EndElement:
+			parents = parents[:len(parents)-1]
+
+			if element.Name.Local == "Track" {
+				if len(whens) > 0 && len(whens) != len(coords) {
+					return nil, fmt.Errorf("line %d: gx:Track has %d times and %d coordinates", lineAt(data, decoder.InputOffset()), len(whens), len(coords))
+				}
+
+				for i, point := range coords {
+					trackPoint := TrackPoint{Point: point}
+					if len(whens) > 0 {
+						trackPoint.Time = whens[i]
+					}
+					track = append(track, trackPoint)
+				}
+				whens, coords = whens[:0], coords[:0]
+			}
+		}
+	}
+
+	return track, nil
+}
+
+// parseKMLCoord converts the longitude and latitude in degrees, followed by
+// an optional altitude, into a point.
+func parseKMLCoord(fields []string) (*pb.Point, error) {
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, fmt.Errorf("invalid coordinates %q", strings.Join(fields, ","))
+	}
+
+	lng, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude %q", fields[0])
+	}
+
+	lat, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude %q", fields[1])
+	}
+
+	return pointFromDegrees(lat, lng)
+}
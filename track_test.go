@@ -0,0 +1,285 @@
+package routeguide
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+func writeTrack(t *testing.T, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExportGPXRoundTrip(t *testing.T) {
+	var (
+		start  = time.Date(2020, 9, 13, 12, 26, 40, 123456789, time.UTC)
+		points = []*pb.Point{
+			{Latitude: 409146138, Longitude: -746188906},
+			{Latitude: -337000001, Longitude: 1512000009},
+			{Latitude: 1, Longitude: 1800000000},
+			{Latitude: -900000000, Longitude: -1800000000},
+		}
+		times = []time.Time{start, start.Add(time.Second), start.Add(time.Minute), start.Add(time.Hour)}
+	)
+
+	timestamps := make([]*timestamp.Timestamp, len(times))
+	for i, at := range times {
+		timestamps[i], _ = ptypes.TimestampProto(at)
+	}
+
+	tests := []struct {
+		name  string
+		times []*timestamp.Timestamp
+	}{
+		{name: "timed", times: timestamps},
+		{name: "untimed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route := &pb.Route{
+				Id:         "route",
+				ClientId:   "client",
+				Points:     points,
+				PointTimes: test.times,
+				StartedAt:  timestamps[0],
+			}
+
+			data, err := ExportGPX(route)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), `xmlns="http://www.topografix.com/GPX/1/1"`) {
+				t.Errorf("expected a GPX 1.1 document, got:\n%s", data)
+			}
+
+			track, err := LoadTrack(writeTrack(t, "route.gpx", data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(track) != len(points) {
+				t.Fatalf("expected %d points, got %d", len(points), len(track))
+			}
+
+			for i, p := range track {
+				if !reflect.DeepEqual(p.Point, points[i]) {
+					t.Errorf("point %d: expected %v, got %v", i, points[i], p.Point)
+				}
+
+				expected := time.Time{}
+				if test.times != nil {
+					expected = times[i]
+				}
+				if !p.Time.Equal(expected) {
+					t.Errorf("point %d: expected the time %s, got %s", i, expected, p.Time)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTrack(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected []TrackPoint
+	}{
+		{
+			name: "GPX routes and tracks",
+			file: "track.gpx",
+			content: `<?xml version="1.0"?>
+<gpx version="1.0" creator="test">
+  <rte><rtept lat="1.5" lon="-2.5"></rtept></rte>
+  <trk>
+    <trkseg>
+      <trkpt lat="10" lon="20"><time>2020-01-01T00:00:00Z</time></trkpt>
+      <trkpt lat="10.0000001" lon="20"><time> 2020-01-01T00:00:01+01:00 </time></trkpt>
+    </trkseg>
+    <trkseg><trkpt lat="-90" lon="180"/></trkseg>
+  </trk>
+</gpx>`,
+			expected: []TrackPoint{
+				{Point: &pb.Point{Latitude: 15000000, Longitude: -25000000}},
+				{Point: &pb.Point{Latitude: 100000000, Longitude: 200000000}, Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+				{Point: &pb.Point{Latitude: 100000001, Longitude: 200000000}, Time: time.Date(2019, 12, 31, 23, 0, 1, 0, time.UTC)},
+				{Point: &pb.Point{Latitude: -900000000, Longitude: 1800000000}},
+			},
+		},
+		{
+			name: "KML line strings and tracks",
+			file: "track.KML",
+			content: `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+  <Document>
+    <Placemark>
+      <LineString>
+        <coordinates>
+          -2.5,1.5,100 20,10
+        </coordinates>
+      </LineString>
+    </Placemark>
+    <Placemark>
+      <Point><coordinates>50,50</coordinates></Point>
+    </Placemark>
+    <Placemark>
+      <gx:Track>
+        <when>2020-01-01T00:00:00Z</when>
+        <when>2020-01-01T00:00:05Z</when>
+        <gx:coord>20 10 5</gx:coord>
+        <gx:coord>20.5 10.5</gx:coord>
+      </gx:Track>
+      <gx:Track>
+        <gx:coord>-180 -90</gx:coord>
+      </gx:Track>
+    </Placemark>
+  </Document>
+</kml>`,
+			expected: []TrackPoint{
+				{Point: &pb.Point{Latitude: 15000000, Longitude: -25000000}},
+				{Point: &pb.Point{Latitude: 100000000, Longitude: 200000000}},
+				{Point: &pb.Point{Latitude: 100000000, Longitude: 200000000}, Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+				{Point: &pb.Point{Latitude: 105000000, Longitude: 205000000}, Time: time.Date(2020, 1, 1, 0, 0, 5, 0, time.UTC)},
+				{Point: &pb.Point{Latitude: -900000000, Longitude: -1800000000}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			track, err := LoadTrack(writeTrack(t, test.file, []byte(test.content)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(track) != len(test.expected) {
+				t.Fatalf("expected %d points, got %d", len(test.expected), len(track))
+			}
+			for i, expected := range test.expected {
+				if !reflect.DeepEqual(track[i].Point, expected.Point) || !track[i].Time.Equal(expected.Time) {
+					t.Errorf("point %d: expected %v at %s, got %v at %s", i, expected.Point, expected.Time, track[i].Point, track[i].Time)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTrackErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "unsupported extension",
+			file:     "track.csv",
+			content:  "1,2",
+			expected: `unsupported track file extension ".csv"`,
+		},
+		{
+			name:     "invalid GPX",
+			file:     "track.gpx",
+			content:  `<gpx><trk><trkseg><trkpt lat="1" lon="2"></trkseg></trk></gpx>`,
+			expected: "XML syntax error",
+		},
+		{
+			name:     "GPX without points",
+			file:     "track.gpx",
+			content:  `<gpx version="1.1"><trk><trkseg></trkseg></trk></gpx>`,
+			expected: "no track points found",
+		},
+		{
+			name:     "GPX latitude that isn't a number",
+			file:     "track.gpx",
+			content:  `<gpx><trk><trkseg><trkpt lat="north" lon="2"/></trkseg></trk></gpx>`,
+			expected: `invalid lat "north"`,
+		},
+		{
+			name:     "GPX longitude out of range",
+			file:     "track.gpx",
+			content:  `<gpx><trk><trkseg><trkpt lat="1" lon="181"/></trkseg></trk></gpx>`,
+			expected: `invalid lon "181"`,
+		},
+		{
+			name:     "GPX latitude out of range",
+			file:     "track.gpx",
+			content:  `<gpx><rte><rtept lat="1" lon="2"/><rtept lat="91" lon="2"/></rte></gpx>`,
+			expected: "point 1:",
+		},
+		{
+			name:     "GPX time",
+			file:     "track.gpx",
+			content:  `<gpx><trk><trkseg><trkpt lat="1" lon="2"><time>yesterday</time></trkpt></trkseg></trk></gpx>`,
+			expected: "point 0: invalid time",
+		},
+		{
+			name:     "invalid KML",
+			file:     "track.kml",
+			content:  `<kml><Document><Placemark></Document></kml>`,
+			expected: "XML syntax error",
+		},
+		{
+			name:     "KML without points",
+			file:     "track.kml",
+			content:  `<kml><Document><Placemark><Point><coordinates>1,2</coordinates></Point></Placemark></Document></kml>`,
+			expected: "no track points found",
+		},
+		{
+			name:     "KML coordinates",
+			file:     "track.kml",
+			content:  "<kml>\n<LineString>\n<coordinates>1,2 3</coordinates>\n</LineString>\n</kml>",
+			expected: `line 3: invalid coordinates "3"`,
+		},
+		{
+			name:     "KML longitude",
+			file:     "track.kml",
+			content:  `<kml><LineString><coordinates>east,2</coordinates></LineString></kml>`,
+			expected: `invalid longitude "east"`,
+		},
+		{
+			name:     "KML latitude out of range",
+			file:     "track.kml",
+			content:  `<kml><gx:Track xmlns:gx="http://www.google.com/kml/ext/2.2"><gx:coord>2 91</gx:coord></gx:Track></kml>`,
+			expected: "latitude",
+		},
+		{
+			name:     "KML time",
+			file:     "track.kml",
+			content:  `<kml><gx:Track xmlns:gx="http://www.google.com/kml/ext/2.2"><when>now</when><gx:coord>2 1</gx:coord></gx:Track></kml>`,
+			expected: "invalid time",
+		},
+		{
+			name:     "KML times and coordinates",
+			file:     "track.kml",
+			content:  `<kml><gx:Track xmlns:gx="http://www.google.com/kml/ext/2.2"><when>2020-01-01T00:00:00Z</when><gx:coord>2 1</gx:coord><gx:coord>3 1</gx:coord></gx:Track></kml>`,
+			expected: "gx:Track has 1 times and 2 coordinates",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadTrack(writeTrack(t, test.file, []byte(test.content)))
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected an error containing %q, got %v", test.expected, err)
+			}
+		})
+	}
+
+	if _, err := LoadTrack(filepath.Join(t.TempDir(), "missing.gpx")); err == nil {
+		t.Error("expected an error of the missing file")
+	}
+}
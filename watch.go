@@ -0,0 +1,184 @@
+package routeguide
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	pb "github.com/ihcsim/routeguide/proto"
+)
+
+var (
+	// ErrRevisionCompacted is returned when a watch is resumed from a
+	// revision whose changes are no longer retained by the store, or that the
+	// store doesn't know of.
+	ErrRevisionCompacted = errors.New("resource version is too old to be resumed from")
+
+	// ErrWatcherOverflow is returned when a watcher falls too far behind the
+	// changes of the store.
+	ErrWatcherOverflow = errors.New("watcher fell too far behind the changes")
+)
+
+const (
+	// watchHistorySize is the number of changes retained for watches that are
+	// resumed from a revision.
+	watchHistorySize = 4096
+
+	// watchBufferSize is the number of changes buffered for a watcher, before
+	// it's dropped.
+	watchBufferSize = 1024
+)
+
+// Watcher receives the changes made to a feature store.
+type Watcher struct {
+	feed     *changeFeed
+	events   chan *pb.FeatureEvent
+	snapshot []*pb.Feature
+	revision int64
+	err      error
+}
+
+// Events returns the channel of changes. The channel is closed when the
+// watcher is stopped, or dropped for falling behind.
+func (w *Watcher) Events() <-chan *pb.FeatureEvent {
+	return w.events
+}
+
+// Snapshot returns the features of the store at the revision the watcher
+// started at, if the watcher wasn't resumed from a revision.
+func (w *Watcher) Snapshot() []*pb.Feature {
+	return w.snapshot
+}
+
+// Revision returns the revision of the store that the watcher started at.
+func (w *Watcher) Revision() int64 {
+	return w.revision
+}
+
+// Err returns the reason the events channel was closed, if the watcher was
+// dropped.
+func (w *Watcher) Err() error {
+	w.feed.mutex.Lock()
+	defer w.feed.mutex.Unlock()
+
+	return w.err
+}
+
+// Stop unsubscribes the watcher from the store.
+func (w *Watcher) Stop() {
+	w.feed.mutex.Lock()
+	defer w.feed.mutex.Unlock()
+
+	w.feed.drop(w, nil)
+}
+
+// changeFeed retains the recent changes of a feature store, and delivers new
+// changes to the subscribed watchers.
+type changeFeed struct {
+	mutex     sync.Mutex
+	history   []*pb.FeatureEvent
+	compacted int64
+	watchers  map[*Watcher]struct{}
+}
+
+// newChangeFeed returns a change feed of a store at the given revision. The
+// changes up to that revision aren't retained.
+func newChangeFeed(revision int64) *changeFeed {
+	return &changeFeed{
+		compacted: revision,
+		watchers:  make(map[*Watcher]struct{}),
+	}
+}
+
+// publish retains the changes and delivers them to the watchers. Changes must
+// be published in the order of their revisions.
+func (c *changeFeed) publish(events []*pb.FeatureEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.history = append(c.history, events...)
+	if len(c.history) > 2*watchHistorySize {
+		// trim in batches so that the history isn't copied on every change
+		trimmed := len(c.history) - watchHistorySize
+		c.compacted = c.history[trimmed-1].ResourceVersion
+		c.history = append([]*pb.FeatureEvent(nil), c.history[trimmed:]...)
+	}
+
+	for w := range c.watchers {
+		c.send(w, events)
+	}
+}
+
+// send delivers the changes to the watcher without blocking. A watcher whose
+// buffer is full is dropped, so that it can't hold up the store. The caller
+// must hold the mutex.
+func (c *changeFeed) send(w *Watcher, events []*pb.FeatureEvent) {
+	for _, event := range events {
+		select {
+		case w.events <- event:
+		default:
+			c.drop(w, ErrWatcherOverflow)
+			return
+		}
+	}
+}
+
+// watch subscribes a watcher to the changes after the given revision. The
+// store must be at the current revision, and must not publish changes until
+// watch returns. If since is zero, the watcher starts with the snapshot
+// instead.
+func (c *changeFeed) watch(since, current int64, snapshot []*pb.Feature) (*Watcher, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	w := &Watcher{
+		feed:     c,
+		snapshot: snapshot,
+		revision: current,
+	}
+
+	var backlog []*pb.FeatureEvent
+	if since != 0 {
+		if since < c.compacted || since > current {
+			return nil, ErrRevisionCompacted
+		}
+
+		first := sort.Search(len(c.history), func(i int) bool {
+			return c.history[i].ResourceVersion > since
+		})
+		backlog = c.history[first:]
+		w.snapshot, w.revision = nil, since
+	}
+
+	w.events = make(chan *pb.FeatureEvent, len(backlog)+watchBufferSize)
+	for _, event := range backlog {
+		w.events <- event
+	}
+	c.watchers[w] = struct{}{}
+
+	return w, nil
+}
+
+// drop unsubscribes the watcher, closing its events channel. The caller must
+// hold the mutex.
+func (c *changeFeed) drop(w *Watcher, err error) {
+	if _, exists := c.watchers[w]; !exists {
+		return
+	}
+
+	delete(c.watchers, w)
+	w.err = err
+	close(w.events)
+}
+
+func featureEvent(eventType pb.FeatureEvent_Type, feature *pb.Feature, revision int64) *pb.FeatureEvent {
+	return &pb.FeatureEvent{
+		Type:            eventType,
+		Feature:         feature,
+		ResourceVersion: revision,
+	}
+}